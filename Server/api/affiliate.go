@@ -1,6 +1,7 @@
 package api
 
 import (
+	"Hanami/sqlc"
 	"net/http"
 	"strconv"
 
@@ -15,7 +16,13 @@ func (server *Server) get_affiliates_by_campaign_id(ctx *gin.Context) {
 		return
 	}
 
-	affiliates, err := server.store.Get_Affiliates_By_CampaignID(ctx, campaignId)
+	args := sqlc.Get_Affiliates_By_CampaignIDParams{
+		CampaignID:     campaignId,
+		IncludeBots:    include_bots(ctx),
+		IncludePending: server.include_pending(ctx),
+	}
+
+	affiliates, err := server.store.Get_Affiliates_By_CampaignID(ctx, args)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
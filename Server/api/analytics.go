@@ -1,6 +1,7 @@
 package api
 
 import (
+	"Hanami/sqlc"
 	"database/sql"
 	"log"
 	"net/http"
@@ -10,6 +11,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// include_bots reports whether the caller asked for bot and link-preview clicks
+// to be counted, via ?include_bots=true.
+func include_bots(ctx *gin.Context) bool {
+	include, _ := strconv.ParseBool(ctx.Query("include_bots"))
+	return include
+}
+
 func (server *Server) get_Brand_Key_Metrics(ctx *gin.Context) {
 	id := ctx.Query("brandId")
 
@@ -24,7 +32,7 @@ func (server *Server) get_Brand_Key_Metrics(ctx *gin.Context) {
 		Valid: true,
 	}
 
-	metrics, err := server.store.Get_Brand_Key_Metrics(ctx, convertedId)
+	metrics, err := server.store.Get_Brand_Key_Metrics(ctx, sqlc.Get_Brand_Key_MetricsParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -47,7 +55,7 @@ func (server *Server) get_Brand_Campaign_Performance(ctx *gin.Context) {
 		Valid: true,
 	}
 
-	performance, err := server.store.Get_Campaign_Performance(ctx, convertedId)
+	performance, err := server.store.Get_Campaign_Performance(ctx, sqlc.Get_Campaign_PerformanceParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -71,7 +79,7 @@ func (server *Server) get_Brand_Revenue(ctx *gin.Context) {
 		return
 	}
 
-	rawData, err := server.store.Get_Revenue_Data(ctx, brandId)
+	rawData, err := server.store.Get_Revenue_Data(ctx, sqlc.Get_Revenue_DataParams{BrandID: brandId, IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		log.Printf("Failed to fetch raw revenue data: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -136,13 +144,13 @@ func (server *Server) get_Brand_UTM_Performance(ctx *gin.Context) {
 		Valid: true,
 	}
 
-	utmSource, err := server.store.Get_UTMSource_Counts(ctx, convertedId)
+	utmSource, err := server.store.Get_UTMSource_Counts(ctx, sqlc.Get_UTMSource_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	utmMedium, err := server.store.Get_UTMMedium_Counts(ctx, convertedId)
+	utmMedium, err := server.store.Get_UTMMedium_Counts(ctx, sqlc.Get_UTMMedium_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -165,7 +173,7 @@ func (server *Server) get_CampaignEffectiveness(ctx *gin.Context) {
 		Valid: true,
 	}
 
-	effectiveness, err := server.store.Get_CampaignEffectiveness(ctx, convertedId)
+	effectiveness, err := server.store.Get_CampaignEffectiveness(ctx, sqlc.Get_CampaignEffectivenessParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -188,7 +196,7 @@ func (server *Server) get_MetricsOverTime(ctx *gin.Context) {
 		Valid: true,
 	}
 
-	metrics, err := server.store.Get_MetricsOverTime(ctx, convertedId)
+	metrics, err := server.store.Get_MetricsOverTime(ctx, sqlc.Get_MetricsOverTimeParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -211,7 +219,7 @@ func (server *Server) get_Campaign_Specific(ctx *gin.Context) {
 		Valid: true,
 	}
 
-	metrics, err := server.store.GetCampaign_Specific_Effectiveness(ctx, convertedId)
+	metrics, err := server.store.GetCampaign_Specific_Effectiveness(ctx, sqlc.GetCampaign_Specific_EffectivenessParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -231,7 +239,7 @@ func (server *Server) get_Revenue_Details(ctx *gin.Context) {
 		return
 	}
 
-	rawData, err := server.store.Get_Revenue_Data(ctx, brandId)
+	rawData, err := server.store.Get_Revenue_Data(ctx, sqlc.Get_Revenue_DataParams{BrandID: brandId, IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		log.Printf("Failed to fetch raw revenue data: %v", err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -277,4 +285,90 @@ func (server *Server) get_Revenue_Details(ctx *gin.Context) {
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"revenueData": revenueData})
-}
\ No newline at end of file
+}
+
+var subIDKeys = map[string]bool{"sub1": true, "sub2": true, "sub3": true, "sub4": true, "sub5": true}
+
+func (server *Server) get_SubLink_Breakdown(ctx *gin.Context) {
+	id := ctx.Query("campaignId")
+
+	campaignId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subKey := ctx.DefaultQuery("subKey", "sub1")
+	if !subIDKeys[subKey] {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "subKey must be one of sub1..sub5"})
+		return
+	}
+
+	args := sqlc.Get_SubLink_BreakdownParams{
+		SubKey: subKey,
+		CampaignID: sql.NullInt64{
+			Int64: campaignId,
+			Valid: true,
+		},
+		IncludeBots:    include_bots(ctx),
+		IncludePending: server.include_pending(ctx),
+	}
+
+	breakdown, err := server.store.Get_SubLink_Breakdown(ctx, args)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"breakdown": breakdown})
+}
+
+// get_geo_breakdown splits clicks, conversions and revenue by country and by
+// region for a brand (?brandId) or a single campaign (?campaignId).
+func (server *Server) get_geo_breakdown(ctx *gin.Context) {
+	var brandID, campaignID sql.NullInt64
+	for _, param := range []struct {
+		name  string
+		value *sql.NullInt64
+	}{{"brandId", &brandID}, {"campaignId", &campaignID}} {
+		raw := ctx.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param.name})
+			return
+		}
+		*param.value = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	if !brandID.Valid && !campaignID.Valid {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "brandId or campaignId is required"})
+		return
+	}
+
+	countries, err := server.store.Get_Geo_Country_Breakdown(ctx, sqlc.Get_Geo_Country_BreakdownParams{
+		IncludeBots:    include_bots(ctx),
+		BrandID:        brandID,
+		CampaignID:     campaignID,
+		IncludePending: server.include_pending(ctx),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	regions, err := server.store.Get_Geo_Region_Breakdown(ctx, sqlc.Get_Geo_Region_BreakdownParams{
+		IncludeBots:    include_bots(ctx),
+		BrandID:        brandID,
+		CampaignID:     campaignID,
+		IncludePending: server.include_pending(ctx),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"countries": countries, "regions": regions})
+}
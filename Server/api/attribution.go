@@ -0,0 +1,84 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"Hanami/util"
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attribution_model is the model that splits credit for a conversion whose
+// last click was on campaignID: the campaign's own, else its brand's.
+func (server *Server) attribution_model(ctx context.Context, campaignID int64) (util.AttributionModel, error) {
+	settings, err := server.store.Get_Attribution_Settings(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.new_attribution_model(settings.AttributionModel, settings.AttributionHalfLifeHours)
+}
+
+// new_attribution_model builds the named model, using the server's default
+// half-life when halfLifeHours is unset.
+func (server *Server) new_attribution_model(name string, halfLifeHours sql.NullInt32) (util.AttributionModel, error) {
+	halfLife := server.config.AttributionHalfLife
+	if halfLifeHours.Valid {
+		halfLife = time.Duration(halfLifeHours.Int32) * time.Hour
+	}
+	return util.NewAttributionModel(name, halfLife)
+}
+
+type update_brand_attribution_params struct {
+	AttributionModel string `json:"attribution_model" binding:"required"`
+	// Only used by time decay; omitted means the server default
+	AttributionHalfLifeHours int32 `json:"attribution_half_life_hours" binding:"omitempty,min=1"`
+}
+
+// update_brand_attribution sets the model used by every campaign of the brand
+// that has not chosen its own.
+func (server *Server) update_brand_attribution(ctx *gin.Context) {
+	brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+
+	var req update_brand_attribution_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !util.ValidAttributionModel(req.AttributionModel) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown attribution model", "models": util.AttributionModels})
+		return
+	}
+
+	updated, err := server.store.Update_Brand_Attribution(ctx, sqlc.Update_Brand_AttributionParams{
+		ID:               brandID,
+		AttributionModel: req.AttributionModel,
+		AttributionHalfLifeHours: sql.NullInt32{
+			Int32: req.AttributionHalfLifeHours,
+			Valid: req.AttributionHalfLifeHours > 0,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to update attribution model for brand %d: %v", brandID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if updated == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Brand not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"attribution_model":           req.AttributionModel,
+		"attribution_half_life_hours": req.AttributionHalfLifeHours,
+	})
+}
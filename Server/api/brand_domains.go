@@ -0,0 +1,230 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"Hanami/util"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type create_brand_domain_params struct {
+	BrandID int64  `json:"brand_id" binding:"required"`
+	Domain  string `json:"domain" binding:"required"`
+}
+
+// create_brand_domain claims a custom domain for the caller's brand. The
+// domain stays inactive until verify_brand_domain finds its token in DNS.
+func (server *Server) create_brand_domain(ctx *gin.Context) {
+	var req create_brand_domain_params
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !server.require_brand(ctx, req.BrandID) {
+		return
+	}
+
+	domain, err := util.NormalizeDomain(req.Domain)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if server.shortLinkHosts[domain] {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "domain is reserved for Hanami short links"})
+		return
+	}
+
+	owner, err := server.host_domain_brand(ctx, domain)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if owner.Valid && owner.Int64 != req.BrandID {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "domain is already verified by another brand"})
+		return
+	}
+
+	token, err := util.NewDomainVerificationToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	args := sqlc.Create_BrandDomainParams{
+		BrandID:           req.BrandID,
+		Domain:            domain,
+		VerificationToken: token,
+	}
+
+	brand_domain, err := server.store.Create_BrandDomain(ctx, args)
+	if err != nil {
+		if isUniqueViolation(err) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "domain is already registered"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"domain": brand_domain, "verification": verification_record(brand_domain)})
+}
+
+// verification_record is the DNS record that proves control of the domain.
+func verification_record(domain sqlc.BrandDomain) gin.H {
+	return gin.H{
+		"type":  "TXT",
+		"name":  util.DomainVerificationPrefix + domain.Domain,
+		"value": domain.VerificationToken,
+	}
+}
+
+// verify_brand_domain activates a domain once its verification TXT record
+// holds the token it was issued.
+func (server *Server) verify_brand_domain(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	brand_domain, ok := server.owned_domain(ctx, id)
+	if !ok {
+		return
+	}
+	if brand_domain.VerifiedAt.Valid {
+		ctx.JSON(http.StatusOK, gin.H{"domain": brand_domain})
+		return
+	}
+
+	records, err := net.DefaultResolver.LookupTXT(ctx.Request.Context(), util.DomainVerificationPrefix+brand_domain.Domain)
+	if err != nil || !slices.Contains(records, brand_domain.VerificationToken) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":        "verification TXT record not found",
+			"verification": verification_record(brand_domain),
+		})
+		return
+	}
+
+	brand_domain, err = server.store.Verify_BrandDomain(ctx, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "domain is already verified by another brand"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	log.Printf("Verified domain %s for brand %d", brand_domain.Domain, brand_domain.BrandID)
+	ctx.JSON(http.StatusOK, gin.H{"domain": brand_domain})
+}
+
+func (server *Server) get_brand_domains_by_brand_id(ctx *gin.Context) {
+	brandIDParam := ctx.Param("id")
+	brandID, err := strconv.ParseInt(brandIDParam, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !server.require_brand(ctx, brandID) {
+		return
+	}
+
+	domains, err := server.store.Get_BrandDomains_By_Brand(ctx, brandID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"domains": domains})
+}
+
+func (server *Server) delete_brand_domain_by_id(ctx *gin.Context) {
+	idParam := ctx.Param("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.owned_domain(ctx, id); !ok {
+		return
+	}
+
+	err = server.store.Delete_BrandDomain(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Domain deleted successfully"})
+}
+
+// owned_domain loads domain id, answering 404 or 403 and reporting false
+// unless it belongs to the caller's brand.
+func (server *Server) owned_domain(ctx *gin.Context, id int64) (sqlc.BrandDomain, bool) {
+	brand_domain, err := server.store.Get_BrandDomain(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+			return brand_domain, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return brand_domain, false
+	}
+	return brand_domain, server.require_brand(ctx, brand_domain.BrandID)
+}
+
+// request_host returns the lowercased Host header without its port.
+func request_host(ctx *gin.Context) string {
+	host := ctx.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// host_brand resolves the brand owning the request's Host header. Hanami's own
+// short-link hosts and unknown or unverified hosts resolve to no brand.
+func (server *Server) host_brand(ctx *gin.Context) (sql.NullInt64, error) {
+	host := request_host(ctx)
+	if host == "" || server.shortLinkHosts[host] {
+		return sql.NullInt64{}, nil
+	}
+	return server.host_domain_brand(ctx, host)
+}
+
+// host_domain_brand resolves the brand that verified domain, if any.
+func (server *Server) host_domain_brand(ctx *gin.Context, domain string) (sql.NullInt64, error) {
+	brand_domain, err := server.store.Get_BrandDomain_By_Domain(ctx, domain)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return sql.NullInt64{}, nil
+		}
+		return sql.NullInt64{}, err
+	}
+
+	return sql.NullInt64{Int64: brand_domain.BrandID, Valid: true}, nil
+}
+
+// short_url is the shareable URL for a link code, on the brand's custom
+// domain when it has one.
+func (server *Server) short_url(linkCode string, domain sql.NullString) string {
+	if domain.Valid && domain.String != "" {
+		return "https://" + domain.String + "/" + url.PathEscape(linkCode)
+	}
+	return server.shortLinkBase + "/" + url.PathEscape(linkCode)
+}
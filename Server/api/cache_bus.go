@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const linkCacheChannel = "hanami:link-cache:invalidate"
+
+// cacheInvalidation names the link or campaign whose cached entries are stale.
+type cacheInvalidation struct {
+	Origin     string `json:"origin"`
+	LinkID     int64  `json:"link_id,omitempty"`
+	CampaignID int64  `json:"campaign_id,omitempty"`
+}
+
+// invalidationBus fans invalidations out to every subscribed cache, the
+// publisher's included; caches drop their own messages by Origin.
+type invalidationBus interface {
+	Publish(ctx context.Context, msg cacheInvalidation) error
+	Subscribe(handler func(cacheInvalidation))
+	Name() string
+	Close() error
+}
+
+// localBus delivers invalidations in-process. It serves single-replica
+// deployments, and caches sharing one localBus behave like separate replicas.
+type localBus struct {
+	mu       sync.RWMutex
+	handlers []func(cacheInvalidation)
+}
+
+func newLocalBus() *localBus {
+	return &localBus{}
+}
+
+func (bus *localBus) Publish(_ context.Context, msg cacheInvalidation) error {
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	for _, handler := range bus.handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+func (bus *localBus) Subscribe(handler func(cacheInvalidation)) {
+	bus.mu.Lock()
+	bus.handlers = append(bus.handlers, handler)
+	bus.mu.Unlock()
+}
+
+func (bus *localBus) Name() string { return "local" }
+
+func (bus *localBus) Close() error { return nil }
+
+// redisBus shares invalidations between replicas over Redis pub/sub.
+type redisBus struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+	local  *localBus
+}
+
+func newRedisBus(redisURL string) (*redisBus, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	bus := &redisBus{
+		client: client,
+		pubsub: client.Subscribe(context.Background(), linkCacheChannel),
+		local:  newLocalBus(),
+	}
+
+	go bus.listen()
+	return bus, nil
+}
+
+// listen runs until Close; go-redis reconnects and resubscribes on its own.
+func (bus *redisBus) listen() {
+	for message := range bus.pubsub.Channel() {
+		var msg cacheInvalidation
+		if err := json.Unmarshal([]byte(message.Payload), &msg); err != nil {
+			log.Printf("Ignoring malformed link cache invalidation %q: %v", message.Payload, err)
+			continue
+		}
+		bus.local.Publish(context.Background(), msg)
+	}
+}
+
+func (bus *redisBus) Publish(ctx context.Context, msg cacheInvalidation) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return bus.client.Publish(ctx, linkCacheChannel, payload).Err()
+}
+
+func (bus *redisBus) Subscribe(handler func(cacheInvalidation)) {
+	bus.local.Subscribe(handler)
+}
+
+func (bus *redisBus) Name() string { return "redis" }
+
+func (bus *redisBus) Close() error {
+	bus.pubsub.Close()
+	return bus.client.Close()
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"Hanami/sqlc"
+	"Hanami/util"
 	"database/sql"
 	"log"
 	"net/http"
@@ -10,12 +11,19 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// defaultLookbackDays matches the column default on campaigns.lookback_days.
+const defaultLookbackDays = 30
+
 type create_campaign_params struct {
 	Name           string `json:"name,omitempty"`
 	Description    string `json:"description,omitempty"`
 	CommissionRate string `json:"commission_rate,omitempty"`
 	LandingUrl     string `json:"landing_url,omitempty"`
+	FallbackUrl    string `json:"fallback_url,omitempty" binding:"omitempty,url"`
 	BrandID        string `json:"brand_id,omitempty"`
+	LookbackDays   int32  `json:"lookback_days,omitempty" binding:"omitempty,min=1,max=365"`
+	// Days a sale stays pending before it is approved; 0 approves at once
+	ApprovalDelayDays int32 `json:"approval_delay_days,omitempty" binding:"omitempty,min=0,max=365"`
 }
 
 func (server *Server) create_campaign(ctx *gin.Context) {
@@ -58,7 +66,16 @@ func (server *Server) create_campaign(ctx *gin.Context) {
 		Description:    covertedDescription,
 		CommissionRate: req.CommissionRate,
 		LandingUrl:     req.LandingUrl,
-		BrandID:        brandId,
+		FallbackUrl: sql.NullString{
+			String: req.FallbackUrl,
+			Valid:  req.FallbackUrl != "",
+		},
+		BrandID:           brandId,
+		LookbackDays:      req.LookbackDays,
+		ApprovalDelayDays: req.ApprovalDelayDays,
+	}
+	if args.LookbackDays == 0 {
+		args.LookbackDays = defaultLookbackDays
 	}
 
 	campaign, err := server.store.Create_Campaign(ctx, args)
@@ -146,6 +163,8 @@ func (server *Server) delete_campaign_by_id(ctx *gin.Context) {
 		return
 	}
 
+	server.linkCache.invalidate_campaign(ctx, campaignID)
+
 	ctx.JSON(http.StatusOK, gin.H{"valid": true})
 
 }
@@ -173,3 +192,84 @@ func (server *Server) get_campaign_for_affiliate(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
 
 }
+
+type update_campaign_settings_params struct {
+	FallbackUrl       *string   `json:"fallback_url" binding:"omitempty,url|len=0"`
+	PassthroughParams *[]string `json:"passthrough_params" binding:"omitempty,max=20"`
+	RedirectTemplate  *string   `json:"redirect_template"`
+	// An empty model, or a zero half-life, falls back to the brand's setting
+	AttributionModel         *string `json:"attribution_model"`
+	AttributionHalfLifeHours *int32  `json:"attribution_half_life_hours" binding:"omitempty,min=0"`
+	LookbackDays             *int32  `json:"lookback_days" binding:"omitempty,min=1,max=365"`
+	ApprovalDelayDays        *int32  `json:"approval_delay_days" binding:"omitempty,min=0,max=365"`
+}
+
+// update_campaign_settings changes only the settings present in the body; an
+// empty string clears a URL setting or the campaign's own attribution model.
+func (server *Server) update_campaign_settings(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	campaignID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req update_campaign_settings_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	args := sqlc.Update_Campaign_SettingsParams{ID: campaignID}
+	if req.FallbackUrl != nil {
+		args.FallbackUrl = sql.NullString{String: *req.FallbackUrl, Valid: true}
+	}
+	if req.PassthroughParams != nil {
+		if err := util.ValidatePassthroughParams(*req.PassthroughParams); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		// A non-nil slice, even an empty one, replaces the stored list
+		args.PassthroughParams = append([]string{}, *req.PassthroughParams...)
+	}
+	if req.RedirectTemplate != nil {
+		if *req.RedirectTemplate != "" {
+			if err := util.ValidateRedirectTemplate(*req.RedirectTemplate); err != nil {
+				ctx.JSON(http.StatusBadRequest, errorResponse(err))
+				return
+			}
+		}
+		args.RedirectTemplate = sql.NullString{String: *req.RedirectTemplate, Valid: true}
+	}
+	if req.AttributionModel != nil {
+		if *req.AttributionModel != "" && !util.ValidAttributionModel(*req.AttributionModel) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown attribution model", "models": util.AttributionModels})
+			return
+		}
+		args.AttributionModel = sql.NullString{String: *req.AttributionModel, Valid: true}
+	}
+	if req.AttributionHalfLifeHours != nil {
+		args.AttributionHalfLifeHours = sql.NullInt32{Int32: *req.AttributionHalfLifeHours, Valid: true}
+	}
+	if req.LookbackDays != nil {
+		args.LookbackDays = sql.NullInt32{Int32: *req.LookbackDays, Valid: true}
+	}
+	if req.ApprovalDelayDays != nil {
+		args.ApprovalDelayDays = sql.NullInt32{Int32: *req.ApprovalDelayDays, Valid: true}
+	}
+
+	campaign, err := server.store.Update_Campaign_Settings(ctx, args)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "campaign not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.linkCache.invalidate_campaign(ctx, campaign.ID)
+
+	ctx.JSON(http.StatusOK, campaign)
+}
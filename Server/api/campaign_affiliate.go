@@ -2,9 +2,10 @@ package api
 
 import (
 	"Hanami/sqlc"
-	"Hanami/util"
 	"database/sql"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +14,8 @@ type campaign_affiliate_params struct {
 	InviteID         int64  `json:"invite_id,omitempty"`
 	Status           string `json:"status,omitempty"`
 	User_AffiliateID int64  `json:"user_affiliate_id,omitempty"`
+	// SubLinks are created next to the affiliate's default link
+	SubLinks []sub_link_params `json:"sub_links,omitempty" binding:"max=20,dive"`
 }
 
 func (server *Server) create_campaign_affiliate(ctx *gin.Context) {
@@ -25,6 +28,15 @@ func (server *Server) create_campaign_affiliate(ctx *gin.Context) {
 	}
 
 	if req.Status == "accepted" {
+		labels := map[string]bool{}
+		for _, sub_link := range req.SubLinks {
+			if labels[sub_link.Label] {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "sub-link labels must be unique"})
+				return
+			}
+			labels[sub_link.Label] = true
+		}
+
 		user_affiliate, err := server.store.User_Affiliate_Exists_By_Id(ctx, req.User_AffiliateID)
 		if err != nil {
 			ctx.JSON(http.StatusBadRequest, errorResponse(err))
@@ -80,12 +92,6 @@ func (server *Server) create_campaign_affiliate(ctx *gin.Context) {
 			return
 		}
 
-		code, err := util.Generate_Code()
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-			return
-		}
-
 		converted_campaign_id := sql.NullInt64{
 			Valid: true,
 			Int64: invite.CampaignID,
@@ -96,17 +102,21 @@ func (server *Server) create_campaign_affiliate(ctx *gin.Context) {
 			Int64: affiliate_id,
 		}
 
-		tracking_args := sqlc.Create_TrackingLinkParams{
+		// The default link and the sub-links are created together or not at all
+		tracking_args := []sqlc.Create_TrackingLinkParams{{
 			AffiliateID: converted_affiliate_id,
 			CampaignID:  converted_campaign_id,
-			LinkCode:    code,
+		}}
+		for _, sub_link := range req.SubLinks {
+			tracking_args = append(tracking_args, sub_link.tracking_args(affiliate_id, invite.CampaignID))
 		}
 
-		tracking_link, err := server.store.Create_TrackingLink(ctx, tracking_args)
+		links, err := server.insert_tracking_links(ctx, tracking_args...)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
+		tracking_link, sub_links := links[0], links[1:]
 
 		err = server.store.Delete_Invite_By_ID(ctx, req.InviteID)
 		if err != nil {
@@ -114,7 +124,7 @@ func (server *Server) create_campaign_affiliate(ctx *gin.Context) {
 			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"campaign": campaign_affiliate.CreatedAt, "link": tracking_link})
+		ctx.JSON(http.StatusOK, gin.H{"campaign": campaign_affiliate.CreatedAt, "link": tracking_link, "sub_links": sub_links})
 	} else {
 
 		err := server.store.Delete_Invite_By_ID(ctx, req.InviteID)
@@ -127,3 +137,83 @@ func (server *Server) create_campaign_affiliate(ctx *gin.Context) {
 
 	}
 }
+
+// affiliate_campaign_ids reads the :id campaign and :affiliate_id of a
+// sub-link route, answering 404 and reporting false unless the affiliate is
+// assigned to the campaign.
+func (server *Server) affiliate_campaign_ids(ctx *gin.Context) (campaignID, affiliateID int64, ok bool) {
+	campaignID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return 0, 0, false
+	}
+	affiliateID, err = strconv.ParseInt(ctx.Param("affiliate_id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid affiliate ID"})
+		return 0, 0, false
+	}
+
+	_, err = server.store.Get_Affiliate_Campaign(ctx, sqlc.Get_Affiliate_CampaignParams{
+		AffiliateID: affiliateID,
+		CampaignID:  campaignID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Affiliate is not assigned to this campaign"})
+		return 0, 0, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return 0, 0, false
+	}
+	return campaignID, affiliateID, true
+}
+
+// list_sub_links lists an affiliate's links in a campaign: the default link
+// first, then the labelled sub-links.
+func (server *Server) list_sub_links(ctx *gin.Context) {
+	campaignID, affiliateID, ok := server.affiliate_campaign_ids(ctx)
+	if !ok {
+		return
+	}
+
+	links, err := server.store.Get_TrackingLinks_By_Affiliate_Campaign(ctx, sqlc.Get_TrackingLinks_By_Affiliate_CampaignParams{
+		AffiliateID: sql.NullInt64{Int64: affiliateID, Valid: true},
+		CampaignID:  sql.NullInt64{Int64: campaignID, Valid: true},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if links == nil {
+		links = []sqlc.TrackingLink{}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// create_sub_link adds a labelled sub-link for an affiliate already assigned
+// to the campaign. Labels are unique per affiliate and campaign.
+func (server *Server) create_sub_link(ctx *gin.Context) {
+	campaignID, affiliateID, ok := server.affiliate_campaign_ids(ctx)
+	if !ok {
+		return
+	}
+
+	var req sub_link_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	link, err := server.insert_tracking_link(ctx, req.tracking_args(affiliateID, campaignID))
+	if isUniqueViolation(err) && violatedConstraint(err) == labelConstraint {
+		ctx.JSON(http.StatusConflict, gin.H{"error": label_conflict(req.Label)})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"link": link})
+}
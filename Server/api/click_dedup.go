@@ -0,0 +1,151 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// maxDedupWindow matches the CHECK on tracking_links.dedup_window_seconds and
+// bounds how long fingerprints are remembered.
+const maxDedupWindow = 24 * time.Hour
+
+const dedupCookiePrefix = "hanami_click_"
+
+type dedup_entry struct {
+	ClickID   uuid.UUID
+	FirstSeen time.Time
+}
+
+// clickDeduper recognises a visitor clicking the same link again inside the
+// link's dedup window, first by the per-link cookie set on their first click,
+// then by fingerprint. Fingerprints are per replica; the cookie covers
+// visitors whose repeat lands on another one. The cookie is signed with key
+// over the link, click_id and first-click time, so a visitor cannot plant a
+// click_id of their choosing or carry one past its window.
+type clickDeduper struct {
+	defaultWindow time.Duration
+	key           []byte
+	entries       *expirable.LRU[string, dedup_entry]
+
+	cookieHits      atomic.Int64
+	fingerprintHits atomic.Int64
+}
+
+type click_dedup_stats struct {
+	DefaultWindow   string `json:"default_window"`
+	Size            int    `json:"size"`
+	CookieHits      int64  `json:"cookie_hits"`
+	FingerprintHits int64  `json:"fingerprint_hits"`
+}
+
+func newClickDeduper(size int, defaultWindow time.Duration, key string) *clickDeduper {
+	deduper := &clickDeduper{defaultWindow: min(defaultWindow, maxDedupWindow), key: []byte(key)}
+	if size > 0 {
+		deduper.entries = expirable.NewLRU[string, dedup_entry](size, nil, maxDedupWindow)
+	}
+	return deduper
+}
+
+// window is the link's own dedup window, or the server default when unset.
+func (deduper *clickDeduper) window(link sqlc.TrackingLink) time.Duration {
+	if link.DedupWindowSeconds.Valid {
+		return time.Duration(link.DedupWindowSeconds.Int32) * time.Second
+	}
+	return deduper.defaultWindow
+}
+
+// original returns the click_id of the visitor's first click on link within
+// window, if there was one.
+func (deduper *clickDeduper) original(ctx *gin.Context, link sqlc.TrackingLink, fingerprint string, window time.Duration) (uuid.UUID, bool) {
+	if cookie, err := ctx.Cookie(dedup_cookie_name(link.ID)); err == nil {
+		if entry, ok := deduper.open_cookie(link.ID, cookie); ok && time.Since(entry.FirstSeen) < window {
+			deduper.cookieHits.Add(1)
+			return entry.ClickID, true
+		}
+	}
+
+	if deduper.entries == nil {
+		return uuid.UUID{}, false
+	}
+	entry, ok := deduper.entries.Get(dedup_key(link.ID, fingerprint))
+	if !ok || time.Since(entry.FirstSeen) >= window {
+		return uuid.UUID{}, false
+	}
+	deduper.fingerprintHits.Add(1)
+	return entry.ClickID, true
+}
+
+// remember records a first click so repeats within window can find it.
+func (deduper *clickDeduper) remember(ctx *gin.Context, link sqlc.TrackingLink, fingerprint string, clickID uuid.UUID, window time.Duration) {
+	entry := dedup_entry{ClickID: clickID, FirstSeen: time.Now()}
+	if deduper.entries != nil {
+		deduper.entries.Add(dedup_key(link.ID, fingerprint), entry)
+	}
+	ctx.SetCookie(dedup_cookie_name(link.ID), deduper.seal_cookie(link.ID, entry), int(window/time.Second), "/", "", ctx.Request.TLS != nil, true)
+}
+
+// seal_cookie encodes entry as <click_id>.<first seen, unix>.<signature>.
+func (deduper *clickDeduper) seal_cookie(linkID int64, entry dedup_entry) string {
+	payload := entry.ClickID.String() + "." + strconv.FormatInt(entry.FirstSeen.Unix(), 10)
+	return payload + "." + deduper.sign(linkID, payload)
+}
+
+// open_cookie returns the entry a cookie was sealed with, if it was sealed by
+// this server for linkID.
+func (deduper *clickDeduper) open_cookie(linkID int64, cookie string) (dedup_entry, bool) {
+	i := strings.LastIndexByte(cookie, '.')
+	if i < 0 {
+		return dedup_entry{}, false
+	}
+	payload, signature := cookie[:i], cookie[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(deduper.sign(linkID, payload))) {
+		return dedup_entry{}, false
+	}
+
+	clickPart, seenPart, _ := strings.Cut(payload, ".")
+	clickID, err := uuid.Parse(clickPart)
+	if err != nil {
+		return dedup_entry{}, false
+	}
+	seconds, err := strconv.ParseInt(seenPart, 10, 64)
+	if err != nil {
+		return dedup_entry{}, false
+	}
+	return dedup_entry{ClickID: clickID, FirstSeen: time.Unix(seconds, 0)}, true
+}
+
+func (deduper *clickDeduper) sign(linkID int64, payload string) string {
+	mac := hmac.New(sha256.New, deduper.key)
+	mac.Write([]byte("click_dedup|" + strconv.FormatInt(linkID, 10) + "|" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (deduper *clickDeduper) stats() click_dedup_stats {
+	stats := click_dedup_stats{
+		DefaultWindow:   deduper.defaultWindow.String(),
+		CookieHits:      deduper.cookieHits.Load(),
+		FingerprintHits: deduper.fingerprintHits.Load(),
+	}
+	if deduper.entries != nil {
+		stats.Size = deduper.entries.Len()
+	}
+	return stats
+}
+
+func dedup_key(linkID int64, fingerprint string) string {
+	return strconv.FormatInt(linkID, 10) + "|" + fingerprint
+}
+
+func dedup_cookie_name(linkID int64) string {
+	return dedupCookiePrefix + strconv.FormatInt(linkID, 10)
+}
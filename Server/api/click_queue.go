@@ -0,0 +1,368 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Backpressure policies for a full click queue.
+const (
+	// clickQueueBlock holds the redirect until a worker frees a slot.
+	clickQueueBlock = "block"
+	// clickQueueDrop discards the click so the redirect is never delayed.
+	clickQueueDrop = "drop"
+	// clickQueueSync writes the click inline, as if there were no queue.
+	clickQueueSync = "sync"
+)
+
+const (
+	// A failed batch is retried this many times in all, backing off from
+	// clickWriteBackoff, before its clicks are written one at a time.
+	clickWriteAttempts = 3
+	clickWriteBackoff  = 200 * time.Millisecond
+	// clickAwaitTimeout bounds how long a conversion waits for its clicks to
+	// leave the queue.
+	clickAwaitTimeout = 5 * time.Second
+	clickAwaitRekick  = 50 * time.Millisecond
+)
+
+var (
+	errClickQueueClosed = errors.New("click queue is closed")
+	errClickDropped     = errors.New("click queue is full, click dropped")
+)
+
+type click_queue_config struct {
+	Size          int
+	Workers       int
+	BatchSize     int
+	FlushInterval time.Duration
+	Policy        string
+	// BlockTimeout is the longest the block policy holds a redirect.
+	BlockTimeout time.Duration
+}
+
+// clickWriter stores a batch of clicks; *sqlc.Store in production.
+type clickWriter interface {
+	Create_Clicks_Batch(ctx context.Context, rows []sqlc.ClickRow) error
+}
+
+// clickQueue buffers clicks off the redirect path and writes them in batches.
+type clickQueue struct {
+	store  clickWriter
+	config click_queue_config
+	events chan sqlc.ClickRow
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	// inflight counts the queued rows of each click_id not yet written, so a
+	// conversion can wait for its click instead of missing it. landed is
+	// closed and replaced whenever rows leave flight.
+	flightMu sync.Mutex
+	inflight map[uuid.UUID]int
+	landed   chan struct{}
+	// kick asks the workers to flush without waiting for their ticker.
+	kick chan struct{}
+
+	enqueued atomic.Int64
+	dropped  atomic.Int64
+	inline   atomic.Int64
+	written  atomic.Int64
+	failed   atomic.Int64
+	batches  atomic.Int64
+}
+
+type click_queue_stats struct {
+	Depth    int    `json:"depth"`
+	Capacity int    `json:"capacity"`
+	Workers  int    `json:"workers"`
+	Policy   string `json:"policy"`
+	Enqueued int64  `json:"enqueued"`
+	Dropped  int64  `json:"dropped"`
+	Inline   int64  `json:"inline"`
+	Written  int64  `json:"written"`
+	Failed   int64  `json:"failed"`
+	Batches  int64  `json:"batches"`
+}
+
+func newClickQueue(store clickWriter, config click_queue_config) (*clickQueue, error) {
+	switch config.Policy {
+	case clickQueueBlock, clickQueueDrop, clickQueueSync:
+	default:
+		return nil, fmt.Errorf("unknown click queue policy %q", config.Policy)
+	}
+	if config.Size < 1 || config.Workers < 1 || config.BatchSize < 1 || config.FlushInterval <= 0 || config.BlockTimeout <= 0 {
+		return nil, errors.New("click queue size, workers, batch size, flush interval and block timeout must be positive")
+	}
+
+	queue := &clickQueue{
+		store:    store,
+		config:   config,
+		events:   make(chan sqlc.ClickRow, config.Size),
+		inflight: map[uuid.UUID]int{},
+		landed:   make(chan struct{}),
+		kick:     make(chan struct{}, config.Workers),
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		queue.wg.Add(1)
+		go queue.work()
+	}
+
+	return queue, nil
+}
+
+// enqueue hands a click to the workers, applying the backpressure policy when
+// the buffer is full. The block policy waits at most the configured block
+// timeout, and gives up early if ctx, the visitor's request, ends. A nil error
+// means the click is queued or written; otherwise it is lost.
+func (queue *clickQueue) enqueue(ctx context.Context, click sqlc.ClickRow) error {
+	queue.mu.RLock()
+	defer queue.mu.RUnlock()
+
+	if queue.closed {
+		return errClickQueueClosed
+	}
+
+	// Tracked before it is sent, so a worker can never land it first
+	batch := []sqlc.ClickRow{click}
+	queue.take_off(batch)
+
+	select {
+	case queue.events <- click:
+		queue.enqueued.Add(1)
+		return nil
+	default:
+	}
+
+	switch queue.config.Policy {
+	case clickQueueDrop:
+		queue.land(batch)
+		queue.dropped.Add(1)
+		return errClickDropped
+	case clickQueueSync:
+		queue.inline.Add(1)
+		err := queue.write(ctx, batch)
+		if err != nil {
+			queue.failed.Add(1)
+		}
+		queue.land(batch)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queue.config.BlockTimeout)
+	defer cancel()
+
+	select {
+	case queue.events <- click:
+		queue.enqueued.Add(1)
+		return nil
+	case <-ctx.Done():
+		queue.land(batch)
+		queue.dropped.Add(1)
+		return ctx.Err()
+	}
+}
+
+// take_off marks rows as in flight.
+func (queue *clickQueue) take_off(rows []sqlc.ClickRow) {
+	queue.flightMu.Lock()
+	defer queue.flightMu.Unlock()
+
+	for _, row := range rows {
+		queue.inflight[row.ClickID]++
+	}
+}
+
+// land marks rows as no longer in flight, whether they were written or lost,
+// and wakes anyone waiting in await.
+func (queue *clickQueue) land(rows []sqlc.ClickRow) {
+	queue.flightMu.Lock()
+	defer queue.flightMu.Unlock()
+
+	for _, row := range rows {
+		if queue.inflight[row.ClickID]--; queue.inflight[row.ClickID] <= 0 {
+			delete(queue.inflight, row.ClickID)
+		}
+	}
+	close(queue.landed)
+	queue.landed = make(chan struct{})
+}
+
+// await waits until none of clickIDs is still queued, nudging the workers to
+// flush, or until ctx ends. It reports whether any of them was in flight, in
+// which case the database is worth asking again.
+func (queue *clickQueue) await(ctx context.Context, clickIDs []uuid.UUID) bool {
+	waited := false
+	for {
+		queue.flightMu.Lock()
+		pending := false
+		for _, clickID := range clickIDs {
+			if queue.inflight[clickID] > 0 {
+				pending = true
+				break
+			}
+		}
+		landed := queue.landed
+		queue.flightMu.Unlock()
+
+		if !pending {
+			return waited
+		}
+		waited = true
+
+		for i := 0; i < queue.config.Workers; i++ {
+			select {
+			case queue.kick <- struct{}{}:
+			default:
+			}
+		}
+
+		// Kicks go to whichever workers take them first, so keep kicking
+		// until the clicks land
+		select {
+		case <-landed:
+		case <-time.After(clickAwaitRekick):
+		case <-ctx.Done():
+			return waited
+		}
+	}
+}
+
+func (queue *clickQueue) work() {
+	defer queue.wg.Done()
+
+	batch := make([]sqlc.ClickRow, 0, queue.config.BatchSize)
+	ticker := time.NewTicker(queue.config.FlushInterval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		queue.persist(batch)
+		queue.land(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case click, ok := <-queue.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, click)
+			if len(batch) >= queue.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-queue.kick:
+			// The kick can arrive before the click it is for, so take
+			// whatever is already buffered before flushing
+			for drained := false; !drained && len(batch) < queue.config.BatchSize; {
+				select {
+				case click, ok := <-queue.events:
+					if !ok {
+						flush()
+						return
+					}
+					batch = append(batch, click)
+				default:
+					drained = true
+				}
+			}
+			flush()
+		}
+	}
+}
+
+// persist writes a worker's batch with one multi-row INSERT, retrying with
+// backoff while the database is unavailable. A batch that still fails is
+// written one click at a time, so a single bad row cannot lose the rest.
+// Writes finish even during shutdown; losing a batch is worse than a slow
+// exit.
+func (queue *clickQueue) persist(batch []sqlc.ClickRow) {
+	ctx := context.Background()
+
+	var err error
+	for attempt := 0; attempt < clickWriteAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(clickWriteBackoff << (attempt - 1))
+		}
+		if err = queue.write(ctx, batch); err == nil {
+			return
+		}
+	}
+	if len(batch) == 1 {
+		log.Printf("Failed to write click %s: %v", batch[0].ClickID, err)
+		queue.failed.Add(1)
+		return
+	}
+
+	log.Printf("Failed to write %d clicks after %d attempts, writing them one at a time: %v", len(batch), clickWriteAttempts, err)
+	for _, click := range batch {
+		if err := queue.write(ctx, []sqlc.ClickRow{click}); err != nil {
+			log.Printf("Failed to write click %s: %v", click.ClickID, err)
+			queue.failed.Add(1)
+		}
+	}
+}
+
+func (queue *clickQueue) write(ctx context.Context, batch []sqlc.ClickRow) error {
+	if err := queue.store.Create_Clicks_Batch(ctx, batch); err != nil {
+		return err
+	}
+	queue.written.Add(int64(len(batch)))
+	queue.batches.Add(1)
+	return nil
+}
+
+// close stops accepting clicks and waits for the workers to drain the buffer,
+// or for ctx to expire.
+func (queue *clickQueue) close(ctx context.Context) error {
+	queue.mu.Lock()
+	if !queue.closed {
+		queue.closed = true
+		close(queue.events)
+	}
+	queue.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		queue.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("click queue flush interrupted with %d clicks buffered: %w", len(queue.events), ctx.Err())
+	}
+}
+
+func (queue *clickQueue) stats() click_queue_stats {
+	return click_queue_stats{
+		Depth:    len(queue.events),
+		Capacity: cap(queue.events),
+		Workers:  queue.config.Workers,
+		Policy:   queue.config.Policy,
+		Enqueued: queue.enqueued.Load(),
+		Dropped:  queue.dropped.Load(),
+		Inline:   queue.inline.Load(),
+		Written:  queue.written.Load(),
+		Failed:   queue.failed.Load(),
+		Batches:  queue.batches.Load(),
+	}
+}
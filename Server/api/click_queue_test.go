@@ -0,0 +1,337 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeClickWriter records the batches it is given. fail, when set, decides
+// whether a batch is written; a batch holding the gate click waits for
+// release first.
+type fakeClickWriter struct {
+	mu      sync.Mutex
+	batches [][]sqlc.ClickRow
+	calls   int
+	fail    func(call int, batch []sqlc.ClickRow) error
+	gate    uuid.UUID
+	release chan struct{}
+}
+
+func (writer *fakeClickWriter) Create_Clicks_Batch(ctx context.Context, rows []sqlc.ClickRow) error {
+	if writer.release != nil {
+		for _, row := range rows {
+			if row.ClickID == writer.gate {
+				<-writer.release
+			}
+		}
+	}
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	writer.calls++
+	if writer.fail != nil {
+		if err := writer.fail(writer.calls, rows); err != nil {
+			return err
+		}
+	}
+	// The queue reuses its batch slice once the write returns
+	writer.batches = append(writer.batches, append([]sqlc.ClickRow(nil), rows...))
+	return nil
+}
+
+func (writer *fakeClickWriter) batchSizes() []int {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	sizes := make([]int, len(writer.batches))
+	for i, batch := range writer.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func (writer *fakeClickWriter) written() map[uuid.UUID]bool {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	written := map[uuid.UUID]bool{}
+	for _, batch := range writer.batches {
+		for _, row := range batch {
+			written[row.ClickID] = true
+		}
+	}
+	return written
+}
+
+func testClickQueue(t *testing.T, writer *fakeClickWriter, config click_queue_config) *clickQueue {
+	t.Helper()
+	if config.Size == 0 {
+		config.Size = 100
+	}
+	if config.Workers == 0 {
+		config.Workers = 1
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 100
+	}
+	if config.FlushInterval == 0 {
+		config.FlushInterval = time.Hour
+	}
+	if config.Policy == "" {
+		config.Policy = clickQueueBlock
+	}
+	if config.BlockTimeout == 0 {
+		config.BlockTimeout = time.Hour
+	}
+
+	queue, err := newClickQueue(writer, config)
+	if err != nil {
+		t.Fatalf("newClickQueue: %v", err)
+	}
+	t.Cleanup(func() {
+		if writer.release != nil {
+			select {
+			case <-writer.release:
+			default:
+				close(writer.release)
+			}
+		}
+		queue.close(context.Background())
+	})
+	return queue
+}
+
+func testClick() sqlc.ClickRow {
+	return sqlc.ClickRow{
+		Create_ClickParams: sqlc.Create_ClickParams{ClickID: uuid.New()},
+		Timestamp:          time.Now().UTC(),
+	}
+}
+
+func mustEnqueue(t *testing.T, queue *clickQueue, click sqlc.ClickRow) {
+	t.Helper()
+	if err := queue.enqueue(context.Background(), click); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+}
+
+// eventually fails the test unless cond holds within a couple of seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClickQueueFlushesFullBatch(t *testing.T) {
+	writer := &fakeClickWriter{}
+	queue := testClickQueue(t, writer, click_queue_config{BatchSize: 3})
+
+	for i := 0; i < 3; i++ {
+		mustEnqueue(t, queue, testClick())
+	}
+
+	eventually(t, "a full batch", func() bool { return len(writer.batchSizes()) == 1 })
+	if sizes := writer.batchSizes(); sizes[0] != 3 {
+		t.Errorf("batch sizes = %v, want [3]", sizes)
+	}
+}
+
+func TestClickQueueFlushesOnTicker(t *testing.T) {
+	writer := &fakeClickWriter{}
+	queue := testClickQueue(t, writer, click_queue_config{FlushInterval: 10 * time.Millisecond})
+
+	click := testClick()
+	mustEnqueue(t, queue, click)
+
+	eventually(t, "the ticker flush", func() bool { return writer.written()[click.ClickID] })
+}
+
+func TestClickQueueAwaitKicksWorkers(t *testing.T) {
+	writer := &fakeClickWriter{}
+	queue := testClickQueue(t, writer, click_queue_config{})
+
+	click := testClick()
+	mustEnqueue(t, queue, click)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if waited := queue.await(ctx, []uuid.UUID{click.ClickID}); !waited {
+		t.Error("await() = false for a queued click, want true")
+	}
+	if !writer.written()[click.ClickID] {
+		t.Error("await() returned before the click was written")
+	}
+
+	if waited := queue.await(ctx, []uuid.UUID{uuid.New()}); waited {
+		t.Error("await() = true for a click that was never queued, want false")
+	}
+}
+
+// fullClickQueue returns a queue whose single worker is stuck writing one
+// click while a second fills the one-slot buffer.
+func fullClickQueue(t *testing.T, config click_queue_config) (*clickQueue, *fakeClickWriter) {
+	t.Helper()
+	stuck := testClick()
+	writer := &fakeClickWriter{gate: stuck.ClickID, release: make(chan struct{})}
+	config.Size, config.Workers, config.BatchSize = 1, 1, 1
+	queue := testClickQueue(t, writer, config)
+
+	mustEnqueue(t, queue, stuck)
+	eventually(t, "the worker to take the first click", func() bool { return len(queue.events) == 0 })
+	mustEnqueue(t, queue, testClick())
+	return queue, writer
+}
+
+func TestClickQueuePolicies(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		queue, writer := fullClickQueue(t, click_queue_config{Policy: clickQueueDrop})
+
+		click := testClick()
+		if err := queue.enqueue(context.Background(), click); !errors.Is(err, errClickDropped) {
+			t.Fatalf("enqueue() error = %v, want errClickDropped", err)
+		}
+		if stats := queue.stats(); stats.Dropped != 1 {
+			t.Errorf("dropped = %d, want 1", stats.Dropped)
+		}
+		close(writer.release)
+		queue.close(context.Background())
+		if writer.written()[click.ClickID] {
+			t.Error("dropped click was written")
+		}
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		queue, writer := fullClickQueue(t, click_queue_config{Policy: clickQueueSync})
+
+		click := testClick()
+		if err := queue.enqueue(context.Background(), click); err != nil {
+			t.Fatalf("enqueue() error = %v", err)
+		}
+		if !writer.written()[click.ClickID] {
+			t.Error("sync click was not written inline")
+		}
+		if stats := queue.stats(); stats.Inline != 1 {
+			t.Errorf("inline = %d, want 1", stats.Inline)
+		}
+	})
+
+	t.Run("block times out", func(t *testing.T) {
+		queue, _ := fullClickQueue(t, click_queue_config{BlockTimeout: 20 * time.Millisecond})
+
+		if err := queue.enqueue(context.Background(), testClick()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("enqueue() error = %v, want context.DeadlineExceeded", err)
+		}
+		if stats := queue.stats(); stats.Dropped != 1 {
+			t.Errorf("dropped = %d, want 1", stats.Dropped)
+		}
+	})
+
+	t.Run("block gives up with the request", func(t *testing.T) {
+		queue, _ := fullClickQueue(t, click_queue_config{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := queue.enqueue(ctx, testClick()); !errors.Is(err, context.Canceled) {
+			t.Fatalf("enqueue() error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("block waits for a free slot", func(t *testing.T) {
+		queue, writer := fullClickQueue(t, click_queue_config{})
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(writer.release)
+		}()
+		click := testClick()
+		if err := queue.enqueue(context.Background(), click); err != nil {
+			t.Fatalf("enqueue() error = %v", err)
+		}
+		eventually(t, "the blocked click", func() bool { return writer.written()[click.ClickID] })
+	})
+}
+
+func TestClickQueueCloseDrains(t *testing.T) {
+	writer := &fakeClickWriter{}
+	queue := testClickQueue(t, writer, click_queue_config{Workers: 2})
+
+	var clicks []sqlc.ClickRow
+	for i := 0; i < 5; i++ {
+		click := testClick()
+		clicks = append(clicks, click)
+		mustEnqueue(t, queue, click)
+	}
+
+	if err := queue.close(context.Background()); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+	written := writer.written()
+	for _, click := range clicks {
+		if !written[click.ClickID] {
+			t.Errorf("click %s was not written before close returned", click.ClickID)
+		}
+	}
+
+	if err := queue.enqueue(context.Background(), testClick()); !errors.Is(err, errClickQueueClosed) {
+		t.Errorf("enqueue() after close error = %v, want errClickQueueClosed", err)
+	}
+}
+
+func TestClickQueueRetriesFailedBatches(t *testing.T) {
+	t.Run("database comes back", func(t *testing.T) {
+		writer := &fakeClickWriter{fail: func(call int, _ []sqlc.ClickRow) error {
+			if call < clickWriteAttempts {
+				return errors.New("database unavailable")
+			}
+			return nil
+		}}
+		queue := testClickQueue(t, writer, click_queue_config{BatchSize: 2})
+
+		mustEnqueue(t, queue, testClick())
+		mustEnqueue(t, queue, testClick())
+
+		eventually(t, "the retried batch", func() bool { return len(writer.batchSizes()) == 1 })
+		if sizes := writer.batchSizes(); sizes[0] != 2 {
+			t.Errorf("batch sizes = %v, want [2]", sizes)
+		}
+	})
+
+	t.Run("bad row is written around", func(t *testing.T) {
+		bad := testClick()
+		writer := &fakeClickWriter{fail: func(_ int, batch []sqlc.ClickRow) error {
+			for _, row := range batch {
+				if row.ClickID == bad.ClickID {
+					return errors.New("bad row")
+				}
+			}
+			return nil
+		}}
+		queue := testClickQueue(t, writer, click_queue_config{BatchSize: 3})
+
+		good := []sqlc.ClickRow{testClick(), testClick()}
+		mustEnqueue(t, queue, good[0])
+		mustEnqueue(t, queue, bad)
+		mustEnqueue(t, queue, good[1])
+
+		eventually(t, "the good rows", func() bool {
+			written := writer.written()
+			return written[good[0].ClickID] && written[good[1].ClickID]
+		})
+		eventually(t, "the bad row to fail", func() bool { return queue.stats().Failed == 1 })
+		if writer.written()[bad.ClickID] {
+			t.Error("bad row was written")
+		}
+	})
+}
@@ -2,49 +2,115 @@ package api
 
 import (
 	"Hanami/sqlc"
+	"Hanami/util"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"net/url"
-	"strings"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-func (server *Server) redirect_user(ctx *gin.Context) {
-	referer := ctx.Request.Header.Get("Referer")
-	var refererDomain string
-	if referer != "" {
-		parsedURL, err := url.Parse(referer)
-		if err != nil {
-			log.Printf("Invalid Referer URL: %v", err)
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Referer URL"})
-			return
-		}
-		refererDomain = parsedURL.Host
-	} else {
-		refererDomain = "unknown"
-	}
+const maxSubIDLength = 255
 
+func (server *Server) redirect_user(ctx *gin.Context) {
 	linkCode := ctx.Query("tracking_code")
 	if linkCode == "" {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "tracking_code query parameter is required"})
 		return
 	}
 
+	hostBrand, err := server.host_brand(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.redirect_link_code(ctx, linkCode, hostBrand)
+}
+
+// redirect_short_link serves /r/:code on any host.
+func (server *Server) redirect_short_link(ctx *gin.Context) {
+	hostBrand, err := server.host_brand(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.redirect_link_code(ctx, ctx.Param("code"), hostBrand)
+}
+
+// redirect_host_short_link serves /:code, which is only a short link on the
+// dedicated short-link hosts and on brand custom domains.
+func (server *Server) redirect_host_short_link(ctx *gin.Context) {
+	hostBrand, err := server.host_brand(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !hostBrand.Valid && !server.shortLinkHosts[request_host(ctx)] {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+
+	server.redirect_link_code(ctx, ctx.Param("code"), hostBrand)
+}
+
+// redirect_link_code records a click for linkCode and sends the visitor to the
+// campaign landing page. When the request arrived on a brand's custom domain,
+// only that brand's links resolve.
+func (server *Server) redirect_link_code(ctx *gin.Context, linkCode string, hostBrand sql.NullInt64) {
+	// A malformed Referer is stored as-is under the invalid platform; it
+	// never costs us the click
+	referrer := util.ParseReferrer(ctx.Request.Header.Get("Referer"), server.config.ReferrerStripQuery)
+
 	utmSource := ctx.Query("utm_source")
 	utmMedium := ctx.Query("utm_medium")
+	utmCampaign := ctx.Query("utm_campaign")
 
-	tracking_link, err := server.store.Get_TrackingLink_By_Link_Code(ctx, linkCode)
+	var subIDs [5]string
+	for i := range subIDs {
+		subIDs[i] = truncate(ctx.Query(fmt.Sprintf("sub%d", i+1)), maxSubIDLength)
+	}
+
+	resolved, err := server.resolve_link(ctx, linkCode)
 	if err != nil {
-		log.Printf("Failed to find tracking link for code %s: %v", linkCode, err)
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Tracking link not found"})
+		log.Printf("Failed to resolve tracking link for code %s: %v", linkCode, err)
+		server.redirect_fallback(ctx, sql.NullString{})
 		return
 	}
+	tracking_link, campaign := resolved.Link, resolved.Campaign
+
+	if hostBrand.Valid && campaign.BrandID.Int64 != hostBrand.Int64 {
+		log.Printf("Tracking link %s does not belong to brand %d", linkCode, hostBrand.Int64)
+		server.redirect_fallback(ctx, sql.NullString{})
+		return
+	}
+
+	if reason := link_unavailable(tracking_link); reason != "" {
+		log.Printf("Tracking link %s is %s, sending visitor to fallback", linkCode, reason)
+		server.redirect_fallback(ctx, campaign.FallbackUrl)
+		return
+	}
+
+	// Sub-links carry default UTM values that an explicit query param overrides
+	if utmSource == "" {
+		utmSource = tracking_link.UtmSource.String
+	}
+	if utmMedium == "" {
+		utmMedium = tracking_link.UtmMedium.String
+	}
+	if utmCampaign == "" {
+		utmCampaign = tracking_link.UtmCampaign.String
+	}
 
-	clickID := uuid.New()
 	userIP := ctx.ClientIP()
 	if userIP == "" {
 		log.Printf("Unable to determine client IP")
@@ -52,22 +118,71 @@ func (server *Server) redirect_user(ctx *gin.Context) {
 		return
 	}
 	userAgent := ctx.Request.UserAgent()
+	botReason := server.botClassifier.Classify(userAgent, userIP)
+	fingerprint := util.ClickFingerprint(userIP, userAgent)
+	device := util.ParseUserAgent(userAgent)
+	location := server.geoIP.Lookup(userIP)
+
+	// A visitor clicking again inside the link's dedup window keeps their
+	// first click_id, so the landing page and SDK see a single tracker
+	clickID := uuid.New()
+	repeat := false
+	window := server.clickDeduper.window(tracking_link)
+	if botReason == "" && window > 0 {
+		if original, ok := server.clickDeduper.original(ctx, tracking_link, fingerprint, window); ok {
+			clickID, repeat = original, true
+		}
+	}
+
+	// Bots, previews and repeats never use up a link's click cap
+	counted := false
+	if botReason == "" && !repeat {
+		var available bool
+		if counted, available = server.claim_click(ctx, tracking_link); !available {
+			log.Printf("Tracking link %s is over its click cap, sending visitor to fallback", linkCode)
+			server.redirect_fallback(ctx, campaign.FallbackUrl)
+			return
+		}
+		if window > 0 {
+			server.clickDeduper.remember(ctx, tracking_link, fingerprint, clickID, window)
+		}
+	}
+
+	clickedAt := time.Now().UTC()
+	var fraudFlag *sqlc.Create_Fraud_FlagParams
+	if botReason == "" {
+		reasons := server.fraudEngine.evaluate_click(ctx, tracking_link.ID, userIP, clickedAt)
+		if score, flagged := server.fraudEngine.score(reasons); flagged {
+			fraudFlag = &sqlc.Create_Fraud_FlagParams{
+				BrandID:   campaign.BrandID.Int64,
+				EventType: "click",
+				ClickID:   clickID,
+				Score:     score,
+				Reasons:   reasons,
+			}
+		}
+	}
+
+	// Everything above needs the full address; only its anonymized form is
+	// stored, and the stored fingerprint is taken over that form too
+	storedIP := server.ipAnonymizer.Anonymize(userIP, clickedAt)
+	if server.ipAnonymizer.Mode() != util.IPPrivacyOff {
+		fingerprint = util.ClickFingerprint(storedIP, userAgent)
+	}
 
-	click_args := sqlc.Create_ClickParams{
+	click_args := sqlc.ClickRow{Timestamp: clickedAt, Repeat: repeat, Counted: counted, Flag: fraudFlag}
+	click_args.Create_ClickParams = sqlc.Create_ClickParams{
 		TrackingLinkID: sql.NullInt64{
 			Int64: tracking_link.ID,
 			Valid: true,
 		},
 		ClickID: clickID,
-		UserIp:  userIP,
+		UserIp:  nullString(storedIP),
 		UserAgent: sql.NullString{
 			String: userAgent,
 			Valid:  userAgent != "",
 		},
-		Referrer: sql.NullString{
-			String: refererDomain,
-			Valid:  refererDomain != "",
-		},
+		Referrer: nullString(referrer.URL),
 		UtmSource: sql.NullString{
 			String: utmSource,
 			Valid:  utmSource != "",
@@ -76,53 +191,158 @@ func (server *Server) redirect_user(ctx *gin.Context) {
 			String: utmMedium,
 			Valid:  utmMedium != "",
 		},
+		UtmCampaign: sql.NullString{
+			String: utmCampaign,
+			Valid:  utmCampaign != "",
+		},
+		Sub1: nullString(subIDs[0]),
+		Sub2: nullString(subIDs[1]),
+		Sub3: nullString(subIDs[2]),
+		Sub4: nullString(subIDs[3]),
+		Sub5: nullString(subIDs[4]),
+		// Previews and bots still get redirected so link unfurls keep working
+		IsBot:            botReason != "",
+		BotReason:        nullString(botReason),
+		Fingerprint:      nullString(fingerprint),
+		DeviceType:       nullString(device.Type),
+		Os:               nullString(device.OS),
+		Browser:          nullString(device.Browser),
+		BrowserVersion:   nullString(device.BrowserVersion),
+		Country:          nullString(location.Country),
+		Region:           nullString(location.Region),
+		City:             nullString(location.City),
+		ReferrerHost:     nullString(referrer.Host),
+		ReferrerPlatform: nullString(referrer.Platform),
 	}
 
-	click, err := server.store.Create_Click(ctx, click_args)
-	if err != nil {
-		log.Printf("Failed to create click for tracking link %d: %v", tracking_link.ID, err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record click"})
-		return
+	// The visitor is redirected even if the click cannot be recorded, but a
+	// lost click gives back its place under the link's cap
+	if err := server.clickQueue.enqueue(ctx.Request.Context(), click_args); err != nil {
+		log.Printf("Failed to record click %s for tracking link %d: %v", clickID, tracking_link.ID, err)
+		if counted {
+			server.release_click(tracking_link)
+		}
 	}
 
-	log.Printf("New Click %s", click.ClickID)
+	// Pixels on checkouts without the SDK find the visitor's trackers here
+	if botReason == "" {
+		remember_tracker(ctx, trackerParams{
+			ClickID:      clickID.String(),
+			TrackingCode: linkCode,
+			UtmSource:    utmSource,
+			UtmMedium:    utmMedium,
+			Timestamp:    clickedAt,
+		}, campaign.LookbackDays)
+	}
 
-	campaign, err := server.store.Get_Campaign(ctx, tracking_link.CampaignID.Int64)
+	macros := map[string]string{
+		"click_id":      clickID.String(),
+		"tracking_code": linkCode,
+		"affiliate_id":  strconv.FormatInt(tracking_link.AffiliateID.Int64, 10),
+		"campaign_id":   strconv.FormatInt(campaign.ID, 10),
+		"label":         tracking_link.Label.String,
+		"landing_url":   campaign.LandingUrl,
+		"utm_source":    utmSource,
+		"utm_medium":    utmMedium,
+		"utm_campaign":  utmCampaign,
+	}
+	for i, subID := range subIDs {
+		macros[fmt.Sprintf("sub%d", i+1)] = subID
+	}
+
+	redirectURL, err := util.BuildRedirectURL(util.RedirectTarget{
+		LandingURL:  campaign.LandingUrl,
+		Template:    campaign.RedirectTemplate.String,
+		Passthrough: campaign.PassthroughParams,
+		Incoming:    ctx.Request.URL.Query(),
+		Params: map[string]string{
+			"click_id":      clickID.String(),
+			"tracking_code": linkCode,
+			"utm_source":    utmSource,
+			"utm_medium":    utmMedium,
+			"utm_campaign":  utmCampaign,
+		},
+		Macros: macros,
+	})
 	if err != nil {
-		log.Printf("Failed to find campaign %d: %v", tracking_link.CampaignID.Int64, err)
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		log.Printf("Failed to build redirect for campaign %d: %v", campaign.ID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid landing URL"})
 		return
 	}
 
-	landingURL := campaign.LandingUrl
-	if !strings.HasPrefix(landingURL, "http://") && !strings.HasPrefix(landingURL, "https://") {
-		landingURL = "https://" + landingURL
+	ctx.Redirect(http.StatusFound, redirectURL)
+}
+
+// link_unavailable explains why a link may no longer redirect to its campaign,
+// or returns "" when it is live. Click caps are enforced by claim_click.
+func link_unavailable(link sqlc.TrackingLink) string {
+	switch {
+	case !link.IsActive:
+		return "paused"
+	case link.ExpiresAt.Valid && time.Now().UTC().After(link.ExpiresAt.Time):
+		return "expired"
 	}
-	landingURL = strings.Replace(landingURL, "http://", "https://", 1) // Force HTTPS
+	return ""
+}
 
-	parsedLandingURL, err := url.Parse(landingURL)
+// claim_click takes a click from a capped link's allowance against the live
+// counter, so concurrent redirects cannot overshoot max_clicks. It reports
+// whether the click was counted and whether the link may still redirect. The
+// link stays live if the counter cannot be read; the click is then counted
+// when its batch is written.
+func (server *Server) claim_click(ctx *gin.Context, link sqlc.TrackingLink) (counted bool, available bool) {
+	if !link.MaxClicks.Valid {
+		return false, true
+	}
+
+	_, err := server.store.Claim_TrackingLink_Click(ctx, link.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false
+	}
 	if err != nil {
-		log.Printf("Failed to parse landing URL %s: %v", landingURL, err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid landing URL"})
-		return
+		log.Printf("Failed to claim a click on tracking link %d: %v", link.ID, err)
+		return false, true
 	}
+	return true, true
+}
 
-	if parsedLandingURL.Hostname() == "" || strings.Contains(parsedLandingURL.Hostname(), "..") {
-		log.Printf("Invalid landing URL hostname: %s", parsedLandingURL.Hostname())
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid landing URL hostname"})
+// release_click gives back a click claim_click counted but which was never
+// recorded. It runs after the visitor's request may have ended, so it does
+// not use the request's context.
+func (server *Server) release_click(link sqlc.TrackingLink) {
+	if err := server.store.Release_TrackingLink_Click(context.Background(), link.ID); err != nil {
+		log.Printf("Failed to release a click on tracking link %d: %v", link.ID, err)
+	}
+}
+
+// redirect_fallback sends a visitor whose link cannot be honoured to the
+// campaign's fallback URL, then the server-wide one. Only when neither is
+// configured does the visitor get a 404.
+func (server *Server) redirect_fallback(ctx *gin.Context, campaignFallback sql.NullString) {
+	target := campaignFallback.String
+	if target == "" {
+		target = server.config.FallbackUrl
+	}
+
+	if target == "" {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Tracking link not found"})
 		return
 	}
 
-	redirectURL := fmt.Sprintf("%s?click_id=%s", landingURL, clickID.String())
+	ctx.Redirect(http.StatusFound, target)
+}
 
-	redirectURL = fmt.Sprintf("%s&tracking_code=%s", redirectURL, url.QueryEscape(linkCode))
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
 
-	if utmSource != "" {
-		redirectURL = fmt.Sprintf("%s&utm_source=%s", redirectURL, url.QueryEscape(utmSource))
+// truncate cuts s to at most max bytes without splitting a character.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
 	}
-	if utmMedium != "" {
-		redirectURL = fmt.Sprintf("%s&utm_medium=%s", redirectURL, url.QueryEscape(utmMedium))
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
 	}
-
-	ctx.Redirect(http.StatusFound, redirectURL)
+	return s[:max]
 }
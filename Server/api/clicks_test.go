@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{"shorter", "abc", 5, "abc"},
+		{"exact", "abcde", 5, "abcde"},
+		{"ascii", "abcdef", 5, "abcde"},
+		{"cut inside a two-byte character", "abcdé", 5, "abcd"},
+		{"cut after a two-byte character", "abcé", 5, "abcé"},
+		{"cut inside a four-byte character", "a😀b", 3, "a"},
+		{"nothing fits", "😀", 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.s, tt.max)
+			if got != tt.want {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncate(%q, %d) = %q is not valid UTF-8", tt.s, tt.max, got)
+			}
+		})
+	}
+}
@@ -2,13 +2,16 @@ package api
 
 import (
 	"Hanami/sqlc"
-	"Hanami/util"
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"log"
 	"net/http"
 	"sort"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,10 +19,15 @@ import (
 )
 
 type createConversionRequest struct {
-	SessionID string          `json:"session_id" binding:"required"`
-	Trackers  []trackerParams `json:"trackers" binding:"required,dive"`
-	Amount    float64         `json:"amount" binding:"required,gt=0"`
-	Currency  string          `json:"currency" binding:"required,oneof=USD INR EUR GBP JPY CAD AUD"`
+	// OrderID is the brand's own reference for the order. Retrying with the
+	// same OrderID returns the conversions recorded the first time; without
+	// one, every request records a new sale.
+	OrderID  string          `json:"order_id" binding:"max=255"`
+	Trackers []trackerParams `json:"trackers" binding:"required,dive"`
+	Amount   float64         `json:"amount" binding:"required,gt=0"`
+	Currency string          `json:"currency" binding:"required,oneof=USD INR EUR GBP JPY CAD AUD"`
+	// Event optionally names what converted, such as "purchase" or "signup"
+	Event string `json:"event" binding:"max=64"`
 }
 
 type trackerParams struct {
@@ -30,6 +38,35 @@ type trackerParams struct {
 	Timestamp    time.Time `json:"timestamp" binding:"required"`
 }
 
+// Tracker statuses reported back by create_conversion. Only accepted trackers
+// are credited.
+const (
+	trackerAccepted     = "accepted"
+	trackerDuplicate    = "duplicate"
+	trackerUnknownClick = "unknown_click"
+	trackerCodeMismatch = "tracking_code_mismatch"
+	trackerForeignBrand = "foreign_brand"
+	trackerExpired      = "outside_lookback"
+	// trackerNotCredited stands in for any of the above when a replayed
+	// order is reported without checking its trackers again
+	trackerNotCredited = "not_credited"
+)
+
+type tracker_result struct {
+	ClickID      string `json:"click_id"`
+	TrackingCode string `json:"tracking_code"`
+	Status       string `json:"status"`
+}
+
+// attributed_tracker is an accepted tracker with the time its click was
+// recorded.
+type attributed_tracker struct {
+	ClickID           uuid.UUID
+	CampaignID        int64
+	ClickedAt         time.Time
+	ApprovalDelayDays int32
+}
+
 func (server *Server) create_conversion(ctx *gin.Context) {
 	var req createConversionRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -48,78 +85,289 @@ func (server *Server) create_conversion(ctx *gin.Context) {
 		return
 	}
 
-	sale_args := sqlc.Create_SaleParams{
-		BrandID:  brandId.Int64,
-		Amount:   strconv.FormatFloat(req.Amount, 'f', -1, 64),
-		Currency: req.Currency,
+	ctx.JSON(server.record_conversion(ctx, brandId.Int64, req, ctx.ClientIP()))
+}
+
+// record_conversion credits req's trackers for a sale of brandID and returns
+// the response to send. clientIP is the converting visitor's address, or "" when the
+// request came from the brand's own servers.
+func (server *Server) record_conversion(ctx *gin.Context, brandID int64, req createConversionRequest, clientIP string) (int, gin.H) {
+	// A retry is answered before the trackers are checked, which can wait on
+	// the click queue
+	requestHash := req.request_hash()
+	if status, body, ok := server.replay_conversion(ctx, brandID, req, requestHash); ok {
+		return status, body
 	}
 
-	sale, err := server.store.Create_Sale(ctx, sale_args)
+	convertedAt := time.Now().UTC()
+	accepted, report, err := server.validate_trackers(ctx, brandID, req.Trackers, convertedAt)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "no sale creation"})
-		return
+		log.Printf("Failed to validate trackers: %v", err)
+		return http.StatusInternalServerError, errorResponse(err)
+	}
+
+	if len(accepted) == 0 {
+		return http.StatusUnprocessableEntity, gin.H{"error": "No tracker matches a recorded click", "trackers": report}
+	}
+
+	// The campaign of the converting click decides how credit is split and
+	// how long the sale waits for approval
+	converting := accepted[len(accepted)-1]
+	model, err := server.attribution_model(ctx, converting.CampaignID)
+	if err != nil {
+		log.Printf("Failed to load attribution model: %v", err)
+		return http.StatusInternalServerError, errorResponse(err)
+	}
+
+	touches := make([]time.Time, len(accepted))
+	for i, tracker := range accepted {
+		touches[i] = tracker.ClickedAt
+	}
+	weights := model.Weights(touches, convertedAt)
+	status, approveAt := approval(converting.ApprovalDelayDays, convertedAt)
+
+	args := sqlc.CreateConversionTxParams{
+		Sale: sqlc.Create_SaleParams{
+			BrandID:     brandID,
+			Amount:      strconv.FormatFloat(req.Amount, 'f', -1, 64),
+			Currency:    req.Currency,
+			OrderID:     nullString(req.OrderID),
+			RequestHash: nullString(requestHash),
+			EventName:   nullString(req.Event),
+			Status:      status,
+			ApproveAt:   approveAt,
+		},
+		Conversions: make([]sqlc.Create_ConversionParams, len(accepted)),
+	}
+	for i, tracker := range accepted {
+		args.Conversions[i] = sqlc.Create_ConversionParams{
+			ClickID: uuid.NullUUID{
+				UUID:  tracker.ClickID,
+				Valid: true,
+			},
+			Amount: req.Amount,
+			Currency: sql.NullString{
+				String: req.Currency,
+				Valid:  true,
+			},
+			Weight: sql.NullFloat64{
+				Float64: weights[i],
+				Valid:   true,
+			},
+			AttributionModel: nullString(model.Name()),
+		}
+	}
+
+	result, err := server.store.CreateConversionTx(ctx, args)
+	if errors.Is(err, sql.ErrNoRows) {
+		// A concurrent request for the same order got there first
+		if status, body, ok := server.replay_conversion(ctx, brandID, req, requestHash); ok {
+			return status, body
+		}
+		return http.StatusConflict, gin.H{"error": "order is already being recorded"}
+	}
+	if err != nil {
+		log.Printf("Failed to record conversion for order %s: %v", req.OrderID, err)
+		return http.StatusInternalServerError, errorResponse(err)
 	}
 
-	log.Printf("created new new sale : %v", sale.ID)
+	log.Printf("created new sale : %v", result.Sale.ID)
+	conversions := result.Conversions
+
+	server.flag_conversions(ctx, brandID, conversions, clientIP)
 
-	trackers := req.Trackers
-	sort.Slice(trackers, func(i, j int) bool {
-		return trackers[i].Timestamp.Before(trackers[j].Timestamp)
+	return http.StatusOK, gin.H{"conversions": conversions, "trackers": report}
+}
+
+// replay_conversion answers a retry of an order the brand has already
+// recorded: the same payload gets the original conversions back, a different
+// one is a conflict. ok reports whether the order existed, and so whether
+// status and body are the response to send. A request without an order ID is
+// never a retry.
+func (server *Server) replay_conversion(ctx *gin.Context, brandID int64, req createConversionRequest, requestHash string) (status int, body gin.H, ok bool) {
+	if req.OrderID == "" {
+		return 0, nil, false
+	}
+
+	sale, err := server.store.Get_Sale_By_Order(ctx, sqlc.Get_Sale_By_OrderParams{
+		BrandID: brandID,
+		OrderID: nullString(req.OrderID),
 	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, false
+	}
+	if err != nil {
+		return http.StatusInternalServerError, errorResponse(err), true
+	}
+
+	if sale.RequestHash.String != requestHash {
+		return http.StatusConflict, gin.H{"error": "order_id was already used for a different conversion"}, true
+	}
+
+	conversions, err := server.store.Get_Conversions_By_Sale(ctx, sql.NullInt64{Int64: sale.ID, Valid: true})
+	if err != nil {
+		return http.StatusInternalServerError, errorResponse(err), true
+	}
+	if conversions == nil {
+		conversions = []sqlc.Conversion{}
+	}
 
-	weights := util.CalculateUShapedWeights(len(trackers))
+	var clickIDs []uuid.UUID
+	for _, conversion := range conversions {
+		if conversion.ClickID.Valid {
+			clickIDs = append(clickIDs, conversion.ClickID.UUID)
+		}
+	}
+
+	log.Printf("replayed sale %d for order %s", sale.ID, req.OrderID)
+	return http.StatusOK, gin.H{"conversions": conversions, "trackers": replay_report(req.Trackers, clickIDs)}, true
+}
 
-	wg := &sync.WaitGroup{}
-	mut := &sync.RWMutex{}
+// replay_report rebuilds the tracker report for a replayed order from the
+// clicks it credited, without checking the trackers again: trackers credited
+// the first time are accepted, and the rest, whatever kept them out, are
+// reported as not credited.
+func replay_report(trackers []trackerParams, clickIDs []uuid.UUID) []tracker_result {
+	credited := map[uuid.UUID]bool{}
+	for _, clickID := range clickIDs {
+		credited[clickID] = true
+	}
 
-	conversions := make([]sqlc.Conversion, 0, len(trackers))
-	errChan := make(chan error, len(trackers))
+	report := make([]tracker_result, len(trackers))
 	for i, tracker := range trackers {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			clickID, err := uuid.Parse(tracker.ClickID)
-			if err != nil {
-				errChan <- err
-				return
-			}
+		report[i] = tracker_result{ClickID: tracker.ClickID, TrackingCode: tracker.TrackingCode, Status: trackerNotCredited}
+		if clickID, err := uuid.Parse(tracker.ClickID); err == nil && credited[clickID] {
+			report[i].Status = trackerAccepted
+			// A repeated click is credited once
+			delete(credited, clickID)
+		}
+	}
+	return report
+}
 
-			args := sqlc.Create_ConversionParams{
-				ClickID: uuid.NullUUID{
-					UUID:  clickID,
-					Valid: true,
-				},
-				Amount: req.Amount,
-				Currency: sql.NullString{
-					String: req.Currency,
-					Valid:  true,
-				},
-				Weight: sql.NullFloat64{
-					Float64: weights[i],
-					Valid:   true,
-				},
-			}
+// request_hash fingerprints everything about a conversion request that must
+// match for a retry to count as the same order. Tracker order does not matter.
+func (req createConversionRequest) request_hash() string {
+	return hash_request(strconv.FormatFloat(req.Amount, 'f', -1, 64)+"\x1e"+req.Currency+"\x1e"+req.Event, req.Trackers)
+}
+
+// hash_request fingerprints a request's own fields, joined into head, and its
+// trackers in any order.
+func hash_request(head string, requestTrackers []trackerParams) string {
+	trackers := make([]string, len(requestTrackers))
+	for i, tracker := range requestTrackers {
+		trackers[i] = strings.Join([]string{
+			strings.ToLower(tracker.ClickID),
+			tracker.TrackingCode,
+			tracker.UtmSource,
+			tracker.UtmMedium,
+			tracker.Timestamp.UTC().Format(time.RFC3339Nano),
+		}, "\x1f")
+	}
+	sort.Strings(trackers)
+
+	hash := sha256.New()
+	hash.Write([]byte(head))
+	for _, tracker := range trackers {
+		hash.Write([]byte("\x1e" + tracker))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// validate_trackers checks each tracker against the click it names: the click
+// must have been recorded, on the tracker's own tracking code, for a campaign
+// of brandID, within that campaign's lookback window of convertedAt. Accepted
+// trackers come back oldest click first, timed by when
+// the click was recorded rather than what the browser says. The report covers
+// every tracker in request order.
+func (server *Server) validate_trackers(ctx *gin.Context, brandID int64, trackers []trackerParams, convertedAt time.Time) ([]attributed_tracker, []tracker_result, error) {
+	clickIDs := make([]uuid.UUID, 0, len(trackers))
+	for _, tracker := range trackers {
+		// Binding already checked the format
+		if clickID, err := uuid.Parse(tracker.ClickID); err == nil {
+			clickIDs = append(clickIDs, clickID)
+		}
+	}
+
+	rows, err := server.store.Get_Tracker_Clicks(ctx, clickIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	clicks := make(map[uuid.UUID]sqlc.Get_Tracker_ClicksRow, len(rows))
+	for _, row := range rows {
+		clicks[row.ClickID] = row
+	}
 
-			conversion, err := server.store.Create_Conversion(ctx, args)
+	// Clicks reach the database up to a flush interval after their redirect,
+	// so a fast conversion waits for any of its clicks still in the queue
+	var missing []uuid.UUID
+	for _, clickID := range clickIDs {
+		if _, found := clicks[clickID]; !found {
+			missing = append(missing, clickID)
+		}
+	}
+	if len(missing) > 0 {
+		awaitCtx, cancel := context.WithTimeout(ctx.Request.Context(), clickAwaitTimeout)
+		waited := server.clickQueue.await(awaitCtx, missing)
+		cancel()
+
+		if waited {
+			rows, err := server.store.Get_Tracker_Clicks(ctx, missing)
 			if err != nil {
-				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-				return
+				return nil, nil, err
+			}
+			for _, row := range rows {
+				clicks[row.ClickID] = row
 			}
-			mut.Lock()
-			conversions = append(conversions, conversion)
-			mut.Unlock()
-		}()
+		}
 	}
 
-	wg.Wait()
-	close(errChan)
+	var accepted []attributed_tracker
+	report := make([]tracker_result, len(trackers))
+	seen := map[uuid.UUID]bool{}
+	for i, tracker := range trackers {
+		report[i] = tracker_result{ClickID: tracker.ClickID, TrackingCode: tracker.TrackingCode}
 
-	for err := range errChan {
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-			return
+		clickID, _ := uuid.Parse(tracker.ClickID)
+		click, found := clicks[clickID]
+		clickedAt := tracker.Timestamp
+		if click.Timestamp.Valid {
+			clickedAt = click.Timestamp.Time
+		}
+
+		switch {
+		case seen[clickID]:
+			report[i].Status = trackerDuplicate
+		case !found:
+			report[i].Status = trackerUnknownClick
+		case click.LinkCode != tracker.TrackingCode:
+			report[i].Status = trackerCodeMismatch
+		case !click.BrandID.Valid || click.BrandID.Int64 != brandID:
+			report[i].Status = trackerForeignBrand
+		case convertedAt.Sub(clickedAt) > lookback(click.LookbackDays):
+			report[i].Status = trackerExpired
+		default:
+			report[i].Status = trackerAccepted
+			accepted = append(accepted, attributed_tracker{
+				ClickID:           clickID,
+				CampaignID:        click.CampaignID.Int64,
+				ClickedAt:         clickedAt,
+				ApprovalDelayDays: click.ApprovalDelayDays,
+			})
+		}
+		seen[clickID] = true
+
+		if report[i].Status != trackerAccepted {
+			log.Printf("Rejected tracker %s (%s) for brand %d: %s", tracker.ClickID, tracker.TrackingCode, brandID, report[i].Status)
 		}
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"conversions": conversions})
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].ClickedAt.Before(accepted[j].ClickedAt)
+	})
+	return accepted, report, nil
+}
+
+func lookback(days int32) time.Duration {
+	return time.Duration(days) * 24 * time.Hour
 }
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type conversion_response struct {
+	Conversions []sqlc.Conversion `json:"conversions"`
+	Trackers    []tracker_result  `json:"trackers"`
+	Error       string            `json:"error"`
+}
+
+// createTestClick records a click on a new link of a new brand's campaign and
+// returns the brand and a tracker naming the click.
+func createTestClick(t *testing.T, server *Server) (int64, trackerParams) {
+	t.Helper()
+	ctx := context.Background()
+
+	brandID, err := server.store.Create_Brand(ctx, sqlc.Create_BrandParams{CompanyName: "test-" + uuid.NewString()})
+	if err != nil {
+		t.Fatalf("Create_Brand: %v", err)
+	}
+	campaign, err := server.store.Create_Campaign(ctx, sqlc.Create_CampaignParams{
+		BrandID:        sql.NullInt64{Int64: brandID, Valid: true},
+		Name:           "test campaign",
+		CommissionRate: "10",
+		LandingUrl:     "https://shop.example/",
+		LookbackDays:   30,
+	})
+	if err != nil {
+		t.Fatalf("Create_Campaign: %v", err)
+	}
+	link, err := server.store.Create_TrackingLink(ctx, sqlc.Create_TrackingLinkParams{
+		CampaignID: sql.NullInt64{Int64: campaign.ID, Valid: true},
+		LinkCode:   uuid.NewString(),
+	})
+	if err != nil {
+		t.Fatalf("Create_TrackingLink: %v", err)
+	}
+	click, err := server.store.Create_Click(ctx, sqlc.Create_ClickParams{
+		TrackingLinkID: sql.NullInt64{Int64: link.ID, Valid: true},
+		ClickID:        uuid.New(),
+		UserIp:         sql.NullString{String: "203.0.113.7", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Create_Click: %v", err)
+	}
+
+	return brandID, trackerParams{
+		ClickID:      click.ClickID.String(),
+		TrackingCode: link.LinkCode,
+		Timestamp:    time.Now().UTC(),
+	}
+}
+
+func postConversion(t *testing.T, server *Server, req createConversionRequest) (int, conversion_response) {
+	t.Helper()
+
+	// Errorf rather than Fatalf: concurrent submissions call this off the
+	// test goroutine
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Errorf("encode: %v", err)
+		return 0, conversion_response{}
+	}
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/api/conversion", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	server.router.ServeHTTP(recorder, request)
+
+	var resp conversion_response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Errorf("decode %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, resp
+}
+
+func conversionIDs(conversions []sqlc.Conversion) []int64 {
+	ids := make([]int64, len(conversions))
+	for i, conversion := range conversions {
+		ids[i] = conversion.ID
+	}
+	return ids
+}
+
+func TestRecordConversionReplay(t *testing.T) {
+	server := requireServer(t)
+	_, tracker := createTestClick(t, server)
+
+	req := createConversionRequest{
+		OrderID:  uuid.NewString(),
+		Trackers: []trackerParams{tracker},
+		Amount:   49.99,
+		Currency: "USD",
+	}
+
+	status, first := postConversion(t, server, req)
+	if status != http.StatusOK {
+		t.Fatalf("first submission: status %d (%s), want 200", status, first.Error)
+	}
+	if len(first.Conversions) != 1 {
+		t.Fatalf("first submission: %d conversions, want 1", len(first.Conversions))
+	}
+
+	// A retry of the same payload gets the original conversions back
+	status, replay := postConversion(t, server, req)
+	if status != http.StatusOK {
+		t.Fatalf("replay: status %d (%s), want 200", status, replay.Error)
+	}
+	if got, want := conversionIDs(replay.Conversions), conversionIDs(first.Conversions); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("replay returned conversions %v, want %v", got, want)
+	}
+	if len(replay.Trackers) != 1 || replay.Trackers[0].Status != trackerAccepted {
+		t.Errorf("replay reported trackers %+v, want one accepted", replay.Trackers)
+	}
+
+	// The same order_id with a different payload is a conflict
+	req.Amount = 59.99
+	status, conflict := postConversion(t, server, req)
+	if status != http.StatusConflict {
+		t.Errorf("conflicting submission: status %d (%s), want 409", status, conflict.Error)
+	}
+}
+
+func TestRecordConversionWithoutOrderID(t *testing.T) {
+	server := requireServer(t)
+	_, tracker := createTestClick(t, server)
+
+	req := createConversionRequest{
+		Trackers: []trackerParams{tracker},
+		Amount:   15,
+		Currency: "USD",
+	}
+
+	// Without an order_id there is nothing to replay, so each request is a
+	// new sale
+	var ids []int64
+	for i := 0; i < 2; i++ {
+		status, resp := postConversion(t, server, req)
+		if status != http.StatusOK {
+			t.Fatalf("submission %d: status %d (%s), want 200", i, status, resp.Error)
+		}
+		if len(resp.Conversions) != 1 {
+			t.Fatalf("submission %d: %d conversions, want 1", i, len(resp.Conversions))
+		}
+		ids = append(ids, resp.Conversions[0].ID)
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("both submissions returned conversion %d, want two sales", ids[0])
+	}
+}
+
+func TestReplayReport(t *testing.T) {
+	credited, repeated, rejected := uuid.New(), uuid.New(), uuid.New()
+	trackers := []trackerParams{
+		{ClickID: rejected.String(), TrackingCode: "a"},
+		{ClickID: credited.String(), TrackingCode: "b"},
+		{ClickID: repeated.String(), TrackingCode: "c"},
+		{ClickID: repeated.String(), TrackingCode: "c"},
+	}
+
+	want := []string{trackerNotCredited, trackerAccepted, trackerAccepted, trackerNotCredited}
+	report := replay_report(trackers, []uuid.UUID{credited, repeated})
+	if len(report) != len(want) {
+		t.Fatalf("replay_report() returned %d trackers, want %d", len(report), len(want))
+	}
+	for i, result := range report {
+		if result.Status != want[i] || result.ClickID != trackers[i].ClickID {
+			t.Errorf("tracker %d = %+v, want %s for %s", i, result, want[i], trackers[i].ClickID)
+		}
+	}
+}
+
+func TestRecordConversionConcurrentOrder(t *testing.T) {
+	server := requireServer(t)
+	brandID, tracker := createTestClick(t, server)
+
+	req := createConversionRequest{
+		OrderID:  uuid.NewString(),
+		Trackers: []trackerParams{tracker},
+		Amount:   20,
+		Currency: "USD",
+	}
+
+	const submissions = 8
+	statuses := make([]int, submissions)
+	responses := make([]conversion_response, submissions)
+
+	var wg sync.WaitGroup
+	for i := 0; i < submissions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i], responses[i] = postConversion(t, server, req)
+		}(i)
+	}
+	wg.Wait()
+
+	sale, err := server.store.Get_Sale_By_Order(context.Background(), sqlc.Get_Sale_By_OrderParams{
+		BrandID: brandID,
+		OrderID: sql.NullString{String: req.OrderID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Get_Sale_By_Order: %v", err)
+	}
+	conversions, err := server.store.Get_Conversions_By_Sale(context.Background(), sql.NullInt64{Int64: sale.ID, Valid: true})
+	if err != nil {
+		t.Fatalf("Get_Conversions_By_Sale: %v", err)
+	}
+	if len(conversions) != 1 {
+		t.Fatalf("sale has %d conversions, want 1", len(conversions))
+	}
+
+	// Every submission either recorded the sale or replayed it; a loser that
+	// raced the winner's commit may instead be told the order is in flight
+	for i := range statuses {
+		switch statuses[i] {
+		case http.StatusOK:
+			if got := conversionIDs(responses[i].Conversions); len(got) != 1 || got[0] != conversions[0].ID {
+				t.Errorf("submission %d returned conversions %v, want [%d]", i, got, conversions[0].ID)
+			}
+		case http.StatusConflict:
+		default:
+			t.Errorf("submission %d: status %d (%s)", i, statuses[i], responses[i].Error)
+		}
+	}
+}
+
+func TestRecordConversionUnknownClick(t *testing.T) {
+	server := requireServer(t)
+	_, tracker := createTestClick(t, server)
+	tracker.ClickID = uuid.NewString()
+
+	status, resp := postConversion(t, server, createConversionRequest{
+		OrderID:  uuid.NewString(),
+		Trackers: []trackerParams{tracker},
+		Amount:   20,
+		Currency: "USD",
+	})
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", status)
+	}
+	if len(resp.Trackers) != 1 || resp.Trackers[0].Status != trackerUnknownClick {
+		t.Errorf("trackers %+v, want one %s", resp.Trackers, trackerUnknownClick)
+	}
+}
@@ -0,0 +1,415 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type event_type_params struct {
+	Name         string   `json:"name" binding:"required,max=64"`
+	Payout       *float64 `json:"payout" binding:"required,gte=0"`
+	Currency     string   `json:"currency" binding:"required,oneof=USD INR EUR GBP JPY CAD AUD"`
+	DedupPerUser bool     `json:"dedup_per_user"`
+}
+
+// upsert_event_type defines an event the campaign pays a fixed amount for,
+// or changes the payout of one it already has. Events recorded before keep
+// the payout they were recorded with.
+func (server *Server) upsert_event_type(ctx *gin.Context) {
+	campaignID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	var req event_type_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	eventType, err := server.store.Upsert_Campaign_Event_Type(ctx, sqlc.Upsert_Campaign_Event_TypeParams{
+		CampaignID:   campaignID,
+		Name:         req.Name,
+		Payout:       strconv.FormatFloat(*req.Payout, 'f', 2, 64),
+		Currency:     req.Currency,
+		DedupPerUser: req.DedupPerUser,
+	})
+	if err != nil {
+		log.Printf("Failed to save event type %q for campaign %d: %v", req.Name, campaignID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"event_type": eventType})
+}
+
+func (server *Server) list_event_types(ctx *gin.Context) {
+	campaignID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	eventTypes, err := server.store.List_Campaign_Event_Types(ctx, campaignID)
+	if err != nil {
+		log.Printf("Failed to list event types for campaign %d: %v", campaignID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if eventTypes == nil {
+		eventTypes = []sqlc.CampaignEventType{}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"event_types": eventTypes})
+}
+
+type create_event_params struct {
+	Event    string          `json:"event" binding:"required,max=64"`
+	Trackers []trackerParams `json:"trackers" binding:"required,dive"`
+	// UserID is the brand's own reference for whoever triggered the event.
+	// Event types deduped per user require it.
+	UserID string `json:"user_id" binding:"max=255"`
+	// OrderID is the brand's own reference for the event, such as a lead or
+	// order number. Retrying with the same OrderID returns the event recorded
+	// the first time.
+	OrderID string `json:"order_id" binding:"max=255"`
+}
+
+// request_hash fingerprints everything about an event request that must match
+// for a retry to count as the same event.
+func (req create_event_params) request_hash() string {
+	return hash_request(req.Event+"\x1e"+req.UserID, req.Trackers)
+}
+
+// create_event records a non-monetary event, such as a signup or lead form,
+// for the campaign's fixed payout. The event is credited to the most recent
+// accepted click alone: a fixed payout is not split between touches.
+func (server *Server) create_event(ctx *gin.Context) {
+	var req create_event_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if len(req.Trackers) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "At least one tracker is required"})
+		return
+	}
+
+	brandID, err := server.store.Get_BrandID_By_TrackingCode(ctx, req.Trackers[0].TrackingCode)
+	if err != nil || !brandID.Valid {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "no brandId found"})
+		return
+	}
+
+	// A retry is answered before the trackers are checked, as for sales
+	requestHash := req.request_hash()
+	if status, body, ok := server.replay_event(ctx, brandID.Int64, req, requestHash); ok {
+		ctx.JSON(status, body)
+		return
+	}
+
+	now := time.Now().UTC()
+	accepted, report, err := server.validate_trackers(ctx, brandID.Int64, req.Trackers, now)
+	if err != nil {
+		log.Printf("Failed to validate trackers: %v", err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if len(accepted) == 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No tracker matches a recorded click", "trackers": report})
+		return
+	}
+	converting := accepted[len(accepted)-1]
+
+	eventType, err := server.store.Get_Campaign_Event_Type(ctx, sqlc.Get_Campaign_Event_TypeParams{
+		CampaignID: converting.CampaignID,
+		Name:       req.Event,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Campaign does not pay for event " + strconv.Quote(req.Event), "trackers": report})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load event type %q for campaign %d: %v", req.Event, converting.CampaignID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var dedupKey sql.NullString
+	if eventType.DedupPerUser {
+		if req.UserID == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required for event " + strconv.Quote(req.Event)})
+			return
+		}
+		dedupKey = nullString(req.UserID)
+	}
+
+	// Events wait out the campaign's approval delay like sales do
+	status, approveAt := approval(converting.ApprovalDelayDays, now)
+
+	event, err := server.store.Create_Event(ctx, sqlc.Create_EventParams{
+		BrandID:     brandID.Int64,
+		EventTypeID: eventType.ID,
+		ClickID:     converting.ClickID,
+		UserRef:     nullString(req.UserID),
+		DedupKey:    dedupKey,
+		OrderID:     nullString(req.OrderID),
+		RequestHash: nullString(requestHash),
+		Payout:      eventType.Payout,
+		Currency:    eventType.Currency,
+		Status:      status,
+		ApproveAt:   approveAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// A concurrent request for the same order got there first
+		if status, body, ok := server.replay_event(ctx, brandID.Int64, req, requestHash); ok {
+			ctx.JSON(status, body)
+			return
+		}
+		if !dedupKey.Valid {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "event is already being recorded"})
+			return
+		}
+
+		// The user already triggered this event; it is paid once
+		event, err = server.store.Get_Event_By_Dedup_Key(ctx, sqlc.Get_Event_By_Dedup_KeyParams{
+			EventTypeID: eventType.ID,
+			DedupKey:    dedupKey,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"event": event, "duplicate": true, "trackers": report})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to record event %q for campaign %d: %v", req.Event, converting.CampaignID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.flag_event(ctx, brandID.Int64, event, ctx.ClientIP())
+
+	ctx.JSON(http.StatusOK, gin.H{"event": event, "duplicate": false, "trackers": report})
+}
+
+// replay_event answers a retry of an event the brand has already recorded
+// under the same order ID, the way replay_conversion does for sales. A request
+// without an order ID is never a retry.
+func (server *Server) replay_event(ctx *gin.Context, brandID int64, req create_event_params, requestHash string) (status int, body gin.H, ok bool) {
+	if req.OrderID == "" {
+		return 0, nil, false
+	}
+
+	event, err := server.store.Get_Event_By_Order(ctx, sqlc.Get_Event_By_OrderParams{
+		BrandID: brandID,
+		OrderID: nullString(req.OrderID),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, false
+	}
+	if err != nil {
+		return http.StatusInternalServerError, errorResponse(err), true
+	}
+
+	if event.RequestHash.String != requestHash {
+		return http.StatusConflict, gin.H{"error": "order_id was already used for a different event"}, true
+	}
+
+	log.Printf("replayed event %d for order %s", event.ID, req.OrderID)
+	return http.StatusOK, gin.H{"event": event, "duplicate": true, "trackers": replay_report(req.Trackers, []uuid.UUID{event.ClickID})}, true
+}
+
+// approve_event approves a pending event without waiting out its delay.
+func (server *Server) approve_event(ctx *gin.Context) {
+	server.update_event_status(ctx, salePending, saleApproved)
+}
+
+// reject_event rejects a pending event, e.g. a fake signup. It is never paid.
+func (server *Server) reject_event(ctx *gin.Context) {
+	server.update_event_status(ctx, salePending, saleRejected)
+}
+
+// reverse_event reverses an approved event, taking back its payout.
+func (server *Server) reverse_event(ctx *gin.Context) {
+	server.update_event_status(ctx, saleApproved, saleReversed)
+}
+
+func (server *Server) update_event_status(ctx *gin.Context, from string, to string) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	// Approving needs no reason, so the body may be left out
+	var req sale_status_params
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+	if to != saleApproved && req.Reason == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	if !server.require_event_brand(ctx, id) {
+		return
+	}
+
+	event, err := server.store.Update_Event_Status(ctx, sqlc.Update_Event_StatusParams{
+		Status:       to,
+		StatusReason: nullString(req.Reason),
+		ID:           id,
+		FromStatus:   from,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		server.event_conflict(ctx, id, "Only "+from+" events can be "+to)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to mark event %d %s: %v", id, to, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"event": event})
+}
+
+// require_event_brand answers 404 or 403 and reports false unless event id
+// exists and belongs to the caller's brand.
+func (server *Server) require_event_brand(ctx *gin.Context, id int64) bool {
+	event, err := server.store.Get_Event(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	return server.require_brand(ctx, event.BrandID)
+}
+
+// event_conflict answers a change the event's state did not allow, or a 404
+// when there is no such event.
+func (server *Server) event_conflict(ctx *gin.Context, id int64, message string) {
+	event, err := server.store.Get_Event(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusConflict, gin.H{"error": message, "event": event})
+}
+
+type event_totals struct {
+	Event    string `json:"event"`
+	Count    int64  `json:"count"`
+	Payout   string `json:"payout"`
+	Currency string `json:"currency"`
+}
+
+type sale_totals struct {
+	Conversions int64  `json:"conversions"`
+	Revenue     string `json:"revenue"`
+	Commission  string `json:"commission"`
+	Currency    string `json:"currency"`
+}
+
+// affiliate_activity is what one affiliate drove on a campaign: sales and
+// their commission by currency, and events by name and currency.
+type affiliate_activity struct {
+	AffiliateID int64          `json:"affiliate_id"`
+	Sales       []sale_totals  `json:"sales"`
+	Events      []event_totals `json:"events"`
+}
+
+// get_campaign_activity reports each affiliate's events next to their sales,
+// counting sales the way get_campaign_commissions does. Events are counted
+// by the same rules.
+func (server *Server) get_campaign_activity(ctx *gin.Context) {
+	campaignID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	commissions, err := server.store.Get_Campaign_Commissions(ctx, sqlc.Get_Campaign_CommissionsParams{
+		CampaignID:     campaignID,
+		IncludePending: server.include_pending(ctx),
+	})
+	if err != nil {
+		log.Printf("Failed to get commissions for campaign %d: %v", campaignID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	events, err := server.store.Get_Campaign_Event_Counts(ctx, sqlc.Get_Campaign_Event_CountsParams{
+		CampaignID:     campaignID,
+		IncludePending: server.include_pending(ctx),
+	})
+	if err != nil {
+		log.Printf("Failed to count events for campaign %d: %v", campaignID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	activity := []*affiliate_activity{}
+	byAffiliate := map[int64]*affiliate_activity{}
+	affiliate := func(id int64) *affiliate_activity {
+		if entry, ok := byAffiliate[id]; ok {
+			return entry
+		}
+		entry := &affiliate_activity{AffiliateID: id, Sales: []sale_totals{}, Events: []event_totals{}}
+		byAffiliate[id] = entry
+		activity = append(activity, entry)
+		return entry
+	}
+
+	for _, row := range commissions {
+		if !row.AffiliateID.Valid {
+			continue
+		}
+		entry := affiliate(row.AffiliateID.Int64)
+		entry.Sales = append(entry.Sales, sale_totals{
+			Conversions: row.Conversions,
+			Revenue:     row.Revenue,
+			Commission:  row.Commission,
+			Currency:    row.Currency,
+		})
+	}
+	for _, row := range events {
+		if !row.AffiliateID.Valid {
+			continue
+		}
+		// A type whose currency changed is reported once per currency, as
+		// payouts in different currencies cannot be added up
+		entry := affiliate(row.AffiliateID.Int64)
+		entry.Events = append(entry.Events, event_totals{
+			Event:    row.Event,
+			Count:    row.Events,
+			Payout:   row.Payout,
+			Currency: row.Currency,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"affiliates": activity})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type event_response struct {
+	Event     sqlc.Event       `json:"event"`
+	Duplicate bool             `json:"duplicate"`
+	Trackers  []tracker_result `json:"trackers"`
+	Error     string           `json:"error"`
+}
+
+func postEvent(t *testing.T, server *Server, req create_event_params) (int, event_response) {
+	t.Helper()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/api/event", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	server.router.ServeHTTP(recorder, request)
+
+	var resp event_response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, resp
+}
+
+// createTestEventType defines a signup event on the campaign of tracker's
+// link.
+func createTestEventType(t *testing.T, server *Server, tracker trackerParams) {
+	t.Helper()
+	ctx := context.Background()
+
+	resolved, err := server.resolve_link(ctx, tracker.TrackingCode)
+	if err != nil {
+		t.Fatalf("resolve_link: %v", err)
+	}
+	_, err = server.store.Upsert_Campaign_Event_Type(ctx, sqlc.Upsert_Campaign_Event_TypeParams{
+		CampaignID: resolved.Campaign.ID,
+		Name:       "signup",
+		Payout:     "5.00",
+		Currency:   "USD",
+	})
+	if err != nil {
+		t.Fatalf("Upsert_Campaign_Event_Type: %v", err)
+	}
+}
+
+func TestCreateEventReplay(t *testing.T) {
+	server := requireServer(t)
+	_, tracker := createTestClick(t, server)
+	createTestEventType(t, server, tracker)
+
+	req := create_event_params{
+		Event:    "signup",
+		Trackers: []trackerParams{tracker},
+		OrderID:  uuid.NewString(),
+	}
+
+	status, first := postEvent(t, server, req)
+	if status != http.StatusOK || first.Duplicate {
+		t.Fatalf("first submission: status %d (%s), duplicate %v, want 200 and a new event", status, first.Error, first.Duplicate)
+	}
+
+	// A retry of the same payload gets the original event back
+	status, replay := postEvent(t, server, req)
+	if status != http.StatusOK || !replay.Duplicate || replay.Event.ID != first.Event.ID {
+		t.Errorf("replay: status %d, event %d, duplicate %v, want 200 and event %d again", status, replay.Event.ID, replay.Duplicate, first.Event.ID)
+	}
+
+	// The same order_id with a different payload is a conflict
+	req.UserID = "someone-else"
+	if status, conflict := postEvent(t, server, req); status != http.StatusConflict {
+		t.Errorf("conflicting submission: status %d (%s), want 409", status, conflict.Error)
+	}
+
+	// Without an order_id every event is recorded
+	req.OrderID, req.UserID = "", ""
+	_, one := postEvent(t, server, req)
+	_, two := postEvent(t, server, req)
+	if one.Duplicate || two.Duplicate || one.Event.ID == two.Event.ID {
+		t.Errorf("events without order_id: got %d and %d, want two new events", one.Event.ID, two.Event.ID)
+	}
+}
@@ -0,0 +1,319 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"Hanami/util"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// velocityKeys bounds how many IPs and IP/link pairs are counted at once.
+const velocityKeys = 100000
+
+type fraud_config struct {
+	Threshold          int
+	IPClicksPerMinute  int
+	LinkClicksPerHour  int
+	MinConversionDelay time.Duration
+	ASNDbPath          string
+	DatacenterASNFile  string
+}
+
+// windowCounter counts events per key in fixed windows. Counts are per
+// replica, so limits are enforced per instance rather than fleet-wide.
+type windowCounter struct {
+	window time.Duration
+	mu     sync.Mutex
+	counts *expirable.LRU[string, int]
+}
+
+func newWindowCounter(window time.Duration) *windowCounter {
+	return &windowCounter{
+		window: window,
+		counts: expirable.NewLRU[string, int](velocityKeys, nil, 2*window),
+	}
+}
+
+// add counts one event for key at t and returns the count so far in t's window.
+func (counter *windowCounter) add(key string, t time.Time) int {
+	bucket := key + "|" + strconv.FormatInt(t.UnixNano()/int64(counter.window), 10)
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	count, _ := counter.counts.Get(bucket)
+	count++
+	counter.counts.Add(bucket, count)
+	return count
+}
+
+// fraudEngine scores clicks and conversions against the fraud rules. Events
+// scoring at or above the threshold are written to fraud_flags for the
+// brand to review.
+type fraudEngine struct {
+	config fraud_config
+
+	ipClicks   *windowCounter
+	linkClicks *windowCounter
+
+	asn        *util.GeoIP
+	datacenter map[uint]bool
+}
+
+func newFraudEngine(config fraud_config) (*fraudEngine, error) {
+	engine := &fraudEngine{
+		config:     config,
+		ipClicks:   newWindowCounter(time.Minute),
+		linkClicks: newWindowCounter(time.Hour),
+	}
+
+	var err error
+	engine.asn, err = util.NewGeoIP(config.ASNDbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DatacenterASNFile != "" {
+		if engine.datacenter, err = util.LoadASNList(config.DatacenterASNFile); err != nil {
+			engine.asn.Close()
+			return nil, err
+		}
+	}
+	return engine, nil
+}
+
+func (engine *fraudEngine) Close() error {
+	return engine.asn.Close()
+}
+
+// score adds up reasons and reports whether they are enough to flag.
+func (engine *fraudEngine) score(reasons []string) (int32, bool) {
+	score := 0
+	for _, reason := range reasons {
+		score += util.FraudScores[reason]
+	}
+	return int32(score), len(reasons) > 0 && score >= engine.config.Threshold
+}
+
+// evaluate_click checks a click before it is queued. Repeats still count
+// towards velocity: hammering a link is exactly what dedup hides.
+func (engine *fraudEngine) evaluate_click(ctx *gin.Context, linkID int64, ip string, at time.Time) []string {
+	var reasons []string
+
+	if limit := engine.config.IPClicksPerMinute; limit > 0 && engine.ipClicks.add(ip, at) > limit {
+		reasons = append(reasons, util.FraudIPVelocity)
+	}
+	if limit := engine.config.LinkClicksPerHour; limit > 0 &&
+		engine.linkClicks.add(strconv.FormatInt(linkID, 10)+"|"+ip, at) > limit {
+		reasons = append(reasons, util.FraudLinkVelocity)
+	}
+
+	// A real click is a top-level navigation. Browsers that send fetch
+	// metadata say so; one loaded into an iframe or as an image is stuffing
+	dest := ctx.GetHeader("Sec-Fetch-Dest")
+	mode := ctx.GetHeader("Sec-Fetch-Mode")
+	if (dest != "" && dest != "document") || (mode != "" && mode != "navigate") {
+		reasons = append(reasons, util.FraudHiddenFrame)
+	}
+
+	if len(engine.datacenter) > 0 && engine.datacenter[engine.asn.ASN(ip)] {
+		reasons = append(reasons, util.FraudDatacenter)
+	}
+	return reasons
+}
+
+// evaluate_conversion checks a conversion against the click it credits.
+// anonymizer turns the converting IP into the form the click's IP was stored
+// in, so the two can be compared.
+func (engine *fraudEngine) evaluate_conversion(click sqlc.Click, found bool, ip string, at time.Time, anonymizer *util.IPAnonymizer) []string {
+	if !found {
+		return []string{util.FraudClickNotFound}
+	}
+
+	var reasons []string
+	if click.Timestamp.Valid && at.Sub(click.Timestamp.Time) < engine.config.MinConversionDelay {
+		reasons = append(reasons, util.FraudFastConversion)
+	}
+
+	// A scrubbed click no longer has an IP to compare
+	if click.UserIp.Valid && ip != "" {
+		var match bool
+		switch anonymizer.Mode() {
+		case util.IPPrivacyHash:
+			match = anonymizer.Anonymize(ip, click.Timestamp.Time) == click.UserIp.String
+		default:
+			match = util.SameIPRange(ip, click.UserIp.String)
+		}
+		if !match {
+			reasons = append(reasons, util.FraudIPMismatch)
+		}
+	}
+	return reasons
+}
+
+type fraud_review_params struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending confirmed cleared"`
+}
+
+func (server *Server) list_fraud_flags(ctx *gin.Context) {
+	brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+
+	var req fraud_review_params
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	flags, err := server.store.List_Fraud_Flags(ctx, sqlc.List_Fraud_FlagsParams{
+		BrandID: brandID,
+		Status:  nullString(req.Status),
+	})
+	if err != nil {
+		log.Printf("Failed to list fraud flags for brand %d: %v", brandID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"flags": flags})
+}
+
+func (server *Server) confirm_fraud_flag(ctx *gin.Context) {
+	server.review_fraud_flag(ctx, "confirmed")
+}
+
+func (server *Server) clear_fraud_flag(ctx *gin.Context) {
+	server.review_fraud_flag(ctx, "cleared")
+}
+
+func (server *Server) review_fraud_flag(ctx *gin.Context, status string) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fraud flag ID"})
+		return
+	}
+
+	flag, err := server.store.Get_Fraud_Flag(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Fraud flag not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !server.require_brand(ctx, flag.BrandID) {
+		return
+	}
+
+	flag, err = server.store.Review_Fraud_Flag(ctx, sqlc.Review_Fraud_FlagParams{ID: id, Status: status})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Fraud flag not found"})
+			return
+		}
+		log.Printf("Failed to review fraud flag %d: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"flag": flag})
+}
+
+// get_campaign_commissions reports what each affiliate has earned on a
+// campaign, leaving out events the brand cleared as fraud and conversions
+// that were rejected or reversed.
+func (server *Server) get_campaign_commissions(ctx *gin.Context) {
+	campaignID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	commissions, err := server.store.Get_Campaign_Commissions(ctx, sqlc.Get_Campaign_CommissionsParams{
+		CampaignID:     campaignID,
+		IncludePending: server.include_pending(ctx),
+	})
+	if err != nil {
+		log.Printf("Failed to get commissions for campaign %d: %v", campaignID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"commissions": commissions})
+}
+
+// flag_conversions scores each new conversion against the click it credits.
+// ip is the converting visitor's, or "" when unknown; the IP rule is skipped
+// then. A conversion is kept whether or not it can be scored.
+func (server *Server) flag_conversions(ctx *gin.Context, brandID int64, conversions []sqlc.Conversion, ip string) {
+	now := time.Now().UTC()
+	for _, conversion := range conversions {
+		if !conversion.ClickID.Valid {
+			continue
+		}
+
+		click, err := server.store.Get_Click_By_ClickID(ctx, conversion.ClickID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to load click %s for fraud checks: %v", conversion.ClickID.UUID, err)
+			continue
+		}
+
+		reasons := server.fraudEngine.evaluate_conversion(click, err == nil, ip, now, server.ipAnonymizer)
+		score, flagged := server.fraudEngine.score(reasons)
+		if !flagged {
+			continue
+		}
+
+		err = server.store.Create_Fraud_Flag(ctx, sqlc.Create_Fraud_FlagParams{
+			BrandID:      brandID,
+			EventType:    "conversion",
+			ClickID:      conversion.ClickID.UUID,
+			ConversionID: sql.NullInt64{Int64: conversion.ID, Valid: true},
+			Score:        score,
+			Reasons:      reasons,
+		})
+		if err != nil {
+			log.Printf("Failed to flag conversion %d: %v", conversion.ID, err)
+		}
+	}
+}
+
+// flag_event scores a new event against the click it credits, the way
+// flag_conversions scores a sale. The event is kept whether or not it can be
+// scored.
+func (server *Server) flag_event(ctx *gin.Context, brandID int64, event sqlc.Event, ip string) {
+	click, err := server.store.Get_Click_By_ClickID(ctx, event.ClickID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to load click %s for fraud checks: %v", event.ClickID, err)
+		return
+	}
+
+	reasons := server.fraudEngine.evaluate_conversion(click, err == nil, ip, time.Now().UTC(), server.ipAnonymizer)
+	score, flagged := server.fraudEngine.score(reasons)
+	if !flagged {
+		return
+	}
+
+	err = server.store.Create_Fraud_Flag(ctx, sqlc.Create_Fraud_FlagParams{
+		BrandID:   brandID,
+		EventType: "event",
+		ClickID:   event.ClickID,
+		EventID:   sql.NullInt64{Int64: event.ID, Valid: true},
+		Score:     score,
+		Reasons:   reasons,
+	})
+	if err != nil {
+		log.Printf("Failed to flag event %d: %v", event.ID, err)
+	}
+}
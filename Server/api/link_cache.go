@@ -0,0 +1,160 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+type resolved_link struct {
+	Link     sqlc.TrackingLink
+	Campaign sqlc.Campaign
+}
+
+// linkCache is a read-through LRU of link code -> link and campaign. Entries
+// live for ttl at most and are dropped early when a link or campaign changes,
+// on this replica or, through the bus, on any other.
+type linkCache struct {
+	// origin tags this replica's invalidations so it can skip its own echoes
+	origin  string
+	ttl     time.Duration
+	entries *expirable.LRU[string, resolved_link]
+	bus     invalidationBus
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+}
+
+type link_cache_stats struct {
+	Enabled       bool   `json:"enabled"`
+	Size          int    `json:"size"`
+	Hits          int64  `json:"hits"`
+	Misses        int64  `json:"misses"`
+	Invalidations int64  `json:"invalidations"`
+	Bus           string `json:"bus"`
+}
+
+// newLinkCache caches up to size codes. A zero ttl disables caching but
+// still lets invalidations flow through the bus.
+func newLinkCache(size int, ttl time.Duration, bus invalidationBus) *linkCache {
+	cache := &linkCache{origin: uuid.NewString(), ttl: ttl, bus: bus}
+	if ttl > 0 && size > 0 {
+		cache.entries = expirable.NewLRU[string, resolved_link](size, nil, ttl)
+	}
+	bus.Subscribe(func(msg cacheInvalidation) {
+		if msg.Origin != cache.origin {
+			cache.apply(msg)
+		}
+	})
+	return cache
+}
+
+func (cache *linkCache) get(code string) (resolved_link, bool) {
+	if cache.entries == nil {
+		return resolved_link{}, false
+	}
+
+	resolved, ok := cache.entries.Get(code)
+	if ok {
+		cache.hits.Add(1)
+	} else {
+		cache.misses.Add(1)
+	}
+	return resolved, ok
+}
+
+func (cache *linkCache) set(code string, resolved resolved_link) {
+	if cache.entries != nil {
+		cache.entries.Add(code, resolved)
+	}
+}
+
+// invalidate drops matching entries here and asks other replicas to do the
+// same. If the bus is down, other replicas catch up when their entries expire.
+func (cache *linkCache) invalidate(ctx context.Context, msg cacheInvalidation) {
+	cache.apply(msg)
+
+	msg.Origin = cache.origin
+	if err := cache.bus.Publish(ctx, msg); err != nil {
+		log.Printf("Failed to publish link cache invalidation %+v: %v", msg, err)
+	}
+}
+
+func (cache *linkCache) invalidate_link(ctx context.Context, linkID int64) {
+	cache.invalidate(ctx, cacheInvalidation{LinkID: linkID})
+}
+
+func (cache *linkCache) invalidate_campaign(ctx context.Context, campaignID int64) {
+	cache.invalidate(ctx, cacheInvalidation{CampaignID: campaignID})
+}
+
+// apply removes every cached code for the link or campaign in msg. Updates are
+// rare next to redirects, so a scan beats keeping reverse indexes in sync.
+func (cache *linkCache) apply(msg cacheInvalidation) {
+	cache.invalidations.Add(1)
+	if cache.entries == nil {
+		return
+	}
+
+	for _, code := range cache.entries.Keys() {
+		resolved, ok := cache.entries.Peek(code)
+		if !ok {
+			continue
+		}
+		if (msg.LinkID != 0 && resolved.Link.ID == msg.LinkID) ||
+			(msg.CampaignID != 0 && resolved.Campaign.ID == msg.CampaignID) {
+			cache.entries.Remove(code)
+		}
+	}
+}
+
+func (cache *linkCache) stats() link_cache_stats {
+	stats := link_cache_stats{
+		Enabled:       cache.entries != nil,
+		Hits:          cache.hits.Load(),
+		Misses:        cache.misses.Load(),
+		Invalidations: cache.invalidations.Load(),
+		Bus:           cache.bus.Name(),
+	}
+	if cache.entries != nil {
+		stats.Size = cache.entries.Len()
+	}
+	return stats
+}
+
+// resolve_link loads a link and its campaign, from the cache when possible.
+// Lookup failures are not cached.
+func (server *Server) resolve_link(ctx context.Context, code string) (resolved_link, error) {
+	if resolved, ok := server.linkCache.get(code); ok {
+		return resolved, nil
+	}
+
+	link, err := server.store.Get_TrackingLink_By_Link_Code(ctx, code)
+	if err != nil {
+		return resolved_link{}, err
+	}
+
+	campaign, err := server.store.Get_Campaign(ctx, link.CampaignID.Int64)
+	if err != nil {
+		return resolved_link{}, err
+	}
+
+	// click_count moves with every redirect, so a cached copy would only
+	// mislead; caps are checked against the live counter instead
+	link.ClickCount = 0
+	resolved := resolved_link{Link: link, Campaign: campaign}
+	server.linkCache.set(code, resolved)
+	return resolved, nil
+}
+
+func (server *Server) get_link_cache_metrics(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"linkCache": server.linkCache.stats()})
+}
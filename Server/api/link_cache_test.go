@@ -0,0 +1,187 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func cachedLink(linkID, campaignID int64) resolved_link {
+	return resolved_link{
+		Link:     sqlc.TrackingLink{ID: linkID},
+		Campaign: sqlc.Campaign{ID: campaignID},
+	}
+}
+
+func TestLinkCacheHitsAndMisses(t *testing.T) {
+	cache := newLinkCache(10, time.Minute, newLocalBus())
+
+	if _, ok := cache.get("abc"); ok {
+		t.Fatal("get() hit on an empty cache")
+	}
+	cache.set("abc", cachedLink(1, 2))
+	resolved, ok := cache.get("abc")
+	if !ok || resolved.Link.ID != 1 || resolved.Campaign.ID != 2 {
+		t.Fatalf("get() = %+v, %v, want link 1 of campaign 2", resolved, ok)
+	}
+
+	stats := cache.stats()
+	if !stats.Enabled || stats.Size != 1 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("stats() = %+v, want enabled with 1 entry, 1 hit and 1 miss", stats)
+	}
+}
+
+func TestLinkCacheExpiry(t *testing.T) {
+	cache := newLinkCache(10, 20*time.Millisecond, newLocalBus())
+
+	cache.set("abc", cachedLink(1, 2))
+	eventually(t, "the entry to expire", func() bool {
+		_, ok := cache.get("abc")
+		return !ok
+	})
+}
+
+func TestLinkCacheDisabled(t *testing.T) {
+	cache := newLinkCache(10, 0, newLocalBus())
+
+	cache.set("abc", cachedLink(1, 2))
+	if _, ok := cache.get("abc"); ok {
+		t.Error("get() hit with caching disabled")
+	}
+	if stats := cache.stats(); stats.Enabled || stats.Misses != 0 {
+		t.Errorf("stats() = %+v, want disabled with no misses", stats)
+	}
+}
+
+func TestLinkCacheInvalidationReachesReplicas(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  cacheInvalidation
+		kept []string
+	}{
+		{"by link", cacheInvalidation{LinkID: 1}, []string{"other"}},
+		{"by campaign", cacheInvalidation{CampaignID: 2}, []string{"other"}},
+		{"unrelated", cacheInvalidation{LinkID: 9, CampaignID: 9}, []string{"abc", "sub", "other"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newLocalBus()
+			local := newLinkCache(10, time.Minute, bus)
+			replica := newLinkCache(10, time.Minute, bus)
+			for _, cache := range []*linkCache{local, replica} {
+				cache.set("abc", cachedLink(1, 2))
+				cache.set("sub", cachedLink(1, 2))
+				cache.set("other", cachedLink(3, 4))
+			}
+
+			local.invalidate(context.Background(), tt.msg)
+
+			for name, cache := range map[string]*linkCache{"local": local, "replica": replica} {
+				got := cache.entries.Keys()
+				slices.Sort(got)
+				want := slices.Sorted(slices.Values(tt.kept))
+				if !slices.Equal(got, want) {
+					t.Errorf("%s cache kept %v, want %v", name, got, want)
+				}
+			}
+			// The publisher skips its own echo from the bus
+			if got := local.stats().Invalidations; got != 1 {
+				t.Errorf("local invalidations = %d, want 1", got)
+			}
+		})
+	}
+}
+
+// createTestLink adds a link, without clicks so it can be deleted, to a new
+// brand's campaign.
+func createTestLink(t *testing.T, server *Server) sqlc.TrackingLink {
+	t.Helper()
+	ctx := context.Background()
+
+	brandID, err := server.store.Create_Brand(ctx, sqlc.Create_BrandParams{CompanyName: "test-" + uuid.NewString()})
+	if err != nil {
+		t.Fatalf("Create_Brand: %v", err)
+	}
+	campaign, err := server.store.Create_Campaign(ctx, sqlc.Create_CampaignParams{
+		BrandID:        sql.NullInt64{Int64: brandID, Valid: true},
+		Name:           "test campaign",
+		CommissionRate: "10",
+		LandingUrl:     "https://shop.example/",
+		LookbackDays:   30,
+	})
+	if err != nil {
+		t.Fatalf("Create_Campaign: %v", err)
+	}
+	link, err := server.store.Create_TrackingLink(ctx, sqlc.Create_TrackingLinkParams{
+		CampaignID: sql.NullInt64{Int64: campaign.ID, Valid: true},
+		LinkCode:   uuid.NewString(),
+	})
+	if err != nil {
+		t.Fatalf("Create_TrackingLink: %v", err)
+	}
+	return link
+}
+
+// TestLinkChangesInvalidateCache changes a cached link through its handlers
+// and checks that neither this replica nor another still serves it.
+func TestLinkChangesInvalidateCache(t *testing.T) {
+	base := requireServer(t)
+
+	tests := []struct {
+		name    string
+		handler func(server *Server) gin.HandlerFunc
+		body    string
+	}{
+		{"pause", func(server *Server) gin.HandlerFunc { return server.pause_tracking_link }, ""},
+		{"limits", func(server *Server) gin.HandlerFunc { return server.update_tracking_link_limits }, `{"max_clicks": 5}`},
+		{"dedup", func(server *Server) gin.HandlerFunc { return server.update_tracking_link_dedup }, `{"dedup_window_seconds": 60}`},
+		{"delete", func(server *Server) gin.HandlerFunc { return server.delete_tracking_link_by_id }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := newLocalBus()
+			server := *base
+			server.linkCache = newLinkCache(10, time.Minute, bus)
+			replica := newLinkCache(10, time.Minute, bus)
+
+			link := createTestLink(t, &server)
+			resolved, err := server.resolve_link(context.Background(), link.LinkCode)
+			if err != nil {
+				t.Fatalf("resolve_link: %v", err)
+			}
+			replica.set(link.LinkCode, resolved)
+			if _, ok := server.linkCache.get(link.LinkCode); !ok {
+				t.Fatal("resolve_link did not cache the link")
+			}
+
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+			ctx.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(link.ID, 10)}}
+			tt.handler(&server)(ctx)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("handler answered %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			if _, ok := server.linkCache.get(link.LinkCode); ok {
+				t.Error("this replica still serves the changed link")
+			}
+			if _, ok := replica.get(link.LinkCode); ok {
+				t.Error("the other replica still serves the changed link")
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"Hanami/util"
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/lib/pq"
+)
+
+// testServer is backed by the migrated database at TEST_DB_URL. Tests that
+// need it are skipped when it is unset.
+var testServer *Server
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	if url := os.Getenv("TEST_DB_URL"); url != "" {
+		conn, err := sql.Open("postgres", url)
+		if err != nil {
+			log.Fatalf("could not open test database: %v", err)
+		}
+		if err := conn.Ping(); err != nil {
+			log.Fatalf("could not reach test database: %v", err)
+		}
+
+		if os.Getenv("TOKEN_SYMMETRIC_KEY") == "" {
+			os.Setenv("TOKEN_SYMMETRIC_KEY", "12345678901234567890123456789012")
+		}
+		config, err := util.LoadConfig(".")
+		if err != nil {
+			log.Fatalf("could not load config: %v", err)
+		}
+
+		testServer, err = NewServer(sqlc.NewStore(conn), config)
+		if err != nil {
+			log.Fatalf("could not create test server: %v", err)
+		}
+	}
+
+	os.Exit(m.Run())
+}
+
+func requireServer(t *testing.T) *Server {
+	t.Helper()
+	if testServer == nil {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	return testServer
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"Hanami/util"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authorizationHeaderKey  = "Authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationPayloadKey = "authorization_payload"
+	authorizedBrandKey      = "authorized_brand"
+	authorizedUserKey       = "authorized_user"
+)
+
+// authMiddleware requires a valid access token in the Authorization header
+// and stores its payload on the context for the handlers behind it.
+func authMiddleware(tokenMaker util.Maker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		fields := strings.Fields(ctx.GetHeader(authorizationHeaderKey))
+		if len(fields) != 2 || strings.ToLower(fields[0]) != authorizationTypeBearer {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "A bearer access token is required"})
+			return
+		}
+
+		payload, err := tokenMaker.VerifyToken(fields[1])
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		if time.Now().After(payload.ExpiredAt) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Access token has expired"})
+			return
+		}
+
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Next()
+	}
+}
+
+// authorized_user is the ID of the signed-in user. It must run behind
+// authMiddleware.
+func (server *Server) authorized_user(ctx *gin.Context) (int64, error) {
+	if userID, ok := ctx.Get(authorizedUserKey); ok {
+		return userID.(int64), nil
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+	user, err := server.store.Get_User_By_Email(ctx, payload.Email)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx.Set(authorizedUserKey, user.ID)
+	return user.ID, nil
+}
+
+// authorized_brand is the ID of the brand the caller signs in as. It must run
+// behind authMiddleware.
+func (server *Server) authorized_brand(ctx *gin.Context) (int64, error) {
+	if brandID, ok := ctx.Get(authorizedBrandKey); ok {
+		return brandID.(int64), nil
+	}
+
+	userID, err := server.authorized_user(ctx)
+	if err != nil {
+		return 0, err
+	}
+	brand, err := server.store.Get_Brand_By_UserID(ctx, sql.NullInt64{Int64: userID, Valid: true})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx.Set(authorizedBrandKey, brand.ID)
+	return brand.ID, nil
+}
+
+// require_brand answers 403 and reports false unless the caller owns brandID.
+func (server *Server) require_brand(ctx *gin.Context, brandID int64) bool {
+	callerBrand, err := server.authorized_brand(ctx)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && callerBrand != brandID) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not own this brand"})
+		return false
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	return true
+}
+
+// brandOwnerMiddleware guards /api/brand/:id/... routes: the caller must be
+// signed in as brand :id.
+func (server *Server) brandOwnerMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+			return
+		}
+		if server.require_brand(ctx, brandID) {
+			ctx.Next()
+		}
+	}
+}
+
+// linkOwnerMiddleware guards /api/tracking/:id/... routes: the caller must be
+// signed in as the brand running the link's campaign, or as the link's
+// affiliate.
+func (server *Server) linkOwnerMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		linkID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid tracking link ID"})
+			return
+		}
+
+		owners, err := server.store.Get_TrackingLink_Owners(ctx, linkID)
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Tracking link not found"})
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		userID, err := server.authorized_user(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not own this tracking link"})
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if owners.AffiliateUserID.Valid && owners.AffiliateUserID.Int64 == userID {
+			ctx.Next()
+			return
+		}
+
+		callerBrand, err := server.authorized_brand(ctx)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && (!owners.BrandID.Valid || callerBrand != owners.BrandID.Int64)) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not own this tracking link"})
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// campaignOwnerMiddleware guards /api/campaign/:id/... routes: the caller must
+// be signed in as the brand running campaign :id.
+func (server *Server) campaignOwnerMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		campaignID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+			return
+		}
+
+		campaign, err := server.store.Get_Campaign(ctx, campaignID)
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+			return
+		}
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if server.require_brand(ctx, campaign.BrandID.Int64) {
+			ctx.Next()
+		}
+	}
+}
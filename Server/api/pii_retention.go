@@ -0,0 +1,57 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// piiRetentionBatch keeps each scrub UPDATE short so it never holds locks
+// that the click writers are waiting on.
+const piiRetentionBatch = 1000
+
+// run_pii_retention scrubs IPs, user agents, fingerprints and cities from
+// clicks older than PII_RETENTION_DAYS, once at start and then every
+// PII_RETENTION_INTERVAL, until ctx is cancelled. Aggregate columns such as
+// country and device stay so analytics keep working.
+func (server *Server) run_pii_retention(ctx context.Context) {
+	if server.config.PIIRetentionDays <= 0 || server.config.PIIRetentionInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(server.config.PIIRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		scrubbed, err := server.scrub_expired_pii(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("PII retention stopped after scrubbing %d clicks: %v", scrubbed, err)
+		} else if scrubbed > 0 {
+			log.Printf("PII retention scrubbed %d clicks", scrubbed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (server *Server) scrub_expired_pii(ctx context.Context) (int64, error) {
+	cutoff := time.Now().UTC().AddDate(0, 0, -server.config.PIIRetentionDays)
+
+	var total int64
+	for {
+		scrubbed, err := server.store.Scrub_Click_PII(ctx, sqlc.Scrub_Click_PIIParams{
+			Cutoff:    sql.NullTime{Time: cutoff, Valid: true},
+			BatchSize: piiRetentionBatch,
+		})
+		total += scrubbed
+		if err != nil || scrubbed < piiRetentionBatch {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trackingSessionCookie holds the trackers of a visitor's recent clicks. It
+// is set on redirect, so it is first-party wherever the redirect and the
+// pixel share a host, such as a brand's custom domain.
+const trackingSessionCookie = "hanami_tracking_session"
+
+// maxSessionTrackers caps how many clicks the session cookie remembers; the
+// oldest are dropped first.
+const maxSessionTrackers = 10
+
+// transparentGIF is a 1x1 transparent GIF.
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00,
+	0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00,
+	0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// tracking_session is the cookie's content, in the shape the SDK sends
+// trackers to /api/conversion.
+type tracking_session struct {
+	Trackers []trackerParams `json:"trackers"`
+}
+
+// pixel_params are what the brand puts in the pixel's URL. The trackers come
+// from the session cookie instead.
+type pixel_params struct {
+	// BrandID names the brand reporting the order; the cookie holds clicks
+	// on every brand's links
+	BrandID  int64   `form:"brand_id" binding:"required,gt=0"`
+	OrderID  string  `form:"order_id" binding:"required,max=255"`
+	Amount   float64 `form:"amount" binding:"required,gt=0"`
+	Currency string  `form:"currency" binding:"required,oneof=USD INR EUR GBP JPY CAD AUD"`
+	Event    string  `form:"event" binding:"max=64"`
+}
+
+// session_trackers reads the visitor's trackers. A missing or unreadable
+// cookie is an empty session.
+func session_trackers(ctx *gin.Context) []trackerParams {
+	cookie, err := ctx.Cookie(trackingSessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	var session tracking_session
+	if err := json.Unmarshal([]byte(cookie), &session); err != nil {
+		return nil
+	}
+	return session.Trackers
+}
+
+// remember_tracker adds tracker to the visitor's session cookie, which then
+// lives as long as the campaign's lookback window. The SDK reads the cookie
+// too, so it is not HttpOnly; trackers are checked against recorded clicks
+// when they convert, so editing it gains nothing.
+func remember_tracker(ctx *gin.Context, tracker trackerParams, lookbackDays int32) {
+	trackers := []trackerParams{}
+	for _, existing := range session_trackers(ctx) {
+		if existing.ClickID != tracker.ClickID {
+			trackers = append(trackers, existing)
+		}
+	}
+	trackers = append(trackers, tracker)
+	if len(trackers) > maxSessionTrackers {
+		trackers = trackers[len(trackers)-maxSessionTrackers:]
+	}
+
+	value, err := json.Marshal(tracking_session{Trackers: trackers})
+	if err != nil {
+		log.Printf("Failed to encode tracking session: %v", err)
+		return
+	}
+	maxAge := int(lookback(lookbackDays) / time.Second)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(trackingSessionCookie, string(value), maxAge, "/", "", ctx.Request.TLS != nil, false)
+}
+
+// conversion_pixel serves GET /api/pixel.gif for checkouts that can only
+// embed an image.
+func (server *Server) conversion_pixel(ctx *gin.Context) {
+	server.record_pixel(ctx)
+	no_cache(ctx)
+	ctx.Data(http.StatusOK, "image/gif", transparentGIF)
+}
+
+// conversion_frame serves GET /api/pixel.html for checkouts that can only
+// embed an iframe.
+func (server *Server) conversion_frame(ctx *gin.Context) {
+	server.record_pixel(ctx)
+	no_cache(ctx)
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte("<!DOCTYPE html><title></title>"))
+}
+
+// record_pixel records the conversion a pixel reports. The pixel itself is
+// always served, since a page cannot act on a broken image, so the outcome is
+// only logged.
+func (server *Server) record_pixel(ctx *gin.Context) {
+	var req pixel_params
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Printf("Ignoring pixel with invalid parameters: %v", err)
+		return
+	}
+
+	brandID := req.BrandID
+	trackers := server.brand_trackers(ctx, brandID, session_trackers(ctx))
+	if len(trackers) == 0 {
+		log.Printf("Ignoring pixel for order %s of brand %d: no tracking session for the brand", req.OrderID, brandID)
+		return
+	}
+
+	if err := server.verify_pixel(ctx, brandID); err != nil {
+		log.Printf("Ignoring pixel for order %s of brand %d: %v", req.OrderID, brandID, err)
+		return
+	}
+
+	status, body := server.record_conversion(ctx, brandID, createConversionRequest{
+		OrderID:  req.OrderID,
+		Trackers: trackers,
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Event:    req.Event,
+	}, ctx.ClientIP())
+	if status != http.StatusOK {
+		log.Printf("Pixel for order %s of brand %d not recorded (%d): %v", req.OrderID, brandID, status, body["error"])
+	}
+}
+
+// brand_trackers keeps the trackers on brandID's links, so a pixel credits
+// only the brand that reports the order. Trackers whose link cannot be
+// loaded are left out.
+func (server *Server) brand_trackers(ctx *gin.Context, brandID int64, trackers []trackerParams) []trackerParams {
+	var kept []trackerParams
+	for _, tracker := range trackers {
+		resolved, err := server.resolve_link(ctx, tracker.TrackingCode)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("Failed to load tracking code %s for a pixel: %v", tracker.TrackingCode, err)
+			}
+			continue
+		}
+		if resolved.Campaign.BrandID.Valid && resolved.Campaign.BrandID.Int64 == brandID {
+			kept = append(kept, tracker)
+		}
+	}
+	return kept
+}
+
+// verify_pixel checks the pixel's signature when one is sent, and insists on
+// one if the brand asked for that. Static tags on hosted checkouts cannot be
+// signed, so unsigned pixels are accepted by default.
+func (server *Server) verify_pixel(ctx *gin.Context, brandID int64) error {
+	key, err := server.store.Get_Postback_Key(ctx, brandID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if ctx.Query("signature") == "" {
+			return nil
+		}
+		return errors.New("brand has no signing secret to check the signature with")
+	}
+	if err != nil {
+		return err
+	}
+
+	if ctx.Query("signature") == "" && !key.PixelSignatureRequired {
+		return nil
+	}
+	return verify_signature(ctx, key.SigningSecret, signed_query(ctx))
+}
+
+type pixel_settings_params struct {
+	SignatureRequired *bool `json:"signature_required" binding:"required"`
+}
+
+// update_pixel_settings decides whether the brand's pixels must be signed
+// with its postback signing secret. They need not be by default.
+func (server *Server) update_pixel_settings(ctx *gin.Context) {
+	brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+
+	var req pixel_settings_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	updated, err := server.store.Update_Pixel_Signature_Required(ctx, sqlc.Update_Pixel_Signature_RequiredParams{
+		BrandID:                brandID,
+		PixelSignatureRequired: *req.SignatureRequired,
+	})
+	if err != nil {
+		log.Printf("Failed to update pixel settings for brand %d: %v", brandID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if updated == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Brand has no postback key; create one first"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"signature_required": *req.SignatureRequired})
+}
+
+// no_cache stops browsers and proxies from answering a repeat pixel load
+// themselves.
+func no_cache(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+	ctx.Header("Pragma", "no-cache")
+	ctx.Header("Expires", "0")
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBrandTrackers(t *testing.T) {
+	server := &Server{linkCache: newLinkCache(10, time.Minute, newLocalBus())}
+	for code, brandID := range map[string]int64{"ours": 1, "also-ours": 1, "theirs": 2} {
+		server.linkCache.set(code, resolved_link{
+			Campaign: sqlc.Campaign{BrandID: sql.NullInt64{Int64: brandID, Valid: true}},
+		})
+	}
+	server.linkCache.set("no-brand", resolved_link{})
+
+	trackers := []trackerParams{
+		{ClickID: "1", TrackingCode: "theirs"},
+		{ClickID: "2", TrackingCode: "ours"},
+		{ClickID: "3", TrackingCode: "no-brand"},
+		{ClickID: "4", TrackingCode: "also-ours"},
+	}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	kept := server.brand_trackers(ctx, 1, trackers)
+	if len(kept) != 2 || kept[0].ClickID != "2" || kept[1].ClickID != "4" {
+		t.Errorf("brand_trackers() = %+v, want the trackers on brand 1's links, in order", kept)
+	}
+}
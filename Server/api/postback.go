@@ -0,0 +1,172 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"Hanami/util"
+	"bytes"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// postbackTolerance is how far a signed postback's timestamp may be from our
+// clock before it is refused as a replay.
+const postbackTolerance = 5 * time.Minute
+
+// postback_params is what a brand's server sends instead of the SDK's
+// trackers: the click_id it was handed on the landing page and the order.
+type postback_params struct {
+	ClickID  string  `form:"click_id" json:"click_id" binding:"required,uuid"`
+	OrderID  string  `form:"order_id" json:"order_id" binding:"required,max=255"`
+	Amount   float64 `form:"amount" json:"amount" binding:"required,gt=0"`
+	Currency string  `form:"currency" json:"currency" binding:"required,oneof=USD INR EUR GBP JPY CAD AUD"`
+	Event    string  `form:"event" json:"event" binding:"max=64"`
+}
+
+// create_postback records a conversion reported by a brand's server. It is
+// authenticated with the brand's API key, or with an HMAC signature over the
+// request made with the brand's signing secret.
+func (server *Server) create_postback(ctx *gin.Context) {
+	var body []byte
+	if ctx.Request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(ctx.Request.Body); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	var req postback_params
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	clickID := uuid.MustParse(req.ClickID)
+	clicks, err := server.store.Get_Tracker_Clicks(ctx, []uuid.UUID{clickID})
+	if err != nil {
+		log.Printf("Failed to load click %s for postback: %v", clickID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if len(clicks) == 0 || !clicks[0].BrandID.Valid {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Click not found"})
+		return
+	}
+	click := clicks[0]
+	brandID := click.BrandID.Int64
+
+	key, err := server.store.Get_Postback_Key(ctx, brandID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Brand has no postback key"})
+			return
+		}
+		log.Printf("Failed to load postback key for brand %d: %v", brandID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err := authenticate_postback(ctx, key.KeyHash, key.SigningSecret, body); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(server.record_conversion(ctx, brandID, createConversionRequest{
+		OrderID: req.OrderID,
+		Trackers: []trackerParams{{
+			ClickID:      clickID.String(),
+			TrackingCode: click.LinkCode,
+			Timestamp:    click.Timestamp.Time,
+		}},
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Event:    req.Event,
+	}, ""))
+}
+
+// authenticate_postback accepts either the brand's API key, in a header only,
+// or a signature. A signed GET covers its query string, less the signature
+// fields; a signed POST covers its raw body.
+func authenticate_postback(ctx *gin.Context, keyHash, secret string, body []byte) error {
+	// A key in the URL ends up in access logs and browser history
+	if _, ok := ctx.GetQuery("api_key"); ok {
+		return errors.New("send the API key in the X-Api-Key header, not the query")
+	}
+
+	if apiKey := ctx.GetHeader("X-Api-Key"); apiKey != "" {
+		if subtle.ConstantTimeCompare([]byte(util.HashAPIKey(apiKey)), []byte(keyHash)) != 1 {
+			return errors.New("invalid API key")
+		}
+		return nil
+	}
+
+	payload := body
+	if ctx.Request.Method == http.MethodGet {
+		payload = signed_query(ctx)
+	}
+	return verify_signature(ctx, secret, payload)
+}
+
+// verify_signature checks the request's signature over payload. It is sent
+// in headers or, where a page can only set a URL, in the query.
+func verify_signature(ctx *gin.Context, secret string, payload []byte) error {
+	signature := ctx.GetHeader("X-Hanami-Signature")
+	if signature == "" {
+		signature = ctx.Query("signature")
+	}
+	timestamp := ctx.GetHeader("X-Hanami-Timestamp")
+	if timestamp == "" {
+		timestamp = ctx.Query("ts")
+	}
+	if signature == "" || timestamp == "" {
+		return errors.New("a signature and timestamp are required")
+	}
+	return util.VerifyPostback(secret, timestamp, payload, signature, time.Now(), postbackTolerance)
+}
+
+// signed_query is the part of a GET's query a signature covers: every
+// parameter but the signature fields, sorted by name.
+func signed_query(ctx *gin.Context) []byte {
+	query := ctx.Request.URL.Query()
+	query.Del("signature")
+	query.Del("ts")
+	return []byte(query.Encode())
+}
+
+// create_postback_key issues the brand a new API key and signing secret,
+// replacing any it had. Neither can be retrieved again afterwards.
+func (server *Server) create_postback_key(ctx *gin.Context) {
+	brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+
+	apiKey, secret, err := util.NewPostbackCredentials()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.Upsert_Postback_Key(ctx, sqlc.Upsert_Postback_KeyParams{
+		BrandID:       brandID,
+		KeyHash:       util.HashAPIKey(apiKey),
+		SigningSecret: secret,
+	})
+	if err != nil {
+		log.Printf("Failed to store postback key for brand %d: %v", brandID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"api_key": apiKey, "signing_secret": secret})
+}
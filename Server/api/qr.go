@@ -0,0 +1,186 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"Hanami/util"
+	"bytes"
+	"database/sql"
+	"errors"
+	"image"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxBrandLogoBytes = 1 << 20
+
+var brandLogoFormats = map[string]bool{
+	"png":  true,
+	"jpeg": true,
+}
+
+type tracking_link_qr_params struct {
+	Size   int    `form:"size"`
+	Level  string `form:"level"`
+	Margin *int   `form:"margin"`
+	Logo   bool   `form:"logo"`
+}
+
+func (server *Server) get_tracking_link_qr_png(ctx *gin.Context) {
+	server.render_tracking_link_qr(ctx, "image/png", util.QRCodePNG)
+}
+
+func (server *Server) get_tracking_link_qr_svg(ctx *gin.Context) {
+	server.render_tracking_link_qr(ctx, "image/svg+xml", util.QRCodeSVG)
+}
+
+// render_tracking_link_qr encodes the link's short URL tagged with
+// utm_medium=qr, so scans show up as their own medium in analytics.
+func (server *Server) render_tracking_link_qr(ctx *gin.Context, contentType string, render func(string, util.QROptions) ([]byte, error)) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req tracking_link_qr_params
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	opts := util.QROptions{Size: util.DefaultQRSize, Margin: util.DefaultQRMargin}
+	if req.Size != 0 {
+		opts.Size = req.Size
+	}
+	if req.Margin != nil {
+		opts.Margin = *req.Margin
+	}
+	opts.Level, err = util.ParseQRLevel(req.Level)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	target, err := server.store.Get_TrackingLink_QR_Target(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Tracking link not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if req.Logo && target.BrandID.Valid {
+		logo, err := server.store.Get_BrandLogo(ctx, target.BrandID.Int64)
+		if err != nil && err != sql.ErrNoRows {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		opts.Logo = logo.Data
+		opts.LogoContentType = logo.ContentType
+	}
+
+	content := server.short_url(target.LinkCode, target.ShortDomain) + "?utm_medium=qr"
+
+	body, err := render(content, opts)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=86400")
+	ctx.Data(http.StatusOK, contentType, body)
+}
+
+func (server *Server) upload_brand_logo(ctx *gin.Context) {
+	brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	file, err := ctx.FormFile("logo")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if file.Size > maxBrandLogoBytes {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "logo must be 1MB or smaller"})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxBrandLogoBytes+1))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Trust the decoded image rather than the client's Content-Type
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !brandLogoFormats[format] {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("logo must be a PNG or JPEG image")))
+		return
+	}
+	if config.Width > util.MaxLogoDimension || config.Height > util.MaxLogoDimension {
+		ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(util.ErrLogoTooLarge))
+		return
+	}
+
+	// Store a downscaled PNG once so QR renders never decode the original
+	data, err = util.PrepareQRLogo(data)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	brand_exists, err := server.store.Brand_Exists_By_Id(ctx, brandID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !brand_exists {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "brand with this id doesn't exists"})
+		return
+	}
+
+	args := sqlc.Upsert_BrandLogoParams{
+		BrandID:     brandID,
+		ContentType: "image/png",
+		Data:        data,
+	}
+
+	if err := server.store.Upsert_BrandLogo(ctx, args); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logo uploaded successfully"})
+}
+
+func (server *Server) delete_brand_logo(ctx *gin.Context) {
+	brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := server.store.Delete_BrandLogo(ctx, brandID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logo deleted successfully"})
+}
@@ -0,0 +1,402 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"Hanami/util"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reattributionBatch is how many sales are re-weighted per transaction.
+const reattributionBatch = 100
+
+// attributionJobStale is how long an unfinished job may go without progress
+// before another job for the brand may replace it.
+const attributionJobStale = 30 * time.Minute
+
+const attributionDateLayout = "2006-01-02"
+
+type attribution_range_params struct {
+	Model         string `form:"model" json:"model" binding:"required"`
+	HalfLifeHours int32  `form:"half_life_hours" json:"half_life_hours" binding:"omitempty,min=1"`
+	// Conversions from the start of From up to the end of To, both UTC days
+	From string `form:"from" json:"from" binding:"required,datetime=2006-01-02"`
+	To   string `form:"to" json:"to" binding:"required,datetime=2006-01-02"`
+}
+
+type reattribute_params struct {
+	attribution_range_params
+	DryRun bool `json:"dry_run"`
+}
+
+type attribution_totals struct {
+	Revenue    float64 `json:"revenue"`
+	Commission float64 `json:"commission"`
+}
+
+type attribution_delta struct {
+	ID       int64              `json:"id"`
+	Current  attribution_totals `json:"current"`
+	Proposed attribution_totals `json:"proposed"`
+	Change   attribution_totals `json:"change"`
+}
+
+type attribution_comparison struct {
+	Model       string `json:"model"`
+	Sales       int    `json:"sales"`
+	Conversions int    `json:"conversions"`
+	// SkippedSales lost a click or link since they were recorded and are
+	// left as they are
+	SkippedSales int                 `json:"skipped_sales"`
+	Affiliates   []attribution_delta `json:"affiliates"`
+	Campaigns    []attribution_delta `json:"campaigns"`
+}
+
+// resolve parses the requested model and the range as [from, to), where to
+// is the day after the To date.
+func (params attribution_range_params) resolve(server *Server) (util.AttributionModel, time.Time, time.Time, error) {
+	from, err := time.Parse(attributionDateLayout, params.From)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	to, err := time.Parse(attributionDateLayout, params.To)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	if to.Before(from) {
+		return nil, time.Time{}, time.Time{}, errors.New("to must not be before from")
+	}
+
+	model, err := server.new_attribution_model(params.Model, sql.NullInt32{Int32: params.HalfLifeHours, Valid: params.HalfLifeHours > 0})
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	return model, from, to.AddDate(0, 0, 1), nil
+}
+
+// load_paths returns the brand's sales in [from, to) whose paths are whole,
+// and how many were skipped because a click or link in the path is gone.
+// Re-weighting what is left of such a path would hand the missing touches'
+// credit to the others.
+func (server *Server) load_paths(ctx context.Context, brandID int64, from, to time.Time) ([]sqlc.Get_Attribution_PathsRow, int, error) {
+	paths, err := server.store.Get_Attribution_Paths(ctx, sqlc.Get_Attribution_PathsParams{
+		BrandID:  brandID,
+		FromTime: from,
+		ToTime:   to,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	complete := paths[:0]
+	skipped := 0
+	for start := 0; start < len(paths); {
+		end := start
+		whole := true
+		for end < len(paths) && paths[end].SaleID == paths[start].SaleID {
+			whole = whole && paths[end].ClickedAt.Valid && paths[end].CommissionRate.Valid
+			end++
+		}
+
+		if whole {
+			complete = append(complete, paths[start:end]...)
+		} else {
+			skipped++
+		}
+		start = end
+	}
+	return complete, skipped, nil
+}
+
+// reweigh replays each sale's path under model. The weights line up with
+// paths, whose rows are grouped by sale, oldest click first.
+func reweigh(paths []sqlc.Get_Attribution_PathsRow, model util.AttributionModel) []float64 {
+	weights := make([]float64, len(paths))
+	for start := 0; start < len(paths); {
+		end := start
+		for end < len(paths) && paths[end].SaleID == paths[start].SaleID {
+			end++
+		}
+
+		touches := make([]time.Time, end-start)
+		for i := range touches {
+			touches[i] = paths[start+i].ClickedAt.Time
+		}
+		copy(weights[start:end], model.Weights(touches, paths[start].ConvertedAt.Time))
+		start = end
+	}
+	return weights
+}
+
+// compare_paths totals revenue and commission per affiliate and campaign under
+// the stored weights and under proposed ones. Conversions the brand cleared as
+// fraud earn nothing either way.
+func compare_paths(model util.AttributionModel, paths []sqlc.Get_Attribution_PathsRow, proposed []float64) attribution_comparison {
+	affiliates := map[int64]*attribution_delta{}
+	campaigns := map[int64]*attribution_delta{}
+	sales := map[int64]bool{}
+
+	add := func(totals map[int64]*attribution_delta, id int64, row sqlc.Get_Attribution_PathsRow, rate float64, weight float64) {
+		delta, ok := totals[id]
+		if !ok {
+			delta = &attribution_delta{ID: id}
+			totals[id] = delta
+		}
+		current := row.Amount * row.Weight.Float64
+		next := row.Amount * weight
+		delta.Current.Revenue += current
+		delta.Current.Commission += current * rate / 100
+		delta.Proposed.Revenue += next
+		delta.Proposed.Commission += next * rate / 100
+	}
+
+	for i, row := range paths {
+		sales[row.SaleID] = true
+		if row.Cleared {
+			continue
+		}
+		rate, err := strconv.ParseFloat(row.CommissionRate.String, 64)
+		if err != nil {
+			log.Printf("Invalid commission rate %q on campaign %d", row.CommissionRate.String, row.CampaignID.Int64)
+			continue
+		}
+		add(affiliates, row.AffiliateID.Int64, row, rate, proposed[i])
+		add(campaigns, row.CampaignID.Int64, row, rate, proposed[i])
+	}
+
+	return attribution_comparison{
+		Model:       model.Name(),
+		Sales:       len(sales),
+		Conversions: len(paths),
+		Affiliates:  sorted_deltas(affiliates),
+		Campaigns:   sorted_deltas(campaigns),
+	}
+}
+
+func sorted_deltas(totals map[int64]*attribution_delta) []attribution_delta {
+	deltas := make([]attribution_delta, 0, len(totals))
+	for _, delta := range totals {
+		delta.Change = attribution_totals{
+			Revenue:    delta.Proposed.Revenue - delta.Current.Revenue,
+			Commission: delta.Proposed.Commission - delta.Current.Commission,
+		}
+		deltas = append(deltas, *delta)
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].ID < deltas[j].ID })
+	return deltas
+}
+
+// compare_attribution shows how a brand's commissions over a date range
+// would change had its sales been split under another model.
+func (server *Server) compare_attribution(ctx *gin.Context) {
+	brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+
+	var req attribution_range_params
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	model, from, to, err := req.resolve(server)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	paths, skipped, err := server.load_paths(ctx, brandID, from, to)
+	if err != nil {
+		log.Printf("Failed to load attribution paths for brand %d: %v", brandID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	comparison := compare_paths(model, paths, reweigh(paths, model))
+	comparison.SkippedSales = skipped
+	ctx.JSON(http.StatusOK, comparison)
+}
+
+// reattribute re-weights a brand's conversions over a date range under a
+// model. A dry run only returns the comparison it would apply; otherwise the
+// work runs as a background job whose progress is at /api/attribution/jobs/:id.
+func (server *Server) reattribute(ctx *gin.Context) {
+	brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+
+	var req reattribute_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	model, from, to, err := req.resolve(server)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.DryRun {
+		paths, skipped, err := server.load_paths(ctx, brandID, from, to)
+		if err != nil {
+			log.Printf("Failed to load attribution paths for brand %d: %v", brandID, err)
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		comparison := compare_paths(model, paths, reweigh(paths, model))
+		comparison.SkippedSales = skipped
+		ctx.JSON(http.StatusOK, gin.H{
+			"dry_run":    true,
+			"comparison": comparison,
+		})
+		return
+	}
+
+	err = server.store.Abandon_Attribution_Jobs(ctx, sqlc.Abandon_Attribution_JobsParams{
+		BrandID:     brandID,
+		StaleBefore: time.Now().UTC().Add(-attributionJobStale),
+	})
+	if err != nil {
+		log.Printf("Failed to abandon stale attribution jobs for brand %d: %v", brandID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// One job per brand at a time; the unique index settles concurrent requests
+	job, err := server.store.Create_Attribution_Job(ctx, sqlc.Create_Attribution_JobParams{
+		BrandID:                  brandID,
+		AttributionModel:         model.Name(),
+		AttributionHalfLifeHours: sql.NullInt32{Int32: req.HalfLifeHours, Valid: req.HalfLifeHours > 0},
+		FromTime:                 from,
+		ToTime:                   to,
+	})
+	if isUniqueViolation(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A re-attribution job is already running for this brand"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create attribution job for brand %d: %v", brandID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	go server.run_attribution_job(server.jobs, job, model)
+
+	ctx.JSON(http.StatusAccepted, gin.H{"job": job})
+}
+
+// run_attribution_job re-weights every sale in the job's range, a batch of
+// whole sales per transaction, and records how it went.
+func (server *Server) run_attribution_job(ctx context.Context, job sqlc.AttributionJob, model util.AttributionModel) {
+	status := sqlc.Update_Attribution_JobParams{ID: job.ID, Status: "running"}
+	if err := server.store.Update_Attribution_Job(ctx, status); err != nil {
+		log.Printf("Failed to start attribution job %d: %v", job.ID, err)
+		return
+	}
+
+	updated, skipped, err := server.reattribute_range(ctx, job, model)
+	status = sqlc.Update_Attribution_JobParams{
+		ID:                 job.ID,
+		Status:             "done",
+		ConversionsUpdated: int32(updated),
+		FinishedAt:         sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		SalesSkipped:       int32(skipped),
+	}
+	if err != nil {
+		log.Printf("Attribution job %d failed after %d conversions: %v", job.ID, updated, err)
+		status.Status = "failed"
+		status.Error = nullString(err.Error())
+	}
+
+	// Record the outcome even when shutdown cancelled the job
+	if err := server.store.Update_Attribution_Job(context.Background(), status); err != nil {
+		log.Printf("Failed to record attribution job %d: %v", job.ID, err)
+	}
+}
+
+// reattribute_range returns how many conversions it re-weighted and how many
+// sales it skipped for an incomplete path. Progress is recorded after every
+// batch, which also keeps the job from being taken for abandoned.
+func (server *Server) reattribute_range(ctx context.Context, job sqlc.AttributionJob, model util.AttributionModel) (int, int, error) {
+	paths, skipped, err := server.load_paths(ctx, job.BrandID, job.FromTime, job.ToTime)
+	if err != nil {
+		return 0, 0, err
+	}
+	if skipped > 0 {
+		log.Printf("Attribution job %d skips %d sales with incomplete paths", job.ID, skipped)
+	}
+	weights := reweigh(paths, model)
+
+	updated := 0
+	var batch []sqlc.Update_Conversion_AttributionParams
+	sales := 0
+	for i, row := range paths {
+		batch = append(batch, sqlc.Update_Conversion_AttributionParams{
+			ID:               row.ConversionID,
+			Weight:           sql.NullFloat64{Float64: weights[i], Valid: true},
+			AttributionModel: nullString(model.Name()),
+		})
+
+		lastOfSale := i == len(paths)-1 || paths[i+1].SaleID != row.SaleID
+		if !lastOfSale {
+			continue
+		}
+		if sales++; sales < reattributionBatch && i < len(paths)-1 {
+			continue
+		}
+
+		if err := server.store.Update_Conversion_Attribution_Batch(ctx, batch); err != nil {
+			return updated, skipped, err
+		}
+		updated += len(batch)
+		batch, sales = batch[:0], 0
+
+		err := server.store.Update_Attribution_Job(ctx, sqlc.Update_Attribution_JobParams{
+			ID:                 job.ID,
+			Status:             "running",
+			ConversionsUpdated: int32(updated),
+			SalesSkipped:       int32(skipped),
+		})
+		if err != nil {
+			log.Printf("Failed to record progress of attribution job %d: %v", job.ID, err)
+		}
+	}
+	return updated, skipped, nil
+}
+
+func (server *Server) get_attribution_job(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := server.store.Get_Attribution_Job(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Attribution job not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !server.require_brand(ctx, job.BrandID) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"job": job})
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sale statuses. A sale's conversions always share its status. Pending sales
+// become approved once their campaign's approval delay has passed, unless the
+// brand rejects them first; approved sales can still be reversed.
+const (
+	salePending  = "pending"
+	saleApproved = "approved"
+	saleRejected = "rejected"
+	saleReversed = "reversed"
+)
+
+// approval is the status a sale converting at convertedAt starts in, and when
+// it is due to be approved if it must wait.
+func approval(delayDays int32, convertedAt time.Time) (string, sql.NullTime) {
+	if delayDays <= 0 {
+		return saleApproved, sql.NullTime{}
+	}
+	return salePending, sql.NullTime{Time: convertedAt.AddDate(0, 0, int(delayDays)), Valid: true}
+}
+
+// include_pending reports whether analytics and commissions count pending
+// conversions next to approved ones. COUNT_PENDING_CONVERSIONS sets the
+// default and ?include_pending= overrides it.
+func (server *Server) include_pending(ctx *gin.Context) bool {
+	include, err := strconv.ParseBool(ctx.Query("include_pending"))
+	if err != nil {
+		return server.config.CountPendingConvs
+	}
+	return include
+}
+
+// run_conversion_approval approves sales and events whose approval delay has
+// passed, once at start and then every CONVERSION_APPROVAL_INTERVAL, until ctx is
+// cancelled.
+func (server *Server) run_conversion_approval(ctx context.Context) {
+	if server.config.ApprovalInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(server.config.ApprovalInterval)
+	defer ticker.Stop()
+
+	for {
+		approved, err := server.store.Approve_Due_Sales(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to approve due sales: %v", err)
+		} else if approved > 0 {
+			log.Printf("Approved %d sales past their approval delay", approved)
+		}
+
+		approved, err = server.store.Approve_Due_Events(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to approve due events: %v", err)
+		} else if approved > 0 {
+			log.Printf("Approved %d events past their approval delay", approved)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type list_sales_params struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending approved rejected reversed"`
+}
+
+func (server *Server) list_sales(ctx *gin.Context) {
+	brandID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid brand ID"})
+		return
+	}
+
+	var req list_sales_params
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	sales, err := server.store.List_Sales_By_Brand(ctx, sqlc.List_Sales_By_BrandParams{
+		BrandID: brandID,
+		Status:  nullString(req.Status),
+	})
+	if err != nil {
+		log.Printf("Failed to list sales for brand %d: %v", brandID, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"sales": sales})
+}
+
+type sale_status_params struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// approve_sale approves a pending sale without waiting out its delay.
+func (server *Server) approve_sale(ctx *gin.Context) {
+	server.update_sale_status(ctx, salePending, saleApproved)
+}
+
+// reject_sale rejects a pending sale, e.g. a cancelled order. Its
+// conversions never earn commission.
+func (server *Server) reject_sale(ctx *gin.Context) {
+	server.update_sale_status(ctx, salePending, saleRejected)
+}
+
+// reverse_sale reverses an approved sale, e.g. an order returned after the
+// approval delay, taking back its commission.
+func (server *Server) reverse_sale(ctx *gin.Context) {
+	server.update_sale_status(ctx, saleApproved, saleReversed)
+}
+
+func (server *Server) update_sale_status(ctx *gin.Context, from string, to string) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sale ID"})
+		return
+	}
+
+	// Approving needs no reason, so the body may be left out
+	var req sale_status_params
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+	if to != saleApproved && req.Reason == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+	if !server.require_sale_brand(ctx, id) {
+		return
+	}
+
+	sale, err := server.store.UpdateSaleStatusTx(ctx, sqlc.Update_Sale_StatusParams{
+		Status:       to,
+		StatusReason: nullString(req.Reason),
+		ID:           id,
+		FromStatus:   from,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		server.sale_conflict(ctx, id, "Only "+from+" sales can be "+to)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to mark sale %d %s: %v", id, to, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"sale": sale})
+}
+
+type refund_sale_params struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Reason string  `json:"reason" binding:"required,max=500"`
+}
+
+// refund_sale refunds part or all of a pending or approved sale. Its
+// conversions are scaled down to the amount kept, so commission follows; a
+// full refund reverses the sale.
+func (server *Server) refund_sale(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sale ID"})
+		return
+	}
+
+	var req refund_sale_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !server.require_sale_brand(ctx, id) {
+		return
+	}
+
+	sale, err := server.store.RefundSaleTx(ctx, sqlc.Refund_SaleParams{
+		Amount:       strconv.FormatFloat(req.Amount, 'f', -1, 64),
+		StatusReason: nullString(req.Reason),
+		ID:           id,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		server.sale_conflict(ctx, id, "Refund is more than is left of the sale, or the sale is rejected or reversed")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to refund sale %d: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"sale": sale})
+}
+
+// require_sale_brand answers 404 or 403 and reports false unless sale id
+// exists and belongs to the caller's brand.
+func (server *Server) require_sale_brand(ctx *gin.Context, id int64) bool {
+	sale, err := server.store.Get_Sale(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Sale not found"})
+		return false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	return server.require_brand(ctx, sale.BrandID)
+}
+
+// sale_conflict answers a change the sale's state did not allow, or a 404
+// when there is no such sale.
+func (server *Server) sale_conflict(ctx *gin.Context, id int64, message string) {
+	sale, err := server.store.Get_Sale(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Sale not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusConflict, gin.H{"error": message, "sale": sale})
+}
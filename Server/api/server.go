@@ -3,17 +3,42 @@ package api
 import (
 	"Hanami/sqlc"
 	"Hanami/util"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
 type Server struct {
-	router     *gin.Engine
-	store      *sqlc.Store
-	config     util.Config
-	tokenMaker util.Maker
+	router        *gin.Engine
+	store         *sqlc.Store
+	config        util.Config
+	tokenMaker    util.Maker
+	codeGenerator *util.CodeGenerator
+
+	shortLinkBase  string
+	shortLinkHosts map[string]bool
+
+	clickQueue    *clickQueue
+	linkCache     *linkCache
+	botClassifier *util.BotClassifier
+	clickDeduper  *clickDeduper
+	geoIP         *util.GeoIP
+	ipAnonymizer  *util.IPAnonymizer
+	fraudEngine   *fraudEngine
+
+	// jobs is cancelled on shutdown to stop background work
+	jobs     context.Context
+	stopJobs context.CancelFunc
 }
 
 func NewServer(store *sqlc.Store, config util.Config) (*Server, error) {
@@ -22,7 +47,76 @@ func NewServer(store *sqlc.Store, config util.Config) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	server := &Server{store: store, config: config, tokenMaker: tokenMaker}
+
+	codeGenerator, err := util.NewCodeGenerator(config.TrackingCodeAlphabet, config.TrackingCodeLength)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{store: store, config: config, tokenMaker: tokenMaker, codeGenerator: codeGenerator}
+	server.jobs, server.stopJobs = context.WithCancel(context.Background())
+
+	server.shortLinkBase = strings.TrimRight(config.ShortLinkBaseUrl, "/")
+	if server.shortLinkBase == "" {
+		server.shortLinkBase = strings.TrimRight(config.Api_Url, "/") + "/r"
+	}
+
+	server.shortLinkHosts = map[string]bool{}
+	for _, host := range splitList(config.ShortLinkHosts) {
+		server.shortLinkHosts[strings.ToLower(host)] = true
+	}
+	if apiURL, err := url.Parse(config.Api_Url); err == nil && apiURL.Hostname() != "" {
+		server.shortLinkHosts[strings.ToLower(apiURL.Hostname())] = true
+	}
+
+	server.clickQueue, err = newClickQueue(store, click_queue_config{
+		Size:          config.ClickQueueSize,
+		Workers:       config.ClickQueueWorkers,
+		BatchSize:     config.ClickBatchSize,
+		FlushInterval: config.ClickFlushInterval,
+		Policy:        config.ClickQueuePolicy,
+		BlockTimeout:  config.ClickBlockTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var bus invalidationBus = newLocalBus()
+	if config.RedisUrl != "" {
+		if bus, err = newRedisBus(config.RedisUrl); err != nil {
+			return nil, err
+		}
+	}
+	server.linkCache = newLinkCache(config.LinkCacheSize, config.LinkCacheTTL, bus)
+
+	server.botClassifier, err = util.NewBotClassifier(splitList(config.BotIPRangeFiles))
+	if err != nil {
+		return nil, err
+	}
+
+	server.clickDeduper = newClickDeduper(config.ClickDedupCacheSize, config.ClickDedupWindow, config.TokenSymmetricKey)
+
+	server.geoIP, err = util.NewGeoIP(config.GeoIPDbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	server.ipAnonymizer, err = util.NewIPAnonymizer(config.IPPrivacyMode, config.IPHashKey, config.IPHashRotation)
+	if err != nil {
+		return nil, err
+	}
+
+	server.fraudEngine, err = newFraudEngine(fraud_config{
+		Threshold:          config.FraudFlagThreshold,
+		IPClicksPerMinute:  config.FraudIPClicksPerMin,
+		LinkClicksPerHour:  config.FraudLinkClicksPerHr,
+		MinConversionDelay: config.FraudMinConvDelay,
+		ASNDbPath:          config.FraudASNDbPath,
+		DatacenterASNFile:  config.FraudDatacenterASNs,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	router := gin.Default()
 
@@ -33,6 +127,16 @@ func NewServer(store *sqlc.Store, config util.Config) (*Server, error) {
 		AllowCredentials: true,
 	}))
 
+	// auth requires a signed-in user
+	auth := authMiddleware(server.tokenMaker)
+	// brandOwner also requires that the caller owns brand :id
+	brandOwner := server.brandOwnerMiddleware()
+	// linkOwner also requires that the caller owns tracking link :id's brand
+	// or is its affiliate
+	linkOwner := server.linkOwnerMiddleware()
+	// campaignOwner also requires that the caller owns campaign :id's brand
+	campaignOwner := server.campaignOwnerMiddleware()
+
 	//User
 	router.POST("/api/user/affiliate", server.create_user_affiliate)
 	router.POST("/api/user/brand", server.create_user_brand)
@@ -52,13 +156,39 @@ func NewServer(store *sqlc.Store, config util.Config) (*Server, error) {
 	router.GET("/api/brand/campaign/:id", server.get_campaign_by_brandId)
 	router.GET("/api/affiliate/campaign/:id", server.get_campaign_for_affiliate)
 	router.DELETE("/api/campaign/:id", server.delete_campaign_by_id)
+	router.PUT("/api/campaign/:id/settings", auth, campaignOwner, server.update_campaign_settings)
+	router.GET("/api/campaign/:id/commissions", server.get_campaign_commissions)
+	router.GET("/api/campaign/:id/events", server.list_event_types)
+	router.POST("/api/campaign/:id/events", auth, campaignOwner, server.upsert_event_type)
+	router.GET("/api/campaign/:id/activity", server.get_campaign_activity)
 
 	//Invite
 	router.POST("/api/brand/campaign/invite", server.send_invite)
 	router.GET("/api/brand/campaign/invite/:id", server.get_invites_by_user_id)
 
+	//Brand-Domain
+	router.POST("/api/brand/domain", auth, server.create_brand_domain)
+	router.GET("/api/brand/domain/:id", auth, server.get_brand_domains_by_brand_id)
+	router.DELETE("/api/brand/domain/:id", auth, server.delete_brand_domain_by_id)
+	router.POST("/api/brand/domain/:id/verify", auth, server.verify_brand_domain)
+	router.POST("/api/brand/:id/logo", auth, brandOwner, server.upload_brand_logo)
+	router.DELETE("/api/brand/:id/logo", auth, brandOwner, server.delete_brand_logo)
+	router.PUT("/api/brand/:id/attribution", auth, brandOwner, server.update_brand_attribution)
+	router.GET("/api/brand/:id/attribution/compare", auth, brandOwner, server.compare_attribution)
+	router.POST("/api/brand/:id/attribution/reattribute", auth, brandOwner, server.reattribute)
+	router.GET("/api/attribution/jobs/:id", auth, server.get_attribution_job)
+	router.POST("/api/brand/:id/postback/key", auth, brandOwner, server.create_postback_key)
+	router.PUT("/api/brand/:id/pixel", auth, brandOwner, server.update_pixel_settings)
+
+	//Fraud
+	router.GET("/api/brand/:id/fraud", auth, brandOwner, server.list_fraud_flags)
+	router.POST("/api/fraud/:id/confirm", auth, server.confirm_fraud_flag)
+	router.POST("/api/fraud/:id/clear", auth, server.clear_fraud_flag)
+
 	//Campaign-Affiliate
 	router.POST("/api/brand/campaign/affiliate", server.create_campaign_affiliate)
+	router.GET("/api/campaign/:id/affiliate/:affiliate_id/links", server.list_sub_links)
+	router.POST("/api/campaign/:id/affiliate/:affiliate_id/links", server.create_sub_link)
 
 	//Tracking-Link
 	router.POST("/api/brand/campaign/tracking/new", server.create_tracking_link)
@@ -67,12 +197,33 @@ func NewServer(store *sqlc.Store, config util.Config) (*Server, error) {
 	router.GET("/api/affiliate/tracking/:id", server.get_tracking_links_by_affiliate_id)
 	router.POST("/api/brand/campaign/tracking/:id", server.delete_tracking_link_by_id)
 	router.GET("/api/campaign/affiliate/tracking", server.get_tracking_link_for_affiliate)
+	router.POST("/api/tracking/:id/pause", auth, linkOwner, server.pause_tracking_link)
+	router.POST("/api/tracking/:id/resume", auth, linkOwner, server.resume_tracking_link)
+	router.PUT("/api/tracking/:id/limits", auth, linkOwner, server.update_tracking_link_limits)
+	router.PUT("/api/tracking/:id/dedup", auth, linkOwner, server.update_tracking_link_dedup)
+	router.GET("/api/tracking/:id/qr.png", server.get_tracking_link_qr_png)
+	router.GET("/api/tracking/:id/qr.svg", server.get_tracking_link_qr_svg)
 
 	//Redirect-URL-MAIN
 	router.GET("/", server.redirect_user)
+	router.GET("/r/:code", server.redirect_short_link)
+	router.GET("/:code", server.redirect_host_short_link)
 
 	//Conversions
 	router.POST("/api/conversion", server.create_conversion)
+	router.POST("/api/event", server.create_event)
+	router.GET("/api/postback", server.create_postback)
+	router.POST("/api/postback", server.create_postback)
+	router.GET("/api/pixel.gif", server.conversion_pixel)
+	router.GET("/api/pixel.html", server.conversion_frame)
+	router.GET("/api/brand/:id/sales", auth, brandOwner, server.list_sales)
+	router.POST("/api/sale/:id/approve", auth, server.approve_sale)
+	router.POST("/api/sale/:id/reject", auth, server.reject_sale)
+	router.POST("/api/sale/:id/reverse", auth, server.reverse_sale)
+	router.POST("/api/sale/:id/refund", auth, server.refund_sale)
+	router.POST("/api/event/:id/approve", auth, server.approve_event)
+	router.POST("/api/event/:id/reject", auth, server.reject_event)
+	router.POST("/api/event/:id/reverse", auth, server.reverse_event)
 
 	//Analytics
 	router.GET("/api/keymetrics", server.get_Brand_Key_Metrics)
@@ -83,12 +234,16 @@ func NewServer(store *sqlc.Store, config util.Config) (*Server, error) {
 	router.GET("/api/metricsTime", server.get_MetricsOverTime)
 	router.GET("/api/campaignSpecific", server.get_Campaign_Specific)
 	router.GET("/api/revenueDetails", server.get_Revenue_Details)
+	router.GET("/api/subLinks", server.get_SubLink_Breakdown)
+	router.GET("/api/metrics/clicks", server.get_click_queue_metrics)
+	router.GET("/api/metrics/linkCache", server.get_link_cache_metrics)
 	
 	// New consolidated tab-based analytics endpoints
 	router.GET("/api/analytics/campaign-analysis", server.getCampaignAnalysisTabData)
 	router.GET("/api/analytics/audience-insights", server.getAudienceInsightsTabData)
 	router.GET("/api/analytics/revenue-stats", server.getRevenueStatsTabData)
 	router.GET("/api/analytics/overview", server.getOverviewTabData)
+	router.GET("/api/analytics/geo", server.get_geo_breakdown)
 
 	server.router = router
 
@@ -96,10 +251,81 @@ func NewServer(store *sqlc.Store, config util.Config) (*Server, error) {
 
 }
 
+// Init serves until SIGINT or SIGTERM, then stops taking requests and flushes
+// buffered clicks before returning.
 func (server *Server) Init(address string) error {
-	return server.router.Run(address)
+	srv := &http.Server{Addr: address, Handler: server.router}
+
+	defer server.stopJobs()
+	go server.run_pii_retention(server.jobs)
+	go server.run_conversion_approval(server.jobs)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			server.clickQueue.close(context.Background())
+			return err
+		}
+	case sig := <-stop:
+		log.Printf("Received %s, shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), server.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("HTTP shutdown: %v", err)
+	}
+
+	err := server.clickQueue.close(ctx)
+	server.linkCache.bus.Close()
+	server.geoIP.Close()
+	server.fraudEngine.Close()
+	return err
+}
+
+func (server *Server) get_click_queue_metrics(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"clickQueue": server.clickQueue.stats(),
+		"clickDedup": server.clickDeduper.stats(),
+	})
+}
+
+// splitList splits a comma-separated config value, dropping blank entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// isUniqueViolation reports whether err is a Postgres unique constraint failure.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// violatedConstraint names the constraint a Postgres error tripped, if any.
+func violatedConstraint(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Constraint
+	}
+	return ""
+}
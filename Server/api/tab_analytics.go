@@ -40,13 +40,13 @@ func (server *Server) getCampaignAnalysisTabData(ctx *gin.Context) {
 				c.name AS campaign,
 				COUNT(DISTINCT cl.id) AS total_clicks,
 				COUNT(DISTINCT conv.id) AS total_conversions,
-				COALESCE(SUM(s.amount), 0) AS total_sales,
+				COALESCE(SUM(s.amount - s.refunded_amount), 0) AS total_sales,
 				COUNT(DISTINCT tl.id) AS total_tracking_links
 			FROM campaigns c
 			LEFT JOIN tracking_links tl ON c.id = tl.campaign_id
-			LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id
-			LEFT JOIN conversions conv ON cl.click_id = conv.click_id
-			LEFT JOIN sales s ON c.brand_id = s.brand_id
+			LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id AND ($2::boolean OR NOT cl.is_bot)
+			LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($3::boolean AND conv.status = 'pending'))
+			LEFT JOIN sales s ON c.brand_id = s.brand_id AND (s.status = 'approved' OR ($3::boolean AND s.status = 'pending'))
 			WHERE c.brand_id = $1
 			GROUP BY c.id, c.name
 		)
@@ -58,7 +58,7 @@ func (server *Server) getCampaignAnalysisTabData(ctx *gin.Context) {
 		FROM campaign_metrics
 		WHERE total_clicks > 0 OR total_conversions > 0 OR total_sales > 0
 		ORDER BY campaign
-	`, brandId)
+	`, brandId, include_bots(ctx), server.include_pending(ctx))
 	
 	var effectivenessData []map[string]interface{}
 	
@@ -89,7 +89,7 @@ func (server *Server) getCampaignAnalysisTabData(ctx *gin.Context) {
 	}
 
 	// Get campaign specific effectiveness data
-	campaignSpecificData, err := server.store.GetCampaign_Specific_Effectiveness(ctx, convertedId)
+	campaignSpecificData, err := server.store.GetCampaign_Specific_Effectiveness(ctx, sqlc.GetCampaign_Specific_EffectivenessParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		log.Printf("Failed to get campaign specific data: %v", err)
 		// Continue execution instead of returning early
@@ -99,7 +99,7 @@ func (server *Server) getCampaignAnalysisTabData(ctx *gin.Context) {
 	}
 
 	// Get metrics over time data
-	metricsTimeData, err := server.store.Get_MetricsOverTime(ctx, convertedId)
+	metricsTimeData, err := server.store.Get_MetricsOverTime(ctx, sqlc.Get_MetricsOverTimeParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		log.Printf("Failed to get metrics over time: %v", err)
 		// Continue execution instead of returning early
@@ -143,7 +143,7 @@ func (server *Server) getAudienceInsightsTabData(ctx *gin.Context) {
 	hasData := false
 
 	// Get UTM source data
-	utmSourceData, err := server.store.Get_UTMSource_Counts(ctx, convertedId)
+	utmSourceData, err := server.store.Get_UTMSource_Counts(ctx, sqlc.Get_UTMSource_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
 	if err != nil {
 		log.Printf("Failed to get UTM source data: %v", err)
 		// Continue execution instead of returning early
@@ -153,7 +153,7 @@ func (server *Server) getAudienceInsightsTabData(ctx *gin.Context) {
 	}
 
 	// Get UTM medium data
-	utmMediumData, err := server.store.Get_UTMMedium_Counts(ctx, convertedId)
+	utmMediumData, err := server.store.Get_UTMMedium_Counts(ctx, sqlc.Get_UTMMedium_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
 	if err != nil {
 		log.Printf("Failed to get UTM medium data: %v", err)
 		// Continue execution instead of returning early
@@ -162,77 +162,81 @@ func (server *Server) getAudienceInsightsTabData(ctx *gin.Context) {
 		hasData = true
 	}
 
-	// Get device stats from database (real data)
-	var deviceStats []map[string]interface{}
-	rows, err := server.store.GetDB().QueryContext(ctx, `
-		SELECT 
-			COALESCE(device_type, 'Unknown') AS name,
-			COUNT(*) AS value
-		FROM clicks cl
-		JOIN tracking_links tl ON cl.tracking_link_id = tl.id
-		JOIN campaigns c ON tl.campaign_id = c.id
-		WHERE c.brand_id = $1
-		GROUP BY device_type
-		ORDER BY value DESC
-	`, brandId)
-	
+	// Device, OS and browser come from user agents parsed at ingest
+	deviceStats := []map[string]interface{}{}
+	deviceData, err := server.store.Get_DeviceType_Counts(ctx, sqlc.Get_DeviceType_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
 	if err != nil {
 		log.Printf("Failed to fetch device stats: %v", err)
-		// Fallback to mock data
-		deviceStats = []map[string]interface{}{
-			{"name": "Mobile", "value": 55},
-			{"name": "Desktop", "value": 35},
-			{"name": "Tablet", "value": 10},
+	} else {
+		for _, row := range deviceData {
+			deviceStats = append(deviceStats, map[string]interface{}{"name": row.Name, "value": row.Value})
 		}
+		hasData = true
+	}
+
+	osStats := []map[string]interface{}{}
+	osData, err := server.store.Get_OS_Counts(ctx, sqlc.Get_OS_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
+	if err != nil {
+		log.Printf("Failed to fetch OS stats: %v", err)
 	} else {
-		defer rows.Close()
-		
-		for rows.Next() {
-			var name string
-			var value int64
-			
-			if err := rows.Scan(&name, &value); err != nil {
-				log.Printf("Error scanning device stats row: %v", err)
-				continue
-			}
-			
-			deviceStats = append(deviceStats, map[string]interface{}{
-				"name": name,
-				"value": value,
-			})
+		for _, row := range osData {
+			osStats = append(osStats, map[string]interface{}{"name": row.Name, "value": row.Value})
 		}
-		
-		// If we got no results, use mock data
-		if len(deviceStats) == 0 {
-			deviceStats = []map[string]interface{}{
-				{"name": "Mobile", "value": 55},
-				{"name": "Desktop", "value": 35},
-				{"name": "Tablet", "value": 10},
-			}
+		hasData = true
+	}
+
+	browserStats := []map[string]interface{}{}
+	browserData, err := server.store.Get_Browser_Counts(ctx, sqlc.Get_Browser_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
+	if err != nil {
+		log.Printf("Failed to fetch browser stats: %v", err)
+	} else {
+		for _, row := range browserData {
+			browserStats = append(browserStats, map[string]interface{}{"name": row.Name, "value": row.Value})
+		}
+		hasData = true
+	}
+
+	referrerPlatforms := []map[string]interface{}{}
+	platformData, err := server.store.Get_Referrer_Platform_Counts(ctx, sqlc.Get_Referrer_Platform_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
+	if err != nil {
+		log.Printf("Failed to fetch referrer platforms: %v", err)
+	} else {
+		for _, row := range platformData {
+			referrerPlatforms = append(referrerPlatforms, map[string]interface{}{"name": row.Name, "value": row.Value})
 		}
 		hasData = true
 	}
 
-	// Mock data for audience breakdown (would be replaced with real data in production)
-	audienceBreakdown := []map[string]interface{}{
-		{"name": "Age 18-24", "value": 15},
-		{"name": "Age 25-34", "value": 35},
-		{"name": "Age 35-44", "value": 25},
-		{"name": "Age 45-54", "value": 15},
-		{"name": "Age 55+", "value": 10},
+	referrerHosts := []map[string]interface{}{}
+	hostData, err := server.store.Get_Referrer_Host_Counts(ctx, sqlc.Get_Referrer_Host_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
+	if err != nil {
+		log.Printf("Failed to fetch referrer hosts: %v", err)
+	} else {
+		for _, row := range hostData {
+			referrerHosts = append(referrerHosts, map[string]interface{}{"name": row.Name, "value": row.Value})
+		}
+		hasData = true
 	}
 
-	// Mock data for geographic distribution (would be replaced with real data in production)
-	geographicDistribution := []map[string]interface{}{
-		{"name": "United States", "value": 45},
-		{"name": "United Kingdom", "value": 15},
-		{"name": "Canada", "value": 10},
-		{"name": "Australia", "value": 8},
-		{"name": "Germany", "value": 7},
-		{"name": "France", "value": 5},
-		{"name": "Other", "value": 10},
+	geographicDistribution := []map[string]interface{}{}
+	geoData, err := server.store.Get_Geo_Country_Breakdown(ctx, sqlc.Get_Geo_Country_BreakdownParams{IncludeBots: include_bots(ctx), BrandID: convertedId, IncludePending: server.include_pending(ctx)})
+	if err != nil {
+		log.Printf("Failed to fetch geographic distribution: %v", err)
+	} else {
+		for _, row := range geoData {
+			name := row.Country
+			if name == "" {
+				name = "Unknown"
+			}
+			geographicDistribution = append(geographicDistribution, map[string]interface{}{"name": name, "value": row.Clicks})
+		}
+		hasData = true
 	}
 
+	// Clicks carry no age, so this stays empty rather than showing made-up
+	// numbers
+	audienceBreakdown := []map[string]interface{}{}
+
 	// If we have no data at all, return an error
 	if !hasData {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve any audience insights data"})
@@ -246,6 +250,10 @@ func (server *Server) getAudienceInsightsTabData(ctx *gin.Context) {
 		"audienceBreakdown":     audienceBreakdown,
 		"geographicDistribution": geographicDistribution,
 		"deviceStats":           deviceStats,
+		"osStats":                osStats,
+		"browserStats":           browserStats,
+		"referrerPlatforms":      referrerPlatforms,
+		"referrerHosts":          referrerHosts,
 	})
 }
 
@@ -271,7 +279,7 @@ func (server *Server) getRevenueStatsTabData(ctx *gin.Context) {
 	hasData := false
 
 	// Get key metrics data
-	keyMetricsData, err := server.store.Get_Brand_Key_Metrics(ctx, convertedId)
+	keyMetricsData, err := server.store.Get_Brand_Key_Metrics(ctx, sqlc.Get_Brand_Key_MetricsParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		log.Printf("Failed to get key metrics: %v", err)
 		// Continue execution instead of returning early
@@ -284,13 +292,14 @@ func (server *Server) getRevenueStatsTabData(ctx *gin.Context) {
 	revenueRows, err := server.store.GetDB().QueryContext(ctx, `
 		SELECT 
 			COALESCE(TO_CHAR(s.created_at, 'Mon'), 'Unknown') AS month,
-			COALESCE(SUM(s.amount), 0)::numeric(10, 2) AS raw_revenue,
+			COALESCE(SUM(s.amount - s.refunded_amount), 0)::numeric(10, 2) AS raw_revenue,
 			STRING_AGG(DISTINCT COALESCE(s.currency, 'USD'), ', ') AS currencies
 		FROM sales s
 		WHERE s.brand_id = $1
+		AND (s.status = 'approved' OR ($2::boolean AND s.status = 'pending'))
 		GROUP BY TO_CHAR(s.created_at, 'Mon')
 		ORDER BY MIN(s.created_at)
-	`, brandId)
+	`, brandId, server.include_pending(ctx))
 	
 	var processedRevenueData []map[string]interface{}
 	
@@ -366,7 +375,7 @@ func (server *Server) getRevenueStatsTabData(ctx *gin.Context) {
 	}
 
 	// Get campaign performance data for revenue by campaign
-	campaignPerformanceData, err := server.store.Get_Campaign_Performance(ctx, convertedId)
+	campaignPerformanceData, err := server.store.Get_Campaign_Performance(ctx, sqlc.Get_Campaign_PerformanceParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		log.Printf("Failed to get campaign performance: %v", err)
 		// Continue execution instead of returning early
@@ -379,17 +388,17 @@ func (server *Server) getRevenueStatsTabData(ctx *gin.Context) {
 	rows, err := server.store.GetDB().QueryContext(ctx, `
 		SELECT 
 			c.name AS campaign,
-			COALESCE(SUM(s.amount), 0)::numeric(10, 2) AS revenue
+			COALESCE(SUM(s.amount - s.refunded_amount), 0)::numeric(10, 2) AS revenue
 		FROM campaigns c
 		LEFT JOIN tracking_links tl ON c.id = tl.campaign_id
-		LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id
-		LEFT JOIN conversions conv ON cl.click_id = conv.click_id
+		LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id AND ($2::boolean OR NOT cl.is_bot)
+		LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($3::boolean AND conv.status = 'pending'))
 		LEFT JOIN sales s ON conv.id = s.conversion_id
 		WHERE c.brand_id = $1
 		GROUP BY c.name
 		ORDER BY revenue DESC
 		LIMIT 5
-	`, brandId)
+	`, brandId, include_bots(ctx), server.include_pending(ctx))
 	
 	if err != nil {
 		log.Printf("Failed to fetch campaign revenue data: %v", err)
@@ -478,7 +487,7 @@ func (server *Server) getOverviewTabData(ctx *gin.Context) {
 	hasData := false
 
 	// Get key metrics data
-	keyMetricsData, err := server.store.Get_Brand_Key_Metrics(ctx, convertedId)
+	keyMetricsData, err := server.store.Get_Brand_Key_Metrics(ctx, sqlc.Get_Brand_Key_MetricsParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		log.Printf("Failed to get key metrics: %v", err)
 		// Continue execution instead of returning early
@@ -488,7 +497,7 @@ func (server *Server) getOverviewTabData(ctx *gin.Context) {
 	}
 
 	// Get campaign performance data
-	campaignPerformanceData, err := server.store.Get_Campaign_Performance(ctx, convertedId)
+	campaignPerformanceData, err := server.store.Get_Campaign_Performance(ctx, sqlc.Get_Campaign_PerformanceParams{BrandID: convertedId, IncludeBots: include_bots(ctx), IncludePending: server.include_pending(ctx)})
 	if err != nil {
 		log.Printf("Failed to get campaign performance: %v", err)
 		// Continue execution instead of returning early
@@ -501,13 +510,14 @@ func (server *Server) getOverviewTabData(ctx *gin.Context) {
 	revenueRows, err := server.store.GetDB().QueryContext(ctx, `
 		SELECT 
 			COALESCE(TO_CHAR(s.created_at, 'Mon'), 'Unknown') AS month,
-			COALESCE(SUM(s.amount), 0)::numeric(10, 2) AS raw_revenue,
+			COALESCE(SUM(s.amount - s.refunded_amount), 0)::numeric(10, 2) AS raw_revenue,
 			STRING_AGG(DISTINCT COALESCE(s.currency, 'USD'), ', ') AS currencies
 		FROM sales s
 		WHERE s.brand_id = $1
+		AND (s.status = 'approved' OR ($2::boolean AND s.status = 'pending'))
 		GROUP BY TO_CHAR(s.created_at, 'Mon')
 		ORDER BY MIN(s.created_at)
-	`, brandId)
+	`, brandId, server.include_pending(ctx))
 	
 	var processedRevenueData []map[string]interface{}
 	
@@ -583,7 +593,7 @@ func (server *Server) getOverviewTabData(ctx *gin.Context) {
 	}
 
 	// Get UTM source data
-	utmSourceData, err := server.store.Get_UTMSource_Counts(ctx, convertedId)
+	utmSourceData, err := server.store.Get_UTMSource_Counts(ctx, sqlc.Get_UTMSource_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
 	if err != nil {
 		log.Printf("Failed to get UTM source data: %v", err)
 		// Continue execution instead of returning early
@@ -593,7 +603,7 @@ func (server *Server) getOverviewTabData(ctx *gin.Context) {
 	}
 
 	// Get UTM medium data
-	utmMediumData, err := server.store.Get_UTMMedium_Counts(ctx, convertedId)
+	utmMediumData, err := server.store.Get_UTMMedium_Counts(ctx, sqlc.Get_UTMMedium_CountsParams{BrandID: convertedId, IncludeBots: include_bots(ctx)})
 	if err != nil {
 		log.Printf("Failed to get UTM medium data: %v", err)
 		// Continue execution instead of returning early
@@ -3,16 +3,78 @@ package api
 import (
 	"Hanami/sqlc"
 	"Hanami/util"
+	"context"
 	"database/sql"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	maxTrackingCodeAttempts = 5
+	linkCodeConstraint      = "tracking_links_link_code_key"
+	labelConstraint         = "tracking_links_campaign_affiliate_label_key"
+)
+
+type tracking_link_res struct {
+	sqlc.Get_TrackingLinks_By_AffiliateRow
+	ShortUrl string
+}
+
 type create_tracking_link_params struct {
-	AffiliateID int64 `json:"affiliate_id,omitempty"`
-	CampaignID  int64 `json:"campaign_id,omitempty"`
+	AffiliateID int64  `json:"affiliate_id,omitempty"`
+	CampaignID  int64  `json:"campaign_id,omitempty"`
+	VanitySlug  string `json:"vanity_slug,omitempty"`
+	Label       string `json:"label,omitempty" binding:"max=64"`
+	UtmSource   string `json:"utm_source,omitempty"`
+	UtmMedium   string `json:"utm_medium,omitempty"`
+	UtmCampaign string `json:"utm_campaign,omitempty"`
+}
+
+// sub_link_params describe a labelled sub-link, which lets an affiliate tell
+// apart the places they share a campaign, such as "youtube" or "newsletter".
+type sub_link_params struct {
+	Label       string `json:"label" binding:"required,max=64"`
+	UtmSource   string `json:"utm_source,omitempty"`
+	UtmMedium   string `json:"utm_medium,omitempty"`
+	UtmCampaign string `json:"utm_campaign,omitempty"`
+}
+
+func (params sub_link_params) tracking_args(affiliateID, campaignID int64) sqlc.Create_TrackingLinkParams {
+	return sqlc.Create_TrackingLinkParams{
+		AffiliateID: sql.NullInt64{Int64: affiliateID, Valid: true},
+		CampaignID:  sql.NullInt64{Int64: campaignID, Valid: true},
+		Label:       nullString(params.Label),
+		UtmSource:   nullString(params.UtmSource),
+		UtmMedium:   nullString(params.UtmMedium),
+		UtmCampaign: nullString(params.UtmCampaign),
+	}
+}
+
+// label_conflict explains a violation of the per-affiliate label constraint.
+func label_conflict(label string) string {
+	if label == "" {
+		return "affiliate already has a default link in the campaign"
+	}
+	return "affiliate already has a link labelled " + strconv.Quote(label) + " in the campaign"
+}
+
+// insert_tracking_links stores links in one transaction under freshly
+// generated codes, drawing a new code whenever an insert collides with an
+// existing one.
+func (server *Server) insert_tracking_links(ctx context.Context, args ...sqlc.Create_TrackingLinkParams) ([]sqlc.TrackingLink, error) {
+	return server.store.CreateTrackingLinksTx(ctx, args, server.codeGenerator.Generate, maxTrackingCodeAttempts)
+}
+
+// insert_tracking_link stores a single link the way insert_tracking_links does.
+func (server *Server) insert_tracking_link(ctx context.Context, args sqlc.Create_TrackingLinkParams) (sqlc.TrackingLink, error) {
+	links, err := server.insert_tracking_links(ctx, args)
+	if err != nil {
+		return sqlc.TrackingLink{}, err
+	}
+	return links[0], nil
 }
 
 func (server *Server) create_tracking_link(ctx *gin.Context) {
@@ -21,11 +83,6 @@ func (server *Server) create_tracking_link(ctx *gin.Context) {
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-	}
-
-	code, err := util.Generate_Code()
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
@@ -42,10 +99,45 @@ func (server *Server) create_tracking_link(ctx *gin.Context) {
 	tracking_args := sqlc.Create_TrackingLinkParams{
 		AffiliateID: converted_affiliate_id,
 		CampaignID:  converted_campaign_id,
-		LinkCode:    code,
+		Label: sql.NullString{
+			String: req.Label,
+			Valid:  req.Label != "",
+		},
+		UtmSource: sql.NullString{
+			String: req.UtmSource,
+			Valid:  req.UtmSource != "",
+		},
+		UtmMedium: sql.NullString{
+			String: req.UtmMedium,
+			Valid:  req.UtmMedium != "",
+		},
+		UtmCampaign: sql.NullString{
+			String: req.UtmCampaign,
+			Valid:  req.UtmCampaign != "",
+		},
 	}
 
-	tracking_link, err := server.store.Create_TrackingLink(ctx, tracking_args)
+	var tracking_link sqlc.TrackingLink
+	var err error
+	if req.VanitySlug != "" {
+		tracking_args.LinkCode, err = util.NormalizeVanitySlug(req.VanitySlug)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		tracking_link, err = server.store.Create_TrackingLink(ctx, tracking_args)
+	} else {
+		tracking_link, err = server.insert_tracking_link(ctx, tracking_args)
+	}
+	if isUniqueViolation(err) {
+		if violatedConstraint(err) == linkCodeConstraint {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "vanity slug is already taken"})
+			return
+		}
+		ctx.JSON(http.StatusConflict, gin.H{"error": label_conflict(req.Label)})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -91,7 +183,15 @@ func (server *Server) get_tracking_links_by_affiliate_id(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"tracking_links": trackingLinks})
+	res := make([]tracking_link_res, 0, len(trackingLinks))
+	for _, link := range trackingLinks {
+		res = append(res, tracking_link_res{
+			Get_TrackingLinks_By_AffiliateRow: link,
+			ShortUrl:                          server.short_url(link.LinkCode, link.ShortDomain),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"tracking_links": res})
 }
 
 func (server *Server) get_tracking_links_by_campaign_id(ctx *gin.Context) {
@@ -130,6 +230,8 @@ func (server *Server) delete_tracking_link_by_id(ctx *gin.Context) {
 		return
 	}
 
+	server.linkCache.invalidate_link(ctx, id)
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "Tracking link deleted successfully"})
 }
 
@@ -170,3 +272,124 @@ func (server *Server) get_tracking_link_for_affiliate(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"tracking_code": trackingCode})
 }
+
+func (server *Server) pause_tracking_link(ctx *gin.Context) {
+	server.set_tracking_link_active(ctx, false)
+}
+
+func (server *Server) resume_tracking_link(ctx *gin.Context) {
+	server.set_tracking_link_active(ctx, true)
+}
+
+func (server *Server) set_tracking_link_active(ctx *gin.Context, active bool) {
+	idParam := ctx.Param("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	args := sqlc.Set_TrackingLink_ActiveParams{
+		ID:       id,
+		IsActive: active,
+	}
+
+	trackingLink, err := server.store.Set_TrackingLink_Active(ctx, args)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Tracking link not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.linkCache.invalidate_link(ctx, trackingLink.ID)
+
+	ctx.JSON(http.StatusOK, gin.H{"tracking_link": trackingLink})
+}
+
+type update_tracking_link_limits_params struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks *int64     `json:"max_clicks" binding:"omitempty,gt=0"`
+}
+
+// update_tracking_link_limits replaces a link's expiry and click cap; omitting
+// either field removes that limit.
+func (server *Server) update_tracking_link_limits(ctx *gin.Context) {
+	idParam := ctx.Param("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req update_tracking_link_limits_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	args := sqlc.Update_TrackingLink_LimitsParams{ID: id}
+	if req.ExpiresAt != nil {
+		args.ExpiresAt = sql.NullTime{Time: req.ExpiresAt.UTC(), Valid: true}
+	}
+	if req.MaxClicks != nil {
+		args.MaxClicks = sql.NullInt64{Int64: *req.MaxClicks, Valid: true}
+	}
+
+	trackingLink, err := server.store.Update_TrackingLink_Limits(ctx, args)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Tracking link not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.linkCache.invalidate_link(ctx, trackingLink.ID)
+
+	ctx.JSON(http.StatusOK, gin.H{"tracking_link": trackingLink})
+}
+
+type update_tracking_link_dedup_params struct {
+	DedupWindowSeconds *int32 `json:"dedup_window_seconds" binding:"omitempty,gte=0,lte=86400"`
+}
+
+// update_tracking_link_dedup sets how long repeat clicks on a link count as
+// the first one. 0 turns deduplication off; omitting the field falls back to
+// the server default.
+func (server *Server) update_tracking_link_dedup(ctx *gin.Context) {
+	idParam := ctx.Param("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req update_tracking_link_dedup_params
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	args := sqlc.Update_TrackingLink_Dedup_WindowParams{ID: id}
+	if req.DedupWindowSeconds != nil {
+		args.DedupWindowSeconds = sql.NullInt32{Int32: *req.DedupWindowSeconds, Valid: true}
+	}
+
+	trackingLink, err := server.store.Update_TrackingLink_Dedup_Window(ctx, args)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Tracking link not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.linkCache.invalidate_link(ctx, trackingLink.ID)
+
+	ctx.JSON(http.StatusOK, gin.H{"tracking_link": trackingLink})
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"Hanami/sqlc"
+	"Hanami/util"
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// smallCodeSpace is a generator with 16 possible codes, drawn from two CJK
+// characters no other test run is likely to have used.
+func smallCodeSpace(t *testing.T) (*util.CodeGenerator, []string) {
+	t.Helper()
+	first := rune(0x4E00 + rand.Intn(20000))
+	alphabet := string([]rune{first, first + 1})
+	generator, err := util.NewCodeGenerator(alphabet, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := []string{""}
+	for i := 0; i < 4; i++ {
+		var next []string
+		for _, code := range codes {
+			for _, r := range alphabet {
+				next = append(next, code+string(r))
+			}
+		}
+		codes = next
+	}
+	return generator, codes
+}
+
+func TestInsertTrackingLinkRetriesCollisions(t *testing.T) {
+	base := requireServer(t)
+	ctx := context.Background()
+	generator, codes := smallCodeSpace(t)
+	server := *base
+	server.codeGenerator = generator
+
+	// All but one code taken: every retry either collides or finds it
+	for _, code := range codes[1:] {
+		if _, err := server.store.Create_TrackingLink(ctx, sqlc.Create_TrackingLinkParams{LinkCode: code}); err != nil {
+			t.Fatalf("Create_TrackingLink(%q): %v", code, err)
+		}
+	}
+
+	link, err := server.insert_tracking_link(ctx, sqlc.Create_TrackingLinkParams{})
+	switch {
+	case err == nil && link.LinkCode != codes[0]:
+		t.Errorf("insert_tracking_link() code = %q, want the only free code %q", link.LinkCode, codes[0])
+	case err != nil && !strings.Contains(err.Error(), "could not allocate"):
+		t.Fatalf("insert_tracking_link() error = %v, want success or exhaustion", err)
+	case err != nil:
+		// Five draws all missed the free code; take it so none are left
+		if _, err := server.store.Create_TrackingLink(ctx, sqlc.Create_TrackingLinkParams{LinkCode: codes[0]}); err != nil {
+			t.Fatalf("Create_TrackingLink(%q): %v", codes[0], err)
+		}
+	}
+
+	// With every code taken the collisions surface as exhaustion, not as a
+	// unique violation
+	_, err = server.insert_tracking_link(ctx, sqlc.Create_TrackingLinkParams{})
+	if err == nil || isUniqueViolation(err) {
+		t.Errorf("insert_tracking_link() with no free codes error = %v, want exhaustion", err)
+	}
+}
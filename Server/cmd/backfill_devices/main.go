@@ -0,0 +1,89 @@
+// Command backfill_devices parses the user agent of clicks recorded before
+// device columns existed and fills in device_type, os, browser and
+// browser_version. It is safe to rerun; only clicks without a device_type are
+// touched.
+package main
+
+import (
+	"Hanami/sqlc"
+	"Hanami/util"
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 1000, "clicks to update per transaction")
+	flag.Parse()
+
+	config, err := util.LoadConfig(".")
+	if err != nil {
+		log.Fatalf("could not able to initialize env : %v", err)
+	}
+
+	conn, err := sql.Open(config.Driver, config.DbUrl)
+	if err != nil {
+		log.Fatalf("could not able to connect to database : %v", err)
+	}
+	defer conn.Close()
+
+	store := sqlc.NewStore(conn)
+	ctx := context.Background()
+
+	var afterID int64
+	var updated int
+	for {
+		clicks, err := store.Get_Clicks_Missing_Device(ctx, sqlc.Get_Clicks_Missing_DeviceParams{
+			AfterID:   afterID,
+			BatchSize: int32(*batchSize),
+		})
+		if err != nil {
+			log.Fatalf("Failed to load clicks after %d: %v", afterID, err)
+		}
+		if len(clicks) == 0 {
+			break
+		}
+
+		if err := backfill(ctx, store, clicks); err != nil {
+			log.Fatalf("Failed to backfill clicks after %d: %v", afterID, err)
+		}
+
+		afterID = clicks[len(clicks)-1].ID
+		updated += len(clicks)
+		log.Printf("Backfilled %d clicks (up to id %d)", updated, afterID)
+	}
+
+	log.Printf("Done, %d clicks backfilled", updated)
+}
+
+func backfill(ctx context.Context, store *sqlc.Store, clicks []sqlc.Get_Clicks_Missing_DeviceRow) error {
+	tx, err := store.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := store.WithTx(tx)
+	for _, click := range clicks {
+		device := util.ParseUserAgent(click.UserAgent.String)
+		err := qtx.Update_Click_Device(ctx, sqlc.Update_Click_DeviceParams{
+			ID:             click.ID,
+			DeviceType:     nullString(device.Type),
+			Os:             nullString(device.OS),
+			Browser:        nullString(device.Browser),
+			BrowserVersion: nullString(device.BrowserVersion),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,15 @@
+CREATE TABLE brand_domains (
+    id bigserial PRIMARY KEY,
+    brand_id bigint NOT NULL REFERENCES brands(id) ON DELETE CASCADE,
+    domain varchar NOT NULL,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+    -- A domain only routes short links once its owner has published the
+    -- verification token in DNS
+    verification_token varchar(64) NOT NULL,
+    verified_at timestamp
+);
+
+CREATE INDEX idx_brand_domains_brand_id ON brand_domains(brand_id);
+-- An unverified claim must not lock the real owner out of their domain
+CREATE UNIQUE INDEX idx_brand_domains_verified ON brand_domains(domain) WHERE verified_at IS NOT NULL;
+CREATE UNIQUE INDEX idx_brand_domains_brand_domain ON brand_domains(brand_id, domain);
@@ -0,0 +1,16 @@
+ALTER TABLE clicks
+DROP COLUMN utm_campaign,
+DROP COLUMN sub1,
+DROP COLUMN sub2,
+DROP COLUMN sub3,
+DROP COLUMN sub4,
+DROP COLUMN sub5;
+
+ALTER TABLE tracking_links
+DROP CONSTRAINT IF EXISTS tracking_links_campaign_affiliate_label_key;
+
+ALTER TABLE tracking_links
+DROP COLUMN label,
+DROP COLUMN utm_source,
+DROP COLUMN utm_medium,
+DROP COLUMN utm_campaign;
@@ -0,0 +1,28 @@
+ALTER TABLE tracking_links
+ADD COLUMN label varchar,
+ADD COLUMN utm_source varchar,
+ADD COLUMN utm_medium varchar,
+ADD COLUMN utm_campaign varchar;
+
+-- An affiliate keeps one unlabelled default link per campaign; any extra
+-- links they already have are named after their id so the constraint can hold
+UPDATE tracking_links tl
+SET label = 'link-' || tl.id
+WHERE EXISTS (
+    SELECT 1
+    FROM tracking_links other
+    WHERE other.campaign_id IS NOT DISTINCT FROM tl.campaign_id
+    AND other.affiliate_id IS NOT DISTINCT FROM tl.affiliate_id
+    AND other.id < tl.id
+);
+
+ALTER TABLE tracking_links
+ADD CONSTRAINT tracking_links_campaign_affiliate_label_key UNIQUE NULLS NOT DISTINCT (campaign_id, affiliate_id, label);
+
+ALTER TABLE clicks
+ADD COLUMN utm_campaign varchar,
+ADD COLUMN sub1 varchar,
+ADD COLUMN sub2 varchar,
+ADD COLUMN sub3 varchar,
+ADD COLUMN sub4 varchar,
+ADD COLUMN sub5 varchar;
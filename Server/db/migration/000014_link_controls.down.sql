@@ -0,0 +1,8 @@
+ALTER TABLE campaigns
+DROP COLUMN fallback_url;
+
+ALTER TABLE tracking_links
+DROP COLUMN expires_at,
+DROP COLUMN is_active,
+DROP COLUMN max_clicks,
+DROP COLUMN click_count;
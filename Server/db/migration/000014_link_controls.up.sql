@@ -0,0 +1,8 @@
+ALTER TABLE tracking_links
+ADD COLUMN expires_at timestamp,
+ADD COLUMN is_active boolean NOT NULL DEFAULT true,
+ADD COLUMN max_clicks bigint CHECK (max_clicks > 0),
+ADD COLUMN click_count bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE campaigns
+ADD COLUMN fallback_url varchar;
@@ -0,0 +1,6 @@
+CREATE TABLE brand_logos (
+    brand_id bigint PRIMARY KEY REFERENCES brands(id) ON DELETE CASCADE,
+    content_type varchar NOT NULL CHECK (content_type IN ('image/png', 'image/jpeg')),
+    data bytea NOT NULL,
+    updated_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+ALTER TABLE campaigns
+DROP COLUMN passthrough_params,
+DROP COLUMN redirect_template;
@@ -0,0 +1,3 @@
+ALTER TABLE campaigns
+ADD COLUMN passthrough_params text[] NOT NULL DEFAULT '{}',
+ADD COLUMN redirect_template varchar;
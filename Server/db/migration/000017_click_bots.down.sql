@@ -0,0 +1,3 @@
+ALTER TABLE clicks
+DROP COLUMN is_bot,
+DROP COLUMN bot_reason;
@@ -0,0 +1,3 @@
+ALTER TABLE clicks
+ADD COLUMN is_bot boolean NOT NULL DEFAULT false,
+ADD COLUMN bot_reason varchar;
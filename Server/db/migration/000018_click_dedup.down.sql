@@ -0,0 +1,7 @@
+ALTER TABLE clicks
+DROP COLUMN fingerprint,
+DROP COLUMN raw_clicks,
+DROP COLUMN last_clicked_at;
+
+ALTER TABLE tracking_links
+DROP COLUMN dedup_window_seconds;
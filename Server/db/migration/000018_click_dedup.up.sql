@@ -0,0 +1,7 @@
+ALTER TABLE tracking_links
+ADD COLUMN dedup_window_seconds integer CHECK (dedup_window_seconds BETWEEN 0 AND 86400);
+
+ALTER TABLE clicks
+ADD COLUMN fingerprint varchar,
+ADD COLUMN raw_clicks integer NOT NULL DEFAULT 1,
+ADD COLUMN last_clicked_at timestamp;
@@ -0,0 +1,5 @@
+ALTER TABLE clicks
+DROP COLUMN device_type,
+DROP COLUMN os,
+DROP COLUMN browser,
+DROP COLUMN browser_version;
@@ -0,0 +1,5 @@
+ALTER TABLE clicks
+ADD COLUMN device_type varchar,
+ADD COLUMN os varchar,
+ADD COLUMN browser varchar,
+ADD COLUMN browser_version varchar;
@@ -0,0 +1,4 @@
+ALTER TABLE clicks
+DROP COLUMN country,
+DROP COLUMN region,
+DROP COLUMN city;
@@ -0,0 +1,6 @@
+ALTER TABLE clicks
+ADD COLUMN country varchar(2),
+ADD COLUMN region varchar,
+ADD COLUMN city varchar;
+
+CREATE INDEX idx_clicks_country_region ON clicks(country, region);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_clicks_pii_pending;
+
+UPDATE clicks SET user_ip = '' WHERE user_ip IS NULL;
+
+ALTER TABLE clicks
+ALTER COLUMN user_ip SET NOT NULL,
+DROP COLUMN pii_scrubbed_at;
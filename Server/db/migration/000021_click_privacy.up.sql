@@ -0,0 +1,5 @@
+ALTER TABLE clicks
+ALTER COLUMN user_ip DROP NOT NULL,
+ADD COLUMN pii_scrubbed_at timestamp;
+
+CREATE INDEX idx_clicks_pii_pending ON clicks(timestamp) WHERE pii_scrubbed_at IS NULL;
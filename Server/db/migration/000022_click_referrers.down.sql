@@ -0,0 +1,6 @@
+UPDATE clicks
+SET referrer = COALESCE(referrer_host, 'unknown');
+
+ALTER TABLE clicks
+DROP COLUMN referrer_host,
+DROP COLUMN referrer_platform;
@@ -0,0 +1,13 @@
+ALTER TABLE clicks
+ADD COLUMN referrer_host varchar,
+ADD COLUMN referrer_platform varchar;
+
+-- referrer used to hold only the host, or 'unknown' when there was none
+UPDATE clicks
+SET 
+    referrer_host = NULLIF(referrer, 'unknown'),
+    referrer = NULLIF(referrer, 'unknown');
+
+UPDATE clicks
+SET referrer_platform = 'direct'
+WHERE referrer IS NULL;
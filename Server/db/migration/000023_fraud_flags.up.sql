@@ -0,0 +1,18 @@
+CREATE TABLE fraud_flags (
+    id bigserial PRIMARY KEY,
+    brand_id bigint NOT NULL REFERENCES brands(id) ON DELETE CASCADE,
+    event_type varchar NOT NULL CHECK (event_type IN ('click', 'conversion', 'event')),
+    click_id uuid NOT NULL,
+    conversion_id bigint REFERENCES conversions(id) ON DELETE CASCADE,
+    score integer NOT NULL,
+    reasons text[] NOT NULL DEFAULT '{}',
+    -- confirmed: the brand accepts the event; cleared: the brand throws it
+    -- out and it no longer earns commission
+    status varchar NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'cleared')),
+    reviewed_at timestamp,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_fraud_flags_click ON fraud_flags(click_id) WHERE event_type = 'click';
+CREATE UNIQUE INDEX idx_fraud_flags_conversion ON fraud_flags(conversion_id);
+CREATE INDEX idx_fraud_flags_brand_status ON fraud_flags(brand_id, status);
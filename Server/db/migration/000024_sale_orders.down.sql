@@ -0,0 +1,6 @@
+ALTER TABLE conversions
+DROP COLUMN sale_id;
+
+ALTER TABLE sales
+DROP COLUMN order_id,
+DROP COLUMN request_hash;
@@ -0,0 +1,11 @@
+ALTER TABLE sales
+ADD COLUMN order_id varchar(255),
+ADD COLUMN request_hash varchar(64);
+
+-- Older sales have no order_id; NULLs never collide
+CREATE UNIQUE INDEX idx_sales_brand_order ON sales(brand_id, order_id);
+
+ALTER TABLE conversions
+ADD COLUMN sale_id bigint REFERENCES sales(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_conversions_sale_id ON conversions(sale_id);
@@ -0,0 +1,19 @@
+ALTER TABLE conversions DROP CONSTRAINT IF EXISTS conversions_weight_check;
+
+UPDATE conversions
+SET weight = NULL
+WHERE weight = 0;
+
+ALTER TABLE conversions
+ADD CONSTRAINT conversions_weight_check CHECK (weight > 0 AND weight <= 1);
+
+ALTER TABLE conversions
+DROP COLUMN attribution_model;
+
+ALTER TABLE campaigns
+DROP COLUMN attribution_model,
+DROP COLUMN attribution_half_life_hours;
+
+ALTER TABLE brands
+DROP COLUMN attribution_model,
+DROP COLUMN attribution_half_life_hours;
@@ -0,0 +1,24 @@
+ALTER TABLE brands
+ADD COLUMN attribution_model varchar NOT NULL DEFAULT 'u_shaped'
+    CHECK (attribution_model IN ('first_touch', 'last_touch', 'linear', 'u_shaped', 'w_shaped', 'time_decay')),
+ADD COLUMN attribution_half_life_hours integer CHECK (attribution_half_life_hours > 0);
+
+-- NULL on a campaign falls back to its brand's setting
+ALTER TABLE campaigns
+ADD COLUMN attribution_model varchar
+    CHECK (attribution_model IN ('first_touch', 'last_touch', 'linear', 'u_shaped', 'w_shaped', 'time_decay')),
+ADD COLUMN attribution_half_life_hours integer CHECK (attribution_half_life_hours > 0);
+
+ALTER TABLE conversions
+ADD COLUMN attribution_model varchar;
+
+-- Every conversion so far was weighted U-shaped
+UPDATE conversions
+SET attribution_model = 'u_shaped';
+
+-- First touch, last touch and time decay give some touches no credit. Their
+-- conversions are still recorded so the path can be re-weighted later
+ALTER TABLE conversions DROP CONSTRAINT IF EXISTS conversions_weight_check;
+
+ALTER TABLE conversions
+ADD CONSTRAINT conversions_weight_check CHECK (weight >= 0 AND weight <= 1);
@@ -0,0 +1,3 @@
+-- Days from click to conversion during which a click can still earn credit
+ALTER TABLE campaigns
+ADD COLUMN lookback_days integer NOT NULL DEFAULT 30 CHECK (lookback_days BETWEEN 1 AND 365);
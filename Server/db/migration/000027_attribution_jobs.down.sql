@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_conversions_timestamp;
+DROP TABLE IF EXISTS attribution_jobs;
@@ -0,0 +1,23 @@
+-- heartbeat_at moves with every batch, so a job left running by a crashed
+-- server can be told apart from a slow one
+CREATE TABLE attribution_jobs (
+    id bigserial PRIMARY KEY,
+    brand_id bigint NOT NULL REFERENCES brands(id) ON DELETE CASCADE,
+    attribution_model varchar NOT NULL,
+    attribution_half_life_hours integer,
+    from_time timestamp NOT NULL,
+    to_time timestamp NOT NULL,
+    status varchar NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    conversions_updated integer NOT NULL DEFAULT 0,
+    error text,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at timestamp,
+    sales_skipped integer NOT NULL DEFAULT 0,
+    heartbeat_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_attribution_jobs_brand_id ON attribution_jobs(brand_id);
+-- Two jobs re-weighting one brand at once would interleave their batches
+CREATE UNIQUE INDEX idx_attribution_jobs_brand_active ON attribution_jobs(brand_id)
+WHERE status IN ('pending', 'running');
+CREATE INDEX idx_conversions_timestamp ON conversions(timestamp);
@@ -0,0 +1,4 @@
+ALTER TABLE sales
+DROP COLUMN event_name;
+
+DROP TABLE IF EXISTS brand_postback_keys;
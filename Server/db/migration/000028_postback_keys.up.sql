@@ -0,0 +1,10 @@
+-- Kept apart from brands so the credentials never ride along with brand rows
+CREATE TABLE brand_postback_keys (
+    brand_id bigint PRIMARY KEY REFERENCES brands(id) ON DELETE CASCADE,
+    key_hash varchar(64) NOT NULL UNIQUE,
+    signing_secret varchar(64) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE sales
+ADD COLUMN event_name varchar(64);
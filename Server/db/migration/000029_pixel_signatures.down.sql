@@ -0,0 +1,2 @@
+ALTER TABLE brand_postback_keys
+DROP COLUMN pixel_signature_required;
@@ -0,0 +1,3 @@
+-- Pixels sit in public page source, so a brand may insist they are signed
+ALTER TABLE brand_postback_keys
+ADD COLUMN pixel_signature_required boolean NOT NULL DEFAULT false;
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_sales_pending_approve_at;
+
+ALTER TABLE conversions
+DROP COLUMN status;
+
+ALTER TABLE sales
+DROP COLUMN refunded_amount,
+DROP COLUMN approve_at,
+DROP COLUMN status_changed_at,
+DROP COLUMN status_reason,
+DROP COLUMN status;
+
+ALTER TABLE campaigns
+DROP COLUMN approval_delay_days;
@@ -0,0 +1,20 @@
+-- Days a sale stays pending before it is approved, e.g. a return window.
+-- 0 approves sales as soon as they are recorded
+ALTER TABLE campaigns
+ADD COLUMN approval_delay_days integer NOT NULL DEFAULT 0 CHECK (approval_delay_days BETWEEN 0 AND 365);
+
+-- Everything recorded before now was final, so it starts out approved
+ALTER TABLE sales
+ADD COLUMN status varchar(16) NOT NULL DEFAULT 'approved' CHECK (status IN ('pending', 'approved', 'rejected', 'reversed')),
+ADD COLUMN status_reason text,
+ADD COLUMN status_changed_at timestamp,
+ADD COLUMN approve_at timestamp,
+ADD COLUMN refunded_amount decimal NOT NULL DEFAULT 0;
+
+ALTER TABLE conversions
+ADD COLUMN status varchar(16) NOT NULL DEFAULT 'approved' CHECK (status IN ('pending', 'approved', 'rejected', 'reversed'));
+
+ALTER TABLE sales ALTER COLUMN status SET DEFAULT 'pending';
+ALTER TABLE conversions ALTER COLUMN status SET DEFAULT 'pending';
+
+CREATE INDEX idx_sales_pending_approve_at ON sales(approve_at) WHERE status = 'pending';
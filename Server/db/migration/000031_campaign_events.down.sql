@@ -0,0 +1,8 @@
+DELETE FROM fraud_flags
+WHERE event_type = 'event';
+
+ALTER TABLE fraud_flags
+DROP COLUMN IF EXISTS event_id;
+
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS campaign_event_types;
@@ -0,0 +1,46 @@
+-- Events a campaign pays a fixed amount for, such as a signup or app install
+CREATE TABLE campaign_event_types (
+    id bigserial PRIMARY KEY,
+    campaign_id bigint NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    name varchar(64) NOT NULL,
+    payout decimal(12, 2) NOT NULL CHECK (payout >= 0),
+    currency varchar(3) NOT NULL DEFAULT 'USD',
+    -- Pay for the event once per user rather than every time it is sent
+    dedup_per_user boolean NOT NULL DEFAULT false,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (campaign_id, name)
+);
+
+-- Events are held, approved and reversed like sales
+CREATE TABLE events (
+    id bigserial PRIMARY KEY,
+    brand_id bigint NOT NULL REFERENCES brands(id) ON DELETE CASCADE,
+    event_type_id bigint NOT NULL REFERENCES campaign_event_types(id) ON DELETE CASCADE,
+    click_id uuid NOT NULL,
+    user_ref varchar(255),
+    -- user_ref for types deduped per user, else NULL so every event is kept
+    dedup_key varchar(255),
+    -- The brand's own reference for the event, so a retry is recorded once;
+    -- request_hash tells a retry from a different event reusing it
+    order_id varchar(255),
+    request_hash varchar(64),
+    -- Copied from the type so later payout changes leave past events alone
+    payout decimal(12, 2) NOT NULL,
+    currency varchar(3) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status varchar(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'reversed')),
+    status_reason text,
+    status_changed_at timestamp,
+    approve_at timestamp,
+    UNIQUE (event_type_id, dedup_key),
+    UNIQUE (brand_id, order_id)
+);
+
+CREATE INDEX idx_events_click_id ON events(click_id);
+CREATE INDEX idx_events_pending_approve_at ON events(approve_at) WHERE status = 'pending';
+
+-- Events are scored for fraud like conversions
+ALTER TABLE fraud_flags
+ADD COLUMN event_id bigint REFERENCES events(id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX idx_fraud_flags_event ON fraud_flags(event_id);
@@ -26,9 +26,9 @@ FROM affiliate_campaigns ac
 JOIN affiliates a ON ac.affiliate_id = a.id
 JOIN users u ON a.user_id = u.id
 LEFT JOIN tracking_links tl ON tl.affiliate_id = a.id AND tl.campaign_id = ac.campaign_id
-LEFT JOIN clicks c ON c.tracking_link_id = tl.id
-LEFT JOIN conversions conv ON conv.click_id = c.click_id
-WHERE ac.campaign_id = $1
+LEFT JOIN clicks c ON c.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT c.is_bot)
+LEFT JOIN conversions conv ON conv.click_id = c.click_id AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+WHERE ac.campaign_id = sqlc.arg(campaign_id)
 GROUP BY 
     a.id,
     a.user_id,
@@ -0,0 +1,74 @@
+-- name: Get_Attribution_Paths :many
+-- Every credited click of each sale in the range, oldest click first, so a
+-- sale's rows form its path. A conversion whose click or link is gone comes
+-- back without clicked_at or commission_rate, so its sale can be skipped
+-- rather than re-weighted over part of its path
+SELECT 
+    s.id AS sale_id,
+    conv.id AS conversion_id,
+    conv.amount,
+    conv.weight,
+    conv.timestamp AS converted_at,
+    cl.timestamp AS clicked_at,
+    tl.affiliate_id,
+    tl.campaign_id,
+    c.commission_rate,
+    EXISTS (
+        SELECT 1
+        FROM fraud_flags ff
+        WHERE ff.status = 'cleared'
+        AND (ff.conversion_id = conv.id OR (ff.event_type = 'click' AND ff.click_id = conv.click_id))
+    ) AS cleared
+FROM sales s
+JOIN conversions conv ON conv.sale_id = s.id
+LEFT JOIN clicks cl ON cl.click_id = conv.click_id
+LEFT JOIN tracking_links tl ON tl.id = cl.tracking_link_id
+LEFT JOIN campaigns c ON c.id = tl.campaign_id
+WHERE s.brand_id = sqlc.arg(brand_id)
+AND conv.timestamp >= sqlc.arg(from_time)::timestamp
+AND conv.timestamp < sqlc.arg(to_time)::timestamp
+AND conv.status IN ('pending', 'approved')
+ORDER BY s.id, cl.timestamp, conv.id;
+
+
+-- name: Create_Attribution_Job :one
+INSERT INTO attribution_jobs (
+    brand_id,
+    attribution_model,
+    attribution_half_life_hours,
+    from_time,
+    to_time
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+
+-- name: Get_Attribution_Job :one
+SELECT *
+FROM attribution_jobs
+WHERE id = $1;
+
+
+-- name: Update_Attribution_Job :exec
+UPDATE attribution_jobs
+SET 
+    status = $2,
+    conversions_updated = $3,
+    error = $4,
+    finished_at = $5,
+    sales_skipped = $6,
+    heartbeat_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+
+-- name: Abandon_Attribution_Jobs :exec
+-- Fails a brand's unfinished jobs that stopped making progress, so a job lost
+-- to a crash does not block the brand forever
+UPDATE attribution_jobs
+SET 
+    status = 'failed',
+    error = 'Abandoned without finishing',
+    finished_at = CURRENT_TIMESTAMP
+WHERE brand_id = sqlc.arg(brand_id)
+AND status IN ('pending', 'running')
+AND heartbeat_at < sqlc.arg(stale_before)::timestamp;
@@ -14,4 +14,38 @@ SELECT c.brand_id
 FROM tracking_links tl
 JOIN campaigns c ON tl.campaign_id = c.id
 WHERE tl.link_code = $1
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+
+-- name: Upsert_BrandLogo :exec
+INSERT INTO brand_logos (
+    brand_id,
+    content_type,
+    data,
+    updated_at
+) VALUES (
+    $1, $2, $3, CURRENT_TIMESTAMP
+)
+ON CONFLICT (brand_id) DO UPDATE
+SET content_type = EXCLUDED.content_type,
+    data = EXCLUDED.data,
+    updated_at = CURRENT_TIMESTAMP;
+
+
+-- name: Get_BrandLogo :one
+SELECT *
+FROM brand_logos
+WHERE brand_id = $1;
+
+
+-- name: Delete_BrandLogo :exec
+DELETE FROM brand_logos
+WHERE brand_id = $1;
+
+
+-- name: Update_Brand_Attribution :execrows
+UPDATE brands
+SET 
+    attribution_model = $2,
+    attribution_half_life_hours = $3
+WHERE id = $1;
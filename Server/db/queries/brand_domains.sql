@@ -0,0 +1,41 @@
+-- name: Create_BrandDomain :one
+INSERT INTO brand_domains (
+    brand_id,
+    domain,
+    verification_token,
+    created_at
+) VALUES (
+    $1, $2, $3, CURRENT_TIMESTAMP
+) RETURNING *;
+
+
+-- name: Get_BrandDomain :one
+SELECT *
+FROM brand_domains
+WHERE id = $1;
+
+
+-- name: Get_BrandDomain_By_Domain :one
+-- Only a verified domain routes to its brand
+SELECT *
+FROM brand_domains
+WHERE domain = $1 AND verified_at IS NOT NULL;
+
+
+-- name: Get_BrandDomains_By_Brand :many
+SELECT *
+FROM brand_domains
+WHERE brand_id = $1
+ORDER BY created_at;
+
+
+-- name: Verify_BrandDomain :one
+UPDATE brand_domains
+SET verified_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+
+-- name: Delete_BrandDomain :exec
+DELETE FROM brand_domains
+WHERE id = $1;
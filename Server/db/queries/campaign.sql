@@ -5,9 +5,12 @@ INSERT INTO campaigns (
     description,
     commission_rate,
     landing_url,
+    fallback_url,
+    lookback_days,
+    approval_delay_days,
     created_at
 ) VALUES (
-    $1, $2, $3, $4, $5, CURRENT_TIMESTAMP
+    $1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP
 ) RETURNING *;
 
 
@@ -33,6 +36,20 @@ WHERE id = $1
 RETURNING *;
 
 
+-- name: Update_Campaign_Settings :one
+UPDATE campaigns
+SET 
+    fallback_url = NULLIF(COALESCE(sqlc.narg(fallback_url), fallback_url), ''),
+    passthrough_params = COALESCE(sqlc.narg(passthrough_params), passthrough_params),
+    redirect_template = NULLIF(COALESCE(sqlc.narg(redirect_template), redirect_template), ''),
+    attribution_model = NULLIF(COALESCE(sqlc.narg(attribution_model), attribution_model), ''),
+    attribution_half_life_hours = NULLIF(COALESCE(sqlc.narg(attribution_half_life_hours), attribution_half_life_hours), 0),
+    lookback_days = COALESCE(sqlc.narg(lookback_days), lookback_days),
+    approval_delay_days = COALESCE(sqlc.narg(approval_delay_days), approval_delay_days)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+
 -- name: DeleteCampaign :exec
 DELETE FROM campaigns
 WHERE id = $1;
@@ -54,6 +71,8 @@ SELECT
     c.description AS campaign_description,
     c.commission_rate,
     c.landing_url,
+    c.lookback_days,
+    c.approval_delay_days,
     c.created_at AS campaign_created_at,
     ac.created_at AS affiliate_campaign_created_at,
     b.id AS brand_id,
@@ -66,4 +85,14 @@ JOIN affiliate_campaigns ac ON a.id = ac.affiliate_id
 JOIN campaigns c ON ac.campaign_id = c.id
 JOIN brands b ON c.brand_id = b.id
 WHERE u.id = $1
-ORDER BY c.created_at DESC;
\ No newline at end of file
+ORDER BY c.created_at DESC;
+
+
+-- name: Get_Attribution_Settings :one
+-- A campaign without its own model uses its brand's
+SELECT 
+    COALESCE(c.attribution_model, b.attribution_model)::varchar AS attribution_model,
+    COALESCE(c.attribution_half_life_hours, b.attribution_half_life_hours) AS attribution_half_life_hours
+FROM campaigns c
+JOIN brands b ON b.id = c.brand_id
+WHERE c.id = $1;
@@ -6,9 +6,27 @@ INSERT INTO clicks (
     user_agent,
     referrer,
     utm_source,
-    utm_medium
+    utm_medium,
+    utm_campaign,
+    sub1,
+    sub2,
+    sub3,
+    sub4,
+    sub5,
+    is_bot,
+    bot_reason,
+    fingerprint,
+    device_type,
+    os,
+    browser,
+    browser_version,
+    country,
+    region,
+    city,
+    referrer_host,
+    referrer_platform
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25
 ) RETURNING *;
 
 -- name: Get_Click_By_ID :one
@@ -20,4 +38,54 @@ WHERE id = $1;
 -- name: Get_Click_By_ClickID :one
 SELECT *
 FROM clicks
-WHERE click_id = $1;
\ No newline at end of file
+WHERE click_id = $1;
+
+-- name: Get_Clicks_Missing_Device :many
+SELECT id, user_agent
+FROM clicks
+WHERE device_type IS NULL AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(batch_size);
+
+
+-- name: Update_Click_Device :exec
+UPDATE clicks
+SET 
+    device_type = $2,
+    os = $3,
+    browser = $4,
+    browser_version = $5
+WHERE id = $1;
+
+
+-- name: Scrub_Click_PII :execrows
+UPDATE clicks
+SET 
+    user_ip = NULL,
+    user_agent = NULL,
+    referrer = NULL,
+    fingerprint = NULL,
+    city = NULL,
+    pii_scrubbed_at = NOW()
+WHERE id IN (
+    SELECT id
+    FROM clicks
+    WHERE pii_scrubbed_at IS NULL AND timestamp < sqlc.arg(cutoff)
+    ORDER BY id
+    LIMIT sqlc.arg(batch_size)
+);
+
+
+-- name: Get_Tracker_Clicks :many
+SELECT 
+    cl.click_id,
+    cl.timestamp,
+    tl.link_code,
+    tl.campaign_id,
+    c.brand_id,
+    c.lookback_days,
+    c.approval_delay_days
+FROM clicks cl
+JOIN tracking_links tl ON tl.id = cl.tracking_link_id
+JOIN campaigns c ON c.id = tl.campaign_id
+WHERE cl.click_id = ANY(sqlc.arg(click_ids)::uuid[]);
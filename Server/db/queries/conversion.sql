@@ -4,7 +4,39 @@ INSERT INTO conversions (
     amount,
     currency,
     weight,
+    sale_id,
+    attribution_model,
+    status,
     timestamp
 ) VALUES (
-    $1, $2, $3, $4 ,CURRENT_TIMESTAMP
+    $1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP
 ) RETURNING *;
+
+
+-- name: Get_Conversions_By_Sale :many
+SELECT *
+FROM conversions
+WHERE sale_id = $1
+ORDER BY id;
+
+
+-- name: Update_Conversion_Attribution :exec
+UPDATE conversions
+SET 
+    weight = $2,
+    attribution_model = $3
+WHERE id = $1;
+
+
+-- name: Update_Sale_Conversions_Status :exec
+UPDATE conversions
+SET status = $2
+WHERE sale_id = $1;
+
+
+-- name: Scale_Sale_Conversions :exec
+-- Sets each conversion of a sale to what is left of the sale after refunds
+UPDATE conversions conv
+SET amount = (s.amount - s.refunded_amount)::float
+FROM sales s
+WHERE s.id = conv.sale_id AND s.id = $1;
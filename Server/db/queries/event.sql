@@ -0,0 +1,117 @@
+-- name: Upsert_Campaign_Event_Type :one
+INSERT INTO campaign_event_types (
+    campaign_id,
+    name,
+    payout,
+    currency,
+    dedup_per_user
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (campaign_id, name) DO UPDATE
+SET payout = EXCLUDED.payout,
+    currency = EXCLUDED.currency,
+    dedup_per_user = EXCLUDED.dedup_per_user
+RETURNING *;
+
+
+-- name: List_Campaign_Event_Types :many
+SELECT *
+FROM campaign_event_types
+WHERE campaign_id = $1
+ORDER BY name;
+
+
+-- name: Get_Campaign_Event_Type :one
+SELECT *
+FROM campaign_event_types
+WHERE campaign_id = $1 AND name = $2;
+
+
+-- name: Create_Event :one
+-- A repeat of a deduped event, or of the brand's order_id, conflicts and
+-- returns no row
+INSERT INTO events (
+    brand_id,
+    event_type_id,
+    click_id,
+    user_ref,
+    dedup_key,
+    order_id,
+    request_hash,
+    payout,
+    currency,
+    status,
+    approve_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+
+-- name: Get_Event_By_Dedup_Key :one
+SELECT *
+FROM events
+WHERE event_type_id = $1 AND dedup_key = $2;
+
+
+-- name: Get_Event_By_Order :one
+SELECT *
+FROM events
+WHERE brand_id = $1 AND order_id = $2;
+
+
+-- name: Get_Event :one
+SELECT *
+FROM events
+WHERE id = $1;
+
+
+-- name: Update_Event_Status :one
+-- Returns no row unless the event is currently in from_status
+UPDATE events
+SET 
+    status = sqlc.arg(status),
+    status_reason = sqlc.narg(status_reason),
+    status_changed_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(from_status)
+RETURNING *;
+
+
+-- name: Approve_Due_Events :one
+-- Approves every pending event whose approve_at has passed
+WITH due AS (
+    UPDATE events
+    SET 
+        status = 'approved',
+        status_changed_at = CURRENT_TIMESTAMP
+    WHERE status = 'pending' AND approve_at <= CURRENT_TIMESTAMP
+    RETURNING id
+)
+SELECT COUNT(*) FROM due;
+
+
+-- name: Get_Campaign_Event_Counts :many
+-- Events each affiliate drove by currency, leaving out events the brand
+-- cleared as fraud and those not approved
+SELECT 
+    tl.affiliate_id,
+    et.name AS event,
+    e.currency,
+    COUNT(e.id) AS events,
+    COALESCE(SUM(e.payout), 0)::numeric(12, 2) AS payout
+FROM events e
+JOIN campaign_event_types et ON et.id = e.event_type_id
+JOIN clicks cl ON cl.click_id = e.click_id
+JOIN tracking_links tl ON tl.id = cl.tracking_link_id
+WHERE et.campaign_id = sqlc.arg(campaign_id)
+AND (e.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND e.status = 'pending'))
+AND NOT EXISTS (
+    SELECT 1
+    FROM fraud_flags ff
+    WHERE ff.status = 'cleared'
+    AND (ff.event_id = e.id OR (ff.event_type = 'click' AND ff.click_id = e.click_id))
+)
+GROUP BY tl.affiliate_id, et.name, e.currency
+ORDER BY tl.affiliate_id, et.name, e.currency;
@@ -0,0 +1,85 @@
+-- name: Create_Fraud_Flag :exec
+INSERT INTO fraud_flags (
+    brand_id,
+    event_type,
+    click_id,
+    conversion_id,
+    score,
+    reasons,
+    event_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (click_id) WHERE event_type = 'click' DO UPDATE SET
+    score = GREATEST(fraud_flags.score, EXCLUDED.score),
+    reasons = ARRAY(SELECT DISTINCT unnest(fraud_flags.reasons || EXCLUDED.reasons));
+
+
+-- name: List_Fraud_Flags :many
+SELECT 
+    ff.id,
+    ff.brand_id,
+    ff.event_type,
+    ff.click_id,
+    ff.conversion_id,
+    ff.score,
+    ff.reasons,
+    ff.status,
+    ff.reviewed_at,
+    ff.created_at,
+    ff.event_id,
+    tl.link_code,
+    tl.affiliate_id,
+    tl.campaign_id,
+    cl.user_ip,
+    cl.user_agent,
+    cl.timestamp AS clicked_at,
+    conv.amount AS conversion_amount
+FROM fraud_flags ff
+LEFT JOIN clicks cl ON cl.click_id = ff.click_id
+LEFT JOIN tracking_links tl ON tl.id = cl.tracking_link_id
+LEFT JOIN conversions conv ON conv.id = ff.conversion_id
+WHERE ff.brand_id = sqlc.arg(brand_id)
+AND (sqlc.narg(status)::varchar IS NULL OR ff.status = sqlc.narg(status))
+ORDER BY ff.score DESC, ff.created_at DESC
+LIMIT 500;
+
+
+-- name: Get_Fraud_Flag :one
+SELECT *
+FROM fraud_flags
+WHERE id = $1;
+
+
+-- name: Review_Fraud_Flag :one
+UPDATE fraud_flags
+SET 
+    status = $2,
+    reviewed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: Get_Campaign_Commissions :many
+-- commission_rate is a percentage of weighted conversion revenue, totalled
+-- per currency since amounts in different currencies cannot be added up
+SELECT 
+    tl.affiliate_id,
+    COALESCE(conv.currency, 'USD')::varchar AS currency,
+    COUNT(DISTINCT conv.id) AS conversions,
+    COALESCE(SUM(conv.amount * conv.weight), 0)::numeric(12, 2) AS revenue,
+    COALESCE(SUM(conv.amount * conv.weight)::numeric * c.commission_rate / 100, 0)::numeric(12, 2) AS commission
+FROM conversions conv
+JOIN clicks cl ON cl.click_id = conv.click_id
+JOIN tracking_links tl ON tl.id = cl.tracking_link_id
+JOIN campaigns c ON c.id = tl.campaign_id
+WHERE c.id = sqlc.arg(campaign_id)
+AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+AND NOT EXISTS (
+    SELECT 1
+    FROM fraud_flags ff
+    WHERE ff.status = 'cleared'
+    AND (ff.conversion_id = conv.id OR (ff.event_type = 'click' AND ff.click_id = conv.click_id))
+)
+GROUP BY tl.affiliate_id, COALESCE(conv.currency, 'USD'), c.commission_rate
+ORDER BY commission DESC;
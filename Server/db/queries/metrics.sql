@@ -3,7 +3,7 @@ WITH campaign_data AS (
     SELECT 
         COUNT(*) AS total_campaigns
     FROM campaigns
-    WHERE campaigns.brand_id = $1
+    WHERE campaigns.brand_id = sqlc.arg(brand_id)
 ),
 active_influencers_data AS (
     SELECT 
@@ -11,17 +11,18 @@ active_influencers_data AS (
     FROM affiliates a
     JOIN tracking_links tl ON a.id = tl.affiliate_id
     JOIN campaigns c ON tl.campaign_id = c.id
-    JOIN clicks cl ON tl.id = cl.tracking_link_id
-    WHERE c.brand_id = $1
+    JOIN clicks cl ON tl.id = cl.tracking_link_id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+    WHERE c.brand_id = sqlc.arg(brand_id)
     AND cl.timestamp >= NOW() - INTERVAL '30 days'
 ),
 click_data AS (
     SELECT 
-        COUNT(*) AS total_reach
+        COUNT(*) AS total_reach,
+        COALESCE(SUM(cl.raw_clicks), 0)::bigint AS total_raw_clicks
     FROM clicks cl
-    JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+    JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
     JOIN campaigns c ON tl.campaign_id = c.id
-    WHERE c.brand_id = $1
+    WHERE c.brand_id = sqlc.arg(brand_id)
 ),
 conversion_data AS (
     SELECT 
@@ -29,15 +30,16 @@ conversion_data AS (
         COALESCE((COUNT(DISTINCT conv.id) * 100.0 / NULLIF(COUNT(cl.id), 0)), 0)::numeric(10, 1) AS conversion_rate,
         COALESCE((SUM(conv.amount * conv.weight) / NULLIF(COUNT(DISTINCT conv.id), 0)), 0)::numeric(10, 2) AS average_order_value
     FROM clicks cl
-    JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+    JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
     JOIN campaigns c ON tl.campaign_id = c.id
-    LEFT JOIN conversions conv ON cl.click_id = conv.click_id
-    WHERE c.brand_id = $1
+    LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+    WHERE c.brand_id = sqlc.arg(brand_id)
 )
 SELECT 
     campaign_data.total_campaigns,
     active_influencers_data.active_influencers,
     click_data.total_reach,
+    click_data.total_raw_clicks,
     conversion_data.total_revenue,
     conversion_data.conversion_rate,
     conversion_data.average_order_value
@@ -49,14 +51,14 @@ FROM campaign_data, active_influencers_data, click_data, conversion_data;
 -- name: Get_Campaign_Performance :many
 SELECT 
     TO_CHAR(cl.timestamp, 'Mon') AS month,
-    COUNT(cl.id) AS impressions, -- Total clicks as a proxy for impressions
-    COUNT(cl.id) AS clicks,     -- Total clicks
+    COALESCE(SUM(cl.raw_clicks), 0)::bigint AS impressions, -- Every tap, repeats included
+    COUNT(cl.id) AS clicks,     -- Unique clicks
     COUNT(DISTINCT conv.id) AS conversions
 FROM clicks cl
-JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
 JOIN campaigns c ON tl.campaign_id = c.id
-LEFT JOIN conversions conv ON cl.click_id = conv.click_id
-WHERE c.brand_id = $1
+LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+WHERE c.brand_id = sqlc.arg(brand_id)
 GROUP BY TO_CHAR(cl.timestamp, 'Mon')
 ORDER BY MIN(cl.timestamp);
 
@@ -64,10 +66,11 @@ ORDER BY MIN(cl.timestamp);
 -- name: Get_Revenue_Data :many
 SELECT 
     TO_CHAR(s.timestamp, 'Mon') AS month,
-    COALESCE(SUM(s.amount), 0)::numeric(10, 2) AS raw_revenue,
+    COALESCE(SUM(s.amount - s.refunded_amount), 0)::numeric(10, 2) AS raw_revenue,
     STRING_AGG(DISTINCT s.currency, ', ') AS currencies -- Track currencies for logging
 FROM sales s
-WHERE s.brand_id = $1
+WHERE s.brand_id = sqlc.arg(brand_id)
+AND (s.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND s.status = 'pending'))
 GROUP BY TO_CHAR(s.timestamp, 'Mon')
 ORDER BY MIN(s.timestamp);
 
@@ -78,9 +81,9 @@ SELECT
     COALESCE(utm_source, 'Unknown') AS name,
     COUNT(*) AS value
 FROM clicks cl
-JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
 JOIN campaigns c ON tl.campaign_id = c.id
-WHERE c.brand_id = $1
+WHERE c.brand_id = sqlc.arg(brand_id)
 GROUP BY utm_source
 ORDER BY value DESC;
 
@@ -89,13 +92,70 @@ SELECT
     COALESCE(utm_medium, 'Unknown') AS name,
     COUNT(*) AS value
 FROM clicks cl
-JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
 JOIN campaigns c ON tl.campaign_id = c.id
-WHERE c.brand_id = $1
+WHERE c.brand_id = sqlc.arg(brand_id)
 GROUP BY utm_medium
 ORDER BY value DESC;
 
 
+-- name: Get_DeviceType_Counts :many
+SELECT 
+    INITCAP(COALESCE(device_type, 'unknown')) AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = sqlc.arg(brand_id)
+GROUP BY device_type
+ORDER BY value DESC;
+
+-- name: Get_OS_Counts :many
+SELECT 
+    COALESCE(os, 'Unknown') AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = sqlc.arg(brand_id)
+GROUP BY os
+ORDER BY value DESC;
+
+-- name: Get_Browser_Counts :many
+SELECT 
+    COALESCE(browser, 'Unknown') AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = sqlc.arg(brand_id)
+GROUP BY browser
+ORDER BY value DESC;
+
+-- name: Get_Referrer_Host_Counts :many
+SELECT 
+    COALESCE(cl.referrer_host, '') AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = sqlc.arg(brand_id)
+AND cl.referrer_host IS NOT NULL
+GROUP BY cl.referrer_host
+ORDER BY value DESC
+LIMIT 20;
+
+-- name: Get_Referrer_Platform_Counts :many
+SELECT 
+    COALESCE(cl.referrer_platform, 'unknown') AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = sqlc.arg(brand_id)
+GROUP BY cl.referrer_platform
+ORDER BY value DESC;
+
 
 -- name: Get_CampaignEffectiveness :many
 WITH campaign_metrics AS (
@@ -103,14 +163,14 @@ WITH campaign_metrics AS (
         c.name AS campaign,
         COUNT(DISTINCT cl.id) AS total_clicks,
         COUNT(DISTINCT conv.id) AS total_conversions,
-        COALESCE(SUM(s.amount), 0) AS total_sales,
+        COALESCE(SUM(s.amount - s.refunded_amount), 0) AS total_sales,
         COUNT(DISTINCT tl.id) AS total_tracking_links
     FROM campaigns c
     LEFT JOIN tracking_links tl ON c.id = tl.campaign_id
-    LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id
-    LEFT JOIN conversions conv ON cl.click_id = conv.click_id
-    LEFT JOIN sales s ON c.brand_id = s.brand_id -- Removed date filtering
-    WHERE c.brand_id = $1
+    LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+    LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+    LEFT JOIN sales s ON c.brand_id = s.brand_id AND (s.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND s.status = 'pending')) -- Removed date filtering
+    WHERE c.brand_id = sqlc.arg(brand_id)
     GROUP BY c.id, c.name
 )
 SELECT 
@@ -133,9 +193,9 @@ WITH monthly_metrics AS (
         COALESCE(SUM(conv.amount), 0) AS total_cost 
     FROM campaigns c
     JOIN tracking_links tl ON c.id = tl.campaign_id
-    JOIN clicks cl ON tl.id = cl.tracking_link_id
-    LEFT JOIN conversions conv ON cl.click_id = conv.click_id
-    WHERE c.brand_id = $1
+    JOIN clicks cl ON tl.id = cl.tracking_link_id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+    LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+    WHERE c.brand_id = sqlc.arg(brand_id)
     GROUP BY TO_CHAR(cl.timestamp, 'YYYY-MM')
 )
 SELECT 
@@ -157,14 +217,14 @@ WITH campaign_metrics AS (
         c.name AS campaign,
         COUNT(DISTINCT cl.id) AS total_clicks,
         COUNT(DISTINCT conv.id) AS total_conversions,
-        COALESCE(SUM(s.amount), 0) AS total_sales,
+        COALESCE(SUM(s.amount - s.refunded_amount), 0) AS total_sales,
         COUNT(DISTINCT tl.id) AS total_tracking_links
     FROM campaigns c
     LEFT JOIN tracking_links tl ON c.id = tl.campaign_id
-    LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id
-    LEFT JOIN conversions conv ON cl.click_id = conv.click_id
-    LEFT JOIN sales s ON c.brand_id = s.brand_id
-    WHERE c.brand_id = $1
+    LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+    LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+    LEFT JOIN sales s ON c.brand_id = s.brand_id AND (s.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND s.status = 'pending'))
+    WHERE c.brand_id = sqlc.arg(brand_id)
     GROUP BY c.id, c.name
 ),
 max_metrics AS (
@@ -194,4 +254,63 @@ SELECT
 FROM brands b
 LEFT JOIN sales s ON b.id = s.brand_id
 WHERE b.id = $1
-AND s.amount IS NOT NULL;
\ No newline at end of file
+AND s.amount IS NOT NULL;
+
+
+
+-- name: Get_SubLink_Breakdown :many
+SELECT 
+    tl.affiliate_id,
+    COALESCE(tl.label, 'default') AS label,
+    COALESCE(
+        CASE sqlc.arg(sub_key)::text
+            WHEN 'sub1' THEN cl.sub1
+            WHEN 'sub2' THEN cl.sub2
+            WHEN 'sub3' THEN cl.sub3
+            WHEN 'sub4' THEN cl.sub4
+            WHEN 'sub5' THEN cl.sub5
+        END,
+        ''
+    )::text AS sub_id,
+    COUNT(DISTINCT cl.id) AS clicks,
+    COUNT(DISTINCT conv.id) AS conversions,
+    COALESCE(SUM(conv.amount * conv.weight), 0)::numeric(10, 2) AS revenue
+FROM tracking_links tl
+JOIN clicks cl ON tl.id = cl.tracking_link_id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+WHERE tl.campaign_id = sqlc.arg(campaign_id)
+GROUP BY tl.affiliate_id, 2, 3
+ORDER BY clicks DESC;
+
+
+-- name: Get_Geo_Country_Breakdown :many
+SELECT 
+    COALESCE(cl.country, '') AS country,
+    COUNT(DISTINCT cl.id) AS clicks,
+    COUNT(DISTINCT conv.id) AS conversions,
+    COALESCE(SUM(conv.amount * conv.weight), 0)::numeric(10, 2) AS revenue
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+WHERE (sqlc.narg(brand_id)::bigint IS NULL OR c.brand_id = sqlc.narg(brand_id))
+AND (sqlc.narg(campaign_id)::bigint IS NULL OR c.id = sqlc.narg(campaign_id))
+GROUP BY 1
+ORDER BY clicks DESC;
+
+
+-- name: Get_Geo_Region_Breakdown :many
+SELECT 
+    COALESCE(cl.country, '') AS country,
+    COALESCE(cl.region, '') AS region,
+    COUNT(DISTINCT cl.id) AS clicks,
+    COUNT(DISTINCT conv.id) AS conversions,
+    COALESCE(SUM(conv.amount * conv.weight), 0)::numeric(10, 2) AS revenue
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND (sqlc.arg(include_bots)::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR (sqlc.arg(include_pending)::boolean AND conv.status = 'pending'))
+WHERE (sqlc.narg(brand_id)::bigint IS NULL OR c.brand_id = sqlc.narg(brand_id))
+AND (sqlc.narg(campaign_id)::bigint IS NULL OR c.id = sqlc.narg(campaign_id))
+GROUP BY 1, 2
+ORDER BY clicks DESC;
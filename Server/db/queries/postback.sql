@@ -0,0 +1,25 @@
+-- name: Upsert_Postback_Key :exec
+INSERT INTO brand_postback_keys (
+    brand_id,
+    key_hash,
+    signing_secret,
+    created_at
+) VALUES (
+    $1, $2, $3, CURRENT_TIMESTAMP
+)
+ON CONFLICT (brand_id) DO UPDATE
+SET key_hash = EXCLUDED.key_hash,
+    signing_secret = EXCLUDED.signing_secret,
+    created_at = CURRENT_TIMESTAMP;
+
+
+-- name: Get_Postback_Key :one
+SELECT *
+FROM brand_postback_keys
+WHERE brand_id = $1;
+
+
+-- name: Update_Pixel_Signature_Required :execrows
+UPDATE brand_postback_keys
+SET pixel_signature_required = $2
+WHERE brand_id = $1;
@@ -1,5 +1,68 @@
 -- name: Create_Sale :one
-INSERT INTO sales (brand_id, amount, currency, timestamp)
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (id) DO NOTHING
-RETURNING id, brand_id, amount, currency, timestamp, created_at;
\ No newline at end of file
+-- Returns no row when the brand already has a sale for order_id
+INSERT INTO sales (brand_id, amount, currency, timestamp, order_id, request_hash, event_name, status, approve_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (brand_id, order_id) DO NOTHING
+RETURNING *;
+
+
+-- name: Get_Sale_By_Order :one
+SELECT *
+FROM sales
+WHERE brand_id = $1 AND order_id = $2;
+
+
+-- name: Get_Sale :one
+SELECT *
+FROM sales
+WHERE id = $1;
+
+
+-- name: List_Sales_By_Brand :many
+SELECT *
+FROM sales
+WHERE brand_id = sqlc.arg(brand_id)
+AND (sqlc.narg(status)::varchar IS NULL OR status = sqlc.narg(status))
+ORDER BY id DESC
+LIMIT 500;
+
+
+-- name: Update_Sale_Status :one
+-- Returns no row unless the sale is currently in from_status
+UPDATE sales
+SET 
+    status = sqlc.arg(status),
+    status_reason = sqlc.narg(status_reason),
+    status_changed_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND status = sqlc.arg(from_status)
+RETURNING *;
+
+
+-- name: Refund_Sale :one
+-- Returns no row unless the sale is pending or approved and has at least
+-- amount left to refund
+UPDATE sales
+SET 
+    refunded_amount = refunded_amount + sqlc.arg(amount)::decimal,
+    status_reason = sqlc.narg(status_reason)
+WHERE id = sqlc.arg(id)
+AND status IN ('pending', 'approved')
+AND refunded_amount + sqlc.arg(amount)::decimal <= amount
+RETURNING *;
+
+
+-- name: Approve_Due_Sales :one
+-- Approves every pending sale whose approve_at has passed, and its conversions
+WITH due AS (
+    UPDATE sales
+    SET 
+        status = 'approved',
+        status_changed_at = CURRENT_TIMESTAMP
+    WHERE status = 'pending' AND approve_at <= CURRENT_TIMESTAMP
+    RETURNING id
+), approved AS (
+    UPDATE conversions
+    SET status = 'approved'
+    WHERE sale_id IN (SELECT id FROM due) AND status = 'pending'
+)
+SELECT COUNT(*) FROM due;
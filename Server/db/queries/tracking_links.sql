@@ -3,9 +3,13 @@ INSERT INTO tracking_links (
     affiliate_id,
     campaign_id,
     link_code,
+    label,
+    utm_source,
+    utm_medium,
+    utm_campaign,
     created_at
 ) VALUES (
-    $1, $2, $3, CURRENT_TIMESTAMP
+    $1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP
 ) RETURNING *;
 
 
@@ -20,10 +24,21 @@ FROM tracking_links
 WHERE link_code = $1;
 
 -- name: Get_TrackingLinks_By_Affiliate :many
-SELECT *
-FROM tracking_links
-WHERE affiliate_id = $1
-ORDER BY created_at DESC;
+SELECT 
+    tl.*,
+    bd.domain AS short_domain
+FROM tracking_links tl
+LEFT JOIN campaigns c ON tl.campaign_id = c.id
+LEFT JOIN LATERAL (
+    SELECT domain
+    FROM brand_domains
+    WHERE brand_domains.brand_id = c.brand_id
+    AND brand_domains.verified_at IS NOT NULL
+    ORDER BY created_at
+    LIMIT 1
+) bd ON true
+WHERE tl.affiliate_id = $1
+ORDER BY tl.created_at DESC;
 
 
 -- name: Get_TrackingLinks_By_Campaign :many
@@ -33,6 +48,14 @@ WHERE campaign_id = $1
 ORDER BY created_at DESC;
 
 
+-- name: Get_TrackingLinks_By_Affiliate_Campaign :many
+-- The unlabelled default link first, then sub-links by label
+SELECT *
+FROM tracking_links
+WHERE affiliate_id = $1 AND campaign_id = $2
+ORDER BY label NULLS FIRST, created_at;
+
+
 -- name: Delete_TrackingLink :exec
 DELETE FROM tracking_links
 WHERE id = $1;
@@ -47,4 +70,79 @@ SELECT
 FROM users u
 JOIN affiliates a ON u.id = a.user_id
 JOIN tracking_links tl ON a.id = tl.affiliate_id
-WHERE tl.campaign_id = $1 AND u.id = $2;
\ No newline at end of file
+WHERE tl.campaign_id = $1 AND u.id = $2
+ORDER BY tl.label NULLS FIRST, tl.created_at
+LIMIT 1;
+
+
+-- name: Claim_TrackingLink_Click :one
+-- Takes one click from a capped link's allowance; no row means it is used up
+UPDATE tracking_links
+SET click_count = click_count + 1
+WHERE id = $1 AND (max_clicks IS NULL OR click_count < max_clicks)
+RETURNING click_count;
+
+
+-- name: Release_TrackingLink_Click :exec
+-- Gives back a click taken by Claim_TrackingLink_Click that was never recorded
+UPDATE tracking_links
+SET click_count = click_count - 1
+WHERE id = $1 AND click_count > 0;
+
+
+-- name: Add_TrackingLink_Clicks :exec
+UPDATE tracking_links
+SET click_count = click_count + sqlc.arg(clicks)
+WHERE id = sqlc.arg(id);
+
+
+-- name: Set_TrackingLink_Active :one
+UPDATE tracking_links
+SET is_active = $2
+WHERE id = $1
+RETURNING *;
+
+
+-- name: Update_TrackingLink_Limits :one
+UPDATE tracking_links
+SET 
+    expires_at = $2,
+    max_clicks = $3
+WHERE id = $1
+RETURNING *;
+
+
+-- name: Update_TrackingLink_Dedup_Window :one
+UPDATE tracking_links
+SET dedup_window_seconds = $2
+WHERE id = $1
+RETURNING *;
+
+
+-- name: Get_TrackingLink_QR_Target :one
+SELECT 
+    tl.link_code,
+    c.brand_id,
+    bd.domain AS short_domain
+FROM tracking_links tl
+JOIN campaigns c ON tl.campaign_id = c.id
+LEFT JOIN LATERAL (
+    SELECT domain
+    FROM brand_domains
+    WHERE brand_domains.brand_id = c.brand_id
+    AND brand_domains.verified_at IS NOT NULL
+    ORDER BY created_at
+    LIMIT 1
+) bd ON true
+WHERE tl.id = $1;
+
+
+-- name: Get_TrackingLink_Owners :one
+-- The brand running a link's campaign and the user behind its affiliate
+SELECT 
+    c.brand_id,
+    a.user_id AS affiliate_user_id
+FROM tracking_links tl
+LEFT JOIN campaigns c ON c.id = tl.campaign_id
+LEFT JOIN affiliates a ON a.id = tl.affiliate_id
+WHERE tl.id = $1;
@@ -78,8 +78,8 @@ FROM affiliate_campaigns ac
 JOIN affiliates a ON ac.affiliate_id = a.id
 JOIN users u ON a.user_id = u.id
 LEFT JOIN tracking_links tl ON tl.affiliate_id = a.id AND tl.campaign_id = ac.campaign_id
-LEFT JOIN clicks c ON c.tracking_link_id = tl.id
-LEFT JOIN conversions conv ON conv.click_id = c.click_id
+LEFT JOIN clicks c ON c.tracking_link_id = tl.id AND ($2::boolean OR NOT c.is_bot)
+LEFT JOIN conversions conv ON conv.click_id = c.click_id AND (conv.status = 'approved' OR ($3::boolean AND conv.status = 'pending'))
 WHERE ac.campaign_id = $1
 GROUP BY 
     a.id,
@@ -108,8 +108,14 @@ type Get_Affiliates_By_CampaignIDRow struct {
 	ConversionIds              interface{}
 }
 
-func (q *Queries) Get_Affiliates_By_CampaignID(ctx context.Context, campaignID int64) ([]Get_Affiliates_By_CampaignIDRow, error) {
-	rows, err := q.db.QueryContext(ctx, get_Affiliates_By_CampaignID, campaignID)
+type Get_Affiliates_By_CampaignIDParams struct {
+	CampaignID     int64
+	IncludeBots    bool
+	IncludePending bool
+}
+
+func (q *Queries) Get_Affiliates_By_CampaignID(ctx context.Context, arg Get_Affiliates_By_CampaignIDParams) ([]Get_Affiliates_By_CampaignIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Affiliates_By_CampaignID, arg.CampaignID, arg.IncludeBots, arg.IncludePending)
 	if err != nil {
 		return nil, err
 	}
@@ -10,6 +10,16 @@ import (
 	"database/sql"
 )
 
+const delete_BrandLogo = `-- name: Delete_BrandLogo :exec
+DELETE FROM brand_logos
+WHERE brand_id = $1
+`
+
+func (q *Queries) Delete_BrandLogo(ctx context.Context, brandID int64) error {
+	_, err := q.db.ExecContext(ctx, delete_BrandLogo, brandID)
+	return err
+}
+
 const get_BrandID_By_TrackingCode = `-- name: Get_BrandID_By_TrackingCode :one
 SELECT c.brand_id
 FROM tracking_links tl
@@ -25,9 +35,27 @@ func (q *Queries) Get_BrandID_By_TrackingCode(ctx context.Context, linkCode stri
 	return brand_id, err
 }
 
+const get_BrandLogo = `-- name: Get_BrandLogo :one
+SELECT brand_id, content_type, data, updated_at
+FROM brand_logos
+WHERE brand_id = $1
+`
+
+func (q *Queries) Get_BrandLogo(ctx context.Context, brandID int64) (BrandLogo, error) {
+	row := q.db.QueryRowContext(ctx, get_BrandLogo, brandID)
+	var i BrandLogo
+	err := row.Scan(
+		&i.BrandID,
+		&i.ContentType,
+		&i.Data,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const get_Brand_By_UserID = `-- name: Get_Brand_By_UserID :one
 SELECT 
-    b.id, b.user_id, b.company_name, b.website, b.created_at,
+    b.id, b.user_id, b.company_name, b.website, b.created_at, b.attribution_model, b.attribution_half_life_hours,
     u.username,
     u.email,
     u.role
@@ -37,14 +65,16 @@ WHERE b.user_id = $1
 `
 
 type Get_Brand_By_UserIDRow struct {
-	ID          int64
-	UserID      sql.NullInt64
-	CompanyName string
-	Website     sql.NullString
-	CreatedAt   sql.NullTime
-	Username    string
-	Email       string
-	Role        string
+	ID                       int64
+	UserID                   sql.NullInt64
+	CompanyName              string
+	Website                  sql.NullString
+	CreatedAt                sql.NullTime
+	AttributionModel         string
+	AttributionHalfLifeHours sql.NullInt32
+	Username                 string
+	Email                    string
+	Role                     string
 }
 
 func (q *Queries) Get_Brand_By_UserID(ctx context.Context, userID sql.NullInt64) (Get_Brand_By_UserIDRow, error) {
@@ -56,9 +86,59 @@ func (q *Queries) Get_Brand_By_UserID(ctx context.Context, userID sql.NullInt64)
 		&i.CompanyName,
 		&i.Website,
 		&i.CreatedAt,
+		&i.AttributionModel,
+		&i.AttributionHalfLifeHours,
 		&i.Username,
 		&i.Email,
 		&i.Role,
 	)
 	return i, err
 }
+
+const update_Brand_Attribution = `-- name: Update_Brand_Attribution :execrows
+UPDATE brands
+SET 
+    attribution_model = $2,
+    attribution_half_life_hours = $3
+WHERE id = $1
+`
+
+type Update_Brand_AttributionParams struct {
+	ID                       int64
+	AttributionModel         string
+	AttributionHalfLifeHours sql.NullInt32
+}
+
+func (q *Queries) Update_Brand_Attribution(ctx context.Context, arg Update_Brand_AttributionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, update_Brand_Attribution, arg.ID, arg.AttributionModel, arg.AttributionHalfLifeHours)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const upsert_BrandLogo = `-- name: Upsert_BrandLogo :exec
+INSERT INTO brand_logos (
+    brand_id,
+    content_type,
+    data,
+    updated_at
+) VALUES (
+    $1, $2, $3, CURRENT_TIMESTAMP
+)
+ON CONFLICT (brand_id) DO UPDATE
+SET content_type = EXCLUDED.content_type,
+    data = EXCLUDED.data,
+    updated_at = CURRENT_TIMESTAMP
+`
+
+type Upsert_BrandLogoParams struct {
+	BrandID     int64
+	ContentType string
+	Data        []byte
+}
+
+func (q *Queries) Upsert_BrandLogo(ctx context.Context, arg Upsert_BrandLogoParams) error {
+	_, err := q.db.ExecContext(ctx, upsert_BrandLogo, arg.BrandID, arg.ContentType, arg.Data)
+	return err
+}
@@ -8,6 +8,8 @@ package sqlc
 import (
 	"context"
 	"database/sql"
+
+	"github.com/lib/pq"
 )
 
 const campaign_Exists_By_Id = `-- name: Campaign_Exists_By_Id :one
@@ -39,18 +41,24 @@ INSERT INTO campaigns (
     description,
     commission_rate,
     landing_url,
+    fallback_url,
+    lookback_days,
+    approval_delay_days,
     created_at
 ) VALUES (
-    $1, $2, $3, $4, $5, CURRENT_TIMESTAMP
-) RETURNING id, brand_id, name, description, commission_rate, landing_url, created_at
+    $1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP
+) RETURNING id, brand_id, name, description, commission_rate, landing_url, created_at, fallback_url, passthrough_params, redirect_template, attribution_model, attribution_half_life_hours, lookback_days, approval_delay_days
 `
 
 type Create_CampaignParams struct {
-	BrandID        sql.NullInt64
-	Name           string
-	Description    sql.NullString
-	CommissionRate string
-	LandingUrl     string
+	BrandID           sql.NullInt64
+	Name              string
+	Description       sql.NullString
+	CommissionRate    string
+	LandingUrl        string
+	FallbackUrl       sql.NullString
+	LookbackDays      int32
+	ApprovalDelayDays int32
 }
 
 func (q *Queries) Create_Campaign(ctx context.Context, arg Create_CampaignParams) (Campaign, error) {
@@ -60,6 +68,9 @@ func (q *Queries) Create_Campaign(ctx context.Context, arg Create_CampaignParams
 		arg.Description,
 		arg.CommissionRate,
 		arg.LandingUrl,
+		arg.FallbackUrl,
+		arg.LookbackDays,
+		arg.ApprovalDelayDays,
 	)
 	var i Campaign
 	err := row.Scan(
@@ -70,6 +81,13 @@ func (q *Queries) Create_Campaign(ctx context.Context, arg Create_CampaignParams
 		&i.CommissionRate,
 		&i.LandingUrl,
 		&i.CreatedAt,
+		&i.FallbackUrl,
+		pq.Array(&i.PassthroughParams),
+		&i.RedirectTemplate,
+		&i.AttributionModel,
+		&i.AttributionHalfLifeHours,
+		&i.LookbackDays,
+		&i.ApprovalDelayDays,
 	)
 	return i, err
 }
@@ -84,8 +102,30 @@ func (q *Queries) DeleteCampaign(ctx context.Context, id int64) error {
 	return err
 }
 
+const get_Attribution_Settings = `-- name: Get_Attribution_Settings :one
+SELECT 
+    COALESCE(c.attribution_model, b.attribution_model)::varchar AS attribution_model,
+    COALESCE(c.attribution_half_life_hours, b.attribution_half_life_hours) AS attribution_half_life_hours
+FROM campaigns c
+JOIN brands b ON b.id = c.brand_id
+WHERE c.id = $1
+`
+
+type Get_Attribution_SettingsRow struct {
+	AttributionModel         string
+	AttributionHalfLifeHours sql.NullInt32
+}
+
+// A campaign without its own model uses its brand's
+func (q *Queries) Get_Attribution_Settings(ctx context.Context, id int64) (Get_Attribution_SettingsRow, error) {
+	row := q.db.QueryRowContext(ctx, get_Attribution_Settings, id)
+	var i Get_Attribution_SettingsRow
+	err := row.Scan(&i.AttributionModel, &i.AttributionHalfLifeHours)
+	return i, err
+}
+
 const get_Campaign = `-- name: Get_Campaign :one
-SELECT id, brand_id, name, description, commission_rate, landing_url, created_at
+SELECT id, brand_id, name, description, commission_rate, landing_url, created_at, fallback_url, passthrough_params, redirect_template, attribution_model, attribution_half_life_hours, lookback_days, approval_delay_days
 FROM campaigns
 WHERE id = $1
 `
@@ -101,12 +141,19 @@ func (q *Queries) Get_Campaign(ctx context.Context, id int64) (Campaign, error)
 		&i.CommissionRate,
 		&i.LandingUrl,
 		&i.CreatedAt,
+		&i.FallbackUrl,
+		pq.Array(&i.PassthroughParams),
+		&i.RedirectTemplate,
+		&i.AttributionModel,
+		&i.AttributionHalfLifeHours,
+		&i.LookbackDays,
+		&i.ApprovalDelayDays,
 	)
 	return i, err
 }
 
 const get_Campaigns_By_Brand = `-- name: Get_Campaigns_By_Brand :many
-SELECT id, brand_id, name, description, commission_rate, landing_url, created_at
+SELECT id, brand_id, name, description, commission_rate, landing_url, created_at, fallback_url, passthrough_params, redirect_template, attribution_model, attribution_half_life_hours, lookback_days, approval_delay_days
 FROM campaigns
 WHERE brand_id = $1
 ORDER BY created_at DESC
@@ -129,6 +176,13 @@ func (q *Queries) Get_Campaigns_By_Brand(ctx context.Context, brandID sql.NullIn
 			&i.CommissionRate,
 			&i.LandingUrl,
 			&i.CreatedAt,
+			&i.FallbackUrl,
+			pq.Array(&i.PassthroughParams),
+			&i.RedirectTemplate,
+			&i.AttributionModel,
+			&i.AttributionHalfLifeHours,
+			&i.LookbackDays,
+			&i.ApprovalDelayDays,
 		); err != nil {
 			return nil, err
 		}
@@ -151,6 +205,8 @@ SELECT
     c.description AS campaign_description,
     c.commission_rate,
     c.landing_url,
+    c.lookback_days,
+    c.approval_delay_days,
     c.created_at AS campaign_created_at,
     ac.created_at AS affiliate_campaign_created_at,
     b.id AS brand_id,
@@ -173,6 +229,8 @@ type Get_Campaigns_By_UserID_As_AffiliateRow struct {
 	CampaignDescription        sql.NullString
 	CommissionRate             string
 	LandingUrl                 string
+	LookbackDays               int32
+	ApprovalDelayDays          int32
 	CampaignCreatedAt          sql.NullTime
 	AffiliateCampaignCreatedAt sql.NullTime
 	BrandID_2                  int64
@@ -197,6 +255,8 @@ func (q *Queries) Get_Campaigns_By_UserID_As_Affiliate(ctx context.Context, id i
 			&i.CampaignDescription,
 			&i.CommissionRate,
 			&i.LandingUrl,
+			&i.LookbackDays,
+			&i.ApprovalDelayDays,
 			&i.CampaignCreatedAt,
 			&i.AffiliateCampaignCreatedAt,
 			&i.BrandID_2,
@@ -225,7 +285,7 @@ SET
     commission_rate = $4,
     landing_url = $5
 WHERE id = $1
-RETURNING id, brand_id, name, description, commission_rate, landing_url, created_at
+RETURNING id, brand_id, name, description, commission_rate, landing_url, created_at, fallback_url, passthrough_params, redirect_template, attribution_model, attribution_half_life_hours, lookback_days, approval_delay_days
 `
 
 type Update_CampaignParams struct {
@@ -253,6 +313,69 @@ func (q *Queries) Update_Campaign(ctx context.Context, arg Update_CampaignParams
 		&i.CommissionRate,
 		&i.LandingUrl,
 		&i.CreatedAt,
+		&i.FallbackUrl,
+		pq.Array(&i.PassthroughParams),
+		&i.RedirectTemplate,
+		&i.AttributionModel,
+		&i.AttributionHalfLifeHours,
+		&i.LookbackDays,
+		&i.ApprovalDelayDays,
+	)
+	return i, err
+}
+
+const update_Campaign_Settings = `-- name: Update_Campaign_Settings :one
+UPDATE campaigns
+SET 
+    fallback_url = NULLIF(COALESCE($1, fallback_url), ''),
+    passthrough_params = COALESCE($2, passthrough_params),
+    redirect_template = NULLIF(COALESCE($3, redirect_template), ''),
+    attribution_model = NULLIF(COALESCE($4, attribution_model), ''),
+    attribution_half_life_hours = NULLIF(COALESCE($5, attribution_half_life_hours), 0),
+    lookback_days = COALESCE($6, lookback_days),
+    approval_delay_days = COALESCE($7, approval_delay_days)
+WHERE id = $8
+RETURNING id, brand_id, name, description, commission_rate, landing_url, created_at, fallback_url, passthrough_params, redirect_template, attribution_model, attribution_half_life_hours, lookback_days, approval_delay_days
+`
+
+type Update_Campaign_SettingsParams struct {
+	FallbackUrl              sql.NullString
+	PassthroughParams        []string
+	RedirectTemplate         sql.NullString
+	AttributionModel         sql.NullString
+	AttributionHalfLifeHours sql.NullInt32
+	LookbackDays             sql.NullInt32
+	ApprovalDelayDays        sql.NullInt32
+	ID                       int64
+}
+
+func (q *Queries) Update_Campaign_Settings(ctx context.Context, arg Update_Campaign_SettingsParams) (Campaign, error) {
+	row := q.db.QueryRowContext(ctx, update_Campaign_Settings,
+		arg.FallbackUrl,
+		pq.Array(arg.PassthroughParams),
+		arg.RedirectTemplate,
+		arg.AttributionModel,
+		arg.AttributionHalfLifeHours,
+		arg.LookbackDays,
+		arg.ApprovalDelayDays,
+		arg.ID,
+	)
+	var i Campaign
+	err := row.Scan(
+		&i.ID,
+		&i.BrandID,
+		&i.Name,
+		&i.Description,
+		&i.CommissionRate,
+		&i.LandingUrl,
+		&i.CreatedAt,
+		&i.FallbackUrl,
+		pq.Array(&i.PassthroughParams),
+		&i.RedirectTemplate,
+		&i.AttributionModel,
+		&i.AttributionHalfLifeHours,
+		&i.LookbackDays,
+		&i.ApprovalDelayDays,
 	)
 	return i, err
 }
@@ -10,6 +10,7 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const create_Click = `-- name: Create_Click :one
@@ -20,20 +21,56 @@ INSERT INTO clicks (
     user_agent,
     referrer,
     utm_source,
-    utm_medium
+    utm_medium,
+    utm_campaign,
+    sub1,
+    sub2,
+    sub3,
+    sub4,
+    sub5,
+    is_bot,
+    bot_reason,
+    fingerprint,
+    device_type,
+    os,
+    browser,
+    browser_version,
+    country,
+    region,
+    city,
+    referrer_host,
+    referrer_platform
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
-) RETURNING id, tracking_link_id, click_id, user_ip, user_agent, referrer, timestamp, utm_source, utm_medium
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25
+) RETURNING id, tracking_link_id, click_id, user_ip, user_agent, referrer, timestamp, utm_source, utm_medium, utm_campaign, sub1, sub2, sub3, sub4, sub5, is_bot, bot_reason, fingerprint, raw_clicks, last_clicked_at, device_type, os, browser, browser_version, country, region, city, pii_scrubbed_at, referrer_host, referrer_platform
 `
 
 type Create_ClickParams struct {
-	TrackingLinkID sql.NullInt64
-	ClickID        uuid.UUID
-	UserIp         string
-	UserAgent      sql.NullString
-	Referrer       sql.NullString
-	UtmSource      sql.NullString
-	UtmMedium      sql.NullString
+	TrackingLinkID   sql.NullInt64
+	ClickID          uuid.UUID
+	UserIp           sql.NullString
+	UserAgent        sql.NullString
+	Referrer         sql.NullString
+	UtmSource        sql.NullString
+	UtmMedium        sql.NullString
+	UtmCampaign      sql.NullString
+	Sub1             sql.NullString
+	Sub2             sql.NullString
+	Sub3             sql.NullString
+	Sub4             sql.NullString
+	Sub5             sql.NullString
+	IsBot            bool
+	BotReason        sql.NullString
+	Fingerprint      sql.NullString
+	DeviceType       sql.NullString
+	Os               sql.NullString
+	Browser          sql.NullString
+	BrowserVersion   sql.NullString
+	Country          sql.NullString
+	Region           sql.NullString
+	City             sql.NullString
+	ReferrerHost     sql.NullString
+	ReferrerPlatform sql.NullString
 }
 
 func (q *Queries) Create_Click(ctx context.Context, arg Create_ClickParams) (Click, error) {
@@ -45,6 +82,24 @@ func (q *Queries) Create_Click(ctx context.Context, arg Create_ClickParams) (Cli
 		arg.Referrer,
 		arg.UtmSource,
 		arg.UtmMedium,
+		arg.UtmCampaign,
+		arg.Sub1,
+		arg.Sub2,
+		arg.Sub3,
+		arg.Sub4,
+		arg.Sub5,
+		arg.IsBot,
+		arg.BotReason,
+		arg.Fingerprint,
+		arg.DeviceType,
+		arg.Os,
+		arg.Browser,
+		arg.BrowserVersion,
+		arg.Country,
+		arg.Region,
+		arg.City,
+		arg.ReferrerHost,
+		arg.ReferrerPlatform,
 	)
 	var i Click
 	err := row.Scan(
@@ -57,12 +112,33 @@ func (q *Queries) Create_Click(ctx context.Context, arg Create_ClickParams) (Cli
 		&i.Timestamp,
 		&i.UtmSource,
 		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.Sub1,
+		&i.Sub2,
+		&i.Sub3,
+		&i.Sub4,
+		&i.Sub5,
+		&i.IsBot,
+		&i.BotReason,
+		&i.Fingerprint,
+		&i.RawClicks,
+		&i.LastClickedAt,
+		&i.DeviceType,
+		&i.Os,
+		&i.Browser,
+		&i.BrowserVersion,
+		&i.Country,
+		&i.Region,
+		&i.City,
+		&i.PiiScrubbedAt,
+		&i.ReferrerHost,
+		&i.ReferrerPlatform,
 	)
 	return i, err
 }
 
 const get_Click_By_ClickID = `-- name: Get_Click_By_ClickID :one
-SELECT id, tracking_link_id, click_id, user_ip, user_agent, referrer, timestamp, utm_source, utm_medium
+SELECT id, tracking_link_id, click_id, user_ip, user_agent, referrer, timestamp, utm_source, utm_medium, utm_campaign, sub1, sub2, sub3, sub4, sub5, is_bot, bot_reason, fingerprint, raw_clicks, last_clicked_at, device_type, os, browser, browser_version, country, region, city, pii_scrubbed_at, referrer_host, referrer_platform
 FROM clicks
 WHERE click_id = $1
 `
@@ -80,12 +156,33 @@ func (q *Queries) Get_Click_By_ClickID(ctx context.Context, clickID uuid.UUID) (
 		&i.Timestamp,
 		&i.UtmSource,
 		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.Sub1,
+		&i.Sub2,
+		&i.Sub3,
+		&i.Sub4,
+		&i.Sub5,
+		&i.IsBot,
+		&i.BotReason,
+		&i.Fingerprint,
+		&i.RawClicks,
+		&i.LastClickedAt,
+		&i.DeviceType,
+		&i.Os,
+		&i.Browser,
+		&i.BrowserVersion,
+		&i.Country,
+		&i.Region,
+		&i.City,
+		&i.PiiScrubbedAt,
+		&i.ReferrerHost,
+		&i.ReferrerPlatform,
 	)
 	return i, err
 }
 
 const get_Click_By_ID = `-- name: Get_Click_By_ID :one
-SELECT id, tracking_link_id, click_id, user_ip, user_agent, referrer, timestamp, utm_source, utm_medium
+SELECT id, tracking_link_id, click_id, user_ip, user_agent, referrer, timestamp, utm_source, utm_medium, utm_campaign, sub1, sub2, sub3, sub4, sub5, is_bot, bot_reason, fingerprint, raw_clicks, last_clicked_at, device_type, os, browser, browser_version, country, region, city, pii_scrubbed_at, referrer_host, referrer_platform
 FROM clicks
 WHERE id = $1
 `
@@ -103,6 +200,184 @@ func (q *Queries) Get_Click_By_ID(ctx context.Context, id int64) (Click, error)
 		&i.Timestamp,
 		&i.UtmSource,
 		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.Sub1,
+		&i.Sub2,
+		&i.Sub3,
+		&i.Sub4,
+		&i.Sub5,
+		&i.IsBot,
+		&i.BotReason,
+		&i.Fingerprint,
+		&i.RawClicks,
+		&i.LastClickedAt,
+		&i.DeviceType,
+		&i.Os,
+		&i.Browser,
+		&i.BrowserVersion,
+		&i.Country,
+		&i.Region,
+		&i.City,
+		&i.PiiScrubbedAt,
+		&i.ReferrerHost,
+		&i.ReferrerPlatform,
 	)
 	return i, err
 }
+
+const get_Clicks_Missing_Device = `-- name: Get_Clicks_Missing_Device :many
+SELECT id, user_agent
+FROM clicks
+WHERE device_type IS NULL AND id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type Get_Clicks_Missing_DeviceParams struct {
+	AfterID   int64
+	BatchSize int32
+}
+
+type Get_Clicks_Missing_DeviceRow struct {
+	ID        int64
+	UserAgent sql.NullString
+}
+
+func (q *Queries) Get_Clicks_Missing_Device(ctx context.Context, arg Get_Clicks_Missing_DeviceParams) ([]Get_Clicks_Missing_DeviceRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Clicks_Missing_Device, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_Clicks_Missing_DeviceRow
+	for rows.Next() {
+		var i Get_Clicks_Missing_DeviceRow
+		if err := rows.Scan(&i.ID, &i.UserAgent); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const get_Tracker_Clicks = `-- name: Get_Tracker_Clicks :many
+SELECT 
+    cl.click_id,
+    cl.timestamp,
+    tl.link_code,
+    tl.campaign_id,
+    c.brand_id,
+    c.lookback_days,
+    c.approval_delay_days
+FROM clicks cl
+JOIN tracking_links tl ON tl.id = cl.tracking_link_id
+JOIN campaigns c ON c.id = tl.campaign_id
+WHERE cl.click_id = ANY($1::uuid[])
+`
+
+type Get_Tracker_ClicksRow struct {
+	ClickID           uuid.UUID
+	Timestamp         sql.NullTime
+	LinkCode          string
+	CampaignID        sql.NullInt64
+	BrandID           sql.NullInt64
+	LookbackDays      int32
+	ApprovalDelayDays int32
+}
+
+func (q *Queries) Get_Tracker_Clicks(ctx context.Context, clickIds []uuid.UUID) ([]Get_Tracker_ClicksRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Tracker_Clicks, pq.Array(clickIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_Tracker_ClicksRow
+	for rows.Next() {
+		var i Get_Tracker_ClicksRow
+		if err := rows.Scan(
+			&i.ClickID,
+			&i.Timestamp,
+			&i.LinkCode,
+			&i.CampaignID,
+			&i.BrandID,
+			&i.LookbackDays,
+			&i.ApprovalDelayDays,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const scrub_Click_PII = `-- name: Scrub_Click_PII :execrows
+UPDATE clicks
+SET 
+    user_ip = NULL,
+    user_agent = NULL,
+    referrer = NULL,
+    fingerprint = NULL,
+    city = NULL,
+    pii_scrubbed_at = NOW()
+WHERE id IN (
+    SELECT id
+    FROM clicks
+    WHERE pii_scrubbed_at IS NULL AND timestamp < $1
+    ORDER BY id
+    LIMIT $2
+)
+`
+
+type Scrub_Click_PIIParams struct {
+	Cutoff    sql.NullTime
+	BatchSize int32
+}
+
+func (q *Queries) Scrub_Click_PII(ctx context.Context, arg Scrub_Click_PIIParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, scrub_Click_PII, arg.Cutoff, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const update_Click_Device = `-- name: Update_Click_Device :exec
+UPDATE clicks
+SET 
+    device_type = $2,
+    os = $3,
+    browser = $4,
+    browser_version = $5
+WHERE id = $1
+`
+
+type Update_Click_DeviceParams struct {
+	ID             int64
+	DeviceType     sql.NullString
+	Os             sql.NullString
+	Browser        sql.NullString
+	BrowserVersion sql.NullString
+}
+
+func (q *Queries) Update_Click_Device(ctx context.Context, arg Update_Click_DeviceParams) error {
+	_, err := q.db.ExecContext(ctx, update_Click_Device,
+		arg.ID,
+		arg.DeviceType,
+		arg.Os,
+		arg.Browser,
+		arg.BrowserVersion,
+	)
+	return err
+}
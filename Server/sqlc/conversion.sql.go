@@ -18,17 +18,23 @@ INSERT INTO conversions (
     amount,
     currency,
     weight,
+    sale_id,
+    attribution_model,
+    status,
     timestamp
 ) VALUES (
-    $1, $2, $3, $4 ,CURRENT_TIMESTAMP
-) RETURNING id, amount, timestamp, currency, click_id, weight
+    $1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP
+) RETURNING id, amount, timestamp, currency, click_id, weight, sale_id, attribution_model, status
 `
 
 type Create_ConversionParams struct {
-	ClickID  uuid.NullUUID
-	Amount   float64
-	Currency sql.NullString
-	Weight   sql.NullFloat64
+	ClickID          uuid.NullUUID
+	Amount           float64
+	Currency         sql.NullString
+	Weight           sql.NullFloat64
+	SaleID           sql.NullInt64
+	AttributionModel sql.NullString
+	Status           string
 }
 
 func (q *Queries) Create_Conversion(ctx context.Context, arg Create_ConversionParams) (Conversion, error) {
@@ -37,6 +43,9 @@ func (q *Queries) Create_Conversion(ctx context.Context, arg Create_ConversionPa
 		arg.Amount,
 		arg.Currency,
 		arg.Weight,
+		arg.SaleID,
+		arg.AttributionModel,
+		arg.Status,
 	)
 	var i Conversion
 	err := row.Scan(
@@ -46,6 +55,97 @@ func (q *Queries) Create_Conversion(ctx context.Context, arg Create_ConversionPa
 		&i.Currency,
 		&i.ClickID,
 		&i.Weight,
+		&i.SaleID,
+		&i.AttributionModel,
+		&i.Status,
 	)
 	return i, err
 }
+
+const get_Conversions_By_Sale = `-- name: Get_Conversions_By_Sale :many
+SELECT id, amount, timestamp, currency, click_id, weight, sale_id, attribution_model, status
+FROM conversions
+WHERE sale_id = $1
+ORDER BY id
+`
+
+func (q *Queries) Get_Conversions_By_Sale(ctx context.Context, saleID sql.NullInt64) ([]Conversion, error) {
+	rows, err := q.db.QueryContext(ctx, get_Conversions_By_Sale, saleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Conversion
+	for rows.Next() {
+		var i Conversion
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.Timestamp,
+			&i.Currency,
+			&i.ClickID,
+			&i.Weight,
+			&i.SaleID,
+			&i.AttributionModel,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const scale_Sale_Conversions = `-- name: Scale_Sale_Conversions :exec
+UPDATE conversions conv
+SET amount = (s.amount - s.refunded_amount)::float
+FROM sales s
+WHERE s.id = conv.sale_id AND s.id = $1
+`
+
+// Sets each conversion of a sale to what is left of the sale after refunds
+func (q *Queries) Scale_Sale_Conversions(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, scale_Sale_Conversions, id)
+	return err
+}
+
+const update_Conversion_Attribution = `-- name: Update_Conversion_Attribution :exec
+UPDATE conversions
+SET 
+    weight = $2,
+    attribution_model = $3
+WHERE id = $1
+`
+
+type Update_Conversion_AttributionParams struct {
+	ID               int64
+	Weight           sql.NullFloat64
+	AttributionModel sql.NullString
+}
+
+func (q *Queries) Update_Conversion_Attribution(ctx context.Context, arg Update_Conversion_AttributionParams) error {
+	_, err := q.db.ExecContext(ctx, update_Conversion_Attribution, arg.ID, arg.Weight, arg.AttributionModel)
+	return err
+}
+
+const update_Sale_Conversions_Status = `-- name: Update_Sale_Conversions_Status :exec
+UPDATE conversions
+SET status = $2
+WHERE sale_id = $1
+`
+
+type Update_Sale_Conversions_StatusParams struct {
+	SaleID sql.NullInt64
+	Status string
+}
+
+func (q *Queries) Update_Sale_Conversions_Status(ctx context.Context, arg Update_Sale_Conversions_StatusParams) error {
+	_, err := q.db.ExecContext(ctx, update_Sale_Conversions_Status, arg.SaleID, arg.Status)
+	return err
+}
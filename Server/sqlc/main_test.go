@@ -0,0 +1,37 @@
+package sqlc
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// testStore runs against the migrated database at TEST_DB_URL. Tests that
+// need it are skipped when it is unset.
+var testStore *Store
+
+func TestMain(m *testing.M) {
+	if url := os.Getenv("TEST_DB_URL"); url != "" {
+		conn, err := sql.Open("postgres", url)
+		if err != nil {
+			log.Fatalf("could not open test database: %v", err)
+		}
+		if err := conn.Ping(); err != nil {
+			log.Fatalf("could not reach test database: %v", err)
+		}
+		testStore = NewStore(conn)
+	}
+
+	os.Exit(m.Run())
+}
+
+func requireStore(t *testing.T) *Store {
+	t.Helper()
+	if testStore == nil {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	return testStore
+}
@@ -17,13 +17,13 @@ WITH campaign_metrics AS (
         c.name AS campaign,
         COUNT(DISTINCT cl.id) AS total_clicks,
         COUNT(DISTINCT conv.id) AS total_conversions,
-        COALESCE(SUM(s.amount), 0) AS total_sales,
+        COALESCE(SUM(s.amount - s.refunded_amount), 0) AS total_sales,
         COUNT(DISTINCT tl.id) AS total_tracking_links
     FROM campaigns c
     LEFT JOIN tracking_links tl ON c.id = tl.campaign_id
-    LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id
-    LEFT JOIN conversions conv ON cl.click_id = conv.click_id
-    LEFT JOIN sales s ON c.brand_id = s.brand_id
+    LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id AND ($2::boolean OR NOT cl.is_bot)
+    LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($3::boolean AND conv.status = 'pending'))
+    LEFT JOIN sales s ON c.brand_id = s.brand_id AND (s.status = 'approved' OR ($3::boolean AND s.status = 'pending'))
     WHERE c.brand_id = $1
     GROUP BY c.id, c.name
 ),
@@ -52,8 +52,14 @@ type GetCampaign_Specific_EffectivenessRow struct {
 	Engagement     string
 }
 
-func (q *Queries) GetCampaign_Specific_Effectiveness(ctx context.Context, brandID sql.NullInt64) ([]GetCampaign_Specific_EffectivenessRow, error) {
-	rows, err := q.db.QueryContext(ctx, getCampaign_Specific_Effectiveness, brandID)
+type GetCampaign_Specific_EffectivenessParams struct {
+	BrandID        sql.NullInt64
+	IncludeBots    bool
+	IncludePending bool
+}
+
+func (q *Queries) GetCampaign_Specific_Effectiveness(ctx context.Context, arg GetCampaign_Specific_EffectivenessParams) ([]GetCampaign_Specific_EffectivenessRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCampaign_Specific_Effectiveness, arg.BrandID, arg.IncludeBots, arg.IncludePending)
 	if err != nil {
 		return nil, err
 	}
@@ -133,15 +139,16 @@ active_influencers_data AS (
     FROM affiliates a
     JOIN tracking_links tl ON a.id = tl.affiliate_id
     JOIN campaigns c ON tl.campaign_id = c.id
-    JOIN clicks cl ON tl.id = cl.tracking_link_id
+    JOIN clicks cl ON tl.id = cl.tracking_link_id AND ($2::boolean OR NOT cl.is_bot)
     WHERE c.brand_id = $1
     AND cl.timestamp >= NOW() - INTERVAL '30 days'
 ),
 click_data AS (
     SELECT 
-        COUNT(*) AS total_reach
+        COUNT(*) AS total_reach,
+        COALESCE(SUM(cl.raw_clicks), 0)::bigint AS total_raw_clicks
     FROM clicks cl
-    JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+    JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
     JOIN campaigns c ON tl.campaign_id = c.id
     WHERE c.brand_id = $1
 ),
@@ -151,15 +158,16 @@ conversion_data AS (
         COALESCE((COUNT(DISTINCT conv.id) * 100.0 / NULLIF(COUNT(cl.id), 0)), 0)::numeric(10, 1) AS conversion_rate,
         COALESCE((SUM(conv.amount * conv.weight) / NULLIF(COUNT(DISTINCT conv.id), 0)), 0)::numeric(10, 2) AS average_order_value
     FROM clicks cl
-    JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+    JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
     JOIN campaigns c ON tl.campaign_id = c.id
-    LEFT JOIN conversions conv ON cl.click_id = conv.click_id
+    LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($3::boolean AND conv.status = 'pending'))
     WHERE c.brand_id = $1
 )
 SELECT 
     campaign_data.total_campaigns,
     active_influencers_data.active_influencers,
     click_data.total_reach,
+    click_data.total_raw_clicks,
     conversion_data.total_revenue,
     conversion_data.conversion_rate,
     conversion_data.average_order_value
@@ -170,18 +178,26 @@ type Get_Brand_Key_MetricsRow struct {
 	TotalCampaigns    int64
 	ActiveInfluencers int64
 	TotalReach        int64
+	TotalRawClicks    int64
 	TotalRevenue      string
 	ConversionRate    string
 	AverageOrderValue string
 }
 
-func (q *Queries) Get_Brand_Key_Metrics(ctx context.Context, brandID sql.NullInt64) (Get_Brand_Key_MetricsRow, error) {
-	row := q.db.QueryRowContext(ctx, get_Brand_Key_Metrics, brandID)
+type Get_Brand_Key_MetricsParams struct {
+	BrandID        sql.NullInt64
+	IncludeBots    bool
+	IncludePending bool
+}
+
+func (q *Queries) Get_Brand_Key_Metrics(ctx context.Context, arg Get_Brand_Key_MetricsParams) (Get_Brand_Key_MetricsRow, error) {
+	row := q.db.QueryRowContext(ctx, get_Brand_Key_Metrics, arg.BrandID, arg.IncludeBots, arg.IncludePending)
 	var i Get_Brand_Key_MetricsRow
 	err := row.Scan(
 		&i.TotalCampaigns,
 		&i.ActiveInfluencers,
 		&i.TotalReach,
+		&i.TotalRawClicks,
 		&i.TotalRevenue,
 		&i.ConversionRate,
 		&i.AverageOrderValue,
@@ -189,19 +205,64 @@ func (q *Queries) Get_Brand_Key_Metrics(ctx context.Context, brandID sql.NullInt
 	return i, err
 }
 
+const get_Browser_Counts = `-- name: Get_Browser_Counts :many
+SELECT 
+    COALESCE(browser, 'Unknown') AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = $1
+GROUP BY browser
+ORDER BY value DESC
+`
+
+type Get_Browser_CountsRow struct {
+	Name  string
+	Value int64
+}
+
+type Get_Browser_CountsParams struct {
+	BrandID     sql.NullInt64
+	IncludeBots bool
+}
+
+func (q *Queries) Get_Browser_Counts(ctx context.Context, arg Get_Browser_CountsParams) ([]Get_Browser_CountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Browser_Counts, arg.BrandID, arg.IncludeBots)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_Browser_CountsRow
+	for rows.Next() {
+		var i Get_Browser_CountsRow
+		if err := rows.Scan(&i.Name, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const get_CampaignEffectiveness = `-- name: Get_CampaignEffectiveness :many
 WITH campaign_metrics AS (
     SELECT 
         c.name AS campaign,
         COUNT(DISTINCT cl.id) AS total_clicks,
         COUNT(DISTINCT conv.id) AS total_conversions,
-        COALESCE(SUM(s.amount), 0) AS total_sales,
+        COALESCE(SUM(s.amount - s.refunded_amount), 0) AS total_sales,
         COUNT(DISTINCT tl.id) AS total_tracking_links
     FROM campaigns c
     LEFT JOIN tracking_links tl ON c.id = tl.campaign_id
-    LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id
-    LEFT JOIN conversions conv ON cl.click_id = conv.click_id
-    LEFT JOIN sales s ON c.brand_id = s.brand_id -- Removed date filtering
+    LEFT JOIN clicks cl ON tl.id = cl.tracking_link_id AND ($2::boolean OR NOT cl.is_bot)
+    LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($3::boolean AND conv.status = 'pending'))
+    LEFT JOIN sales s ON c.brand_id = s.brand_id AND (s.status = 'approved' OR ($3::boolean AND s.status = 'pending')) -- Removed date filtering
     WHERE c.brand_id = $1
     GROUP BY c.id, c.name
 )
@@ -222,8 +283,14 @@ type Get_CampaignEffectivenessRow struct {
 	Engagement     string
 }
 
-func (q *Queries) Get_CampaignEffectiveness(ctx context.Context, brandID sql.NullInt64) ([]Get_CampaignEffectivenessRow, error) {
-	rows, err := q.db.QueryContext(ctx, get_CampaignEffectiveness, brandID)
+type Get_CampaignEffectivenessParams struct {
+	BrandID        sql.NullInt64
+	IncludeBots    bool
+	IncludePending bool
+}
+
+func (q *Queries) Get_CampaignEffectiveness(ctx context.Context, arg Get_CampaignEffectivenessParams) ([]Get_CampaignEffectivenessRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_CampaignEffectiveness, arg.BrandID, arg.IncludeBots, arg.IncludePending)
 	if err != nil {
 		return nil, err
 	}
@@ -253,13 +320,13 @@ func (q *Queries) Get_CampaignEffectiveness(ctx context.Context, brandID sql.Nul
 const get_Campaign_Performance = `-- name: Get_Campaign_Performance :many
 SELECT 
     TO_CHAR(cl.timestamp, 'Mon') AS month,
-    COUNT(cl.id) AS impressions, -- Total clicks as a proxy for impressions
-    COUNT(cl.id) AS clicks,     -- Total clicks
+    COALESCE(SUM(cl.raw_clicks), 0)::bigint AS impressions, -- Every tap, repeats included
+    COUNT(cl.id) AS clicks,     -- Unique clicks
     COUNT(DISTINCT conv.id) AS conversions
 FROM clicks cl
-JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
 JOIN campaigns c ON tl.campaign_id = c.id
-LEFT JOIN conversions conv ON cl.click_id = conv.click_id
+LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($3::boolean AND conv.status = 'pending'))
 WHERE c.brand_id = $1
 GROUP BY TO_CHAR(cl.timestamp, 'Mon')
 ORDER BY MIN(cl.timestamp)
@@ -272,8 +339,14 @@ type Get_Campaign_PerformanceRow struct {
 	Conversions int64
 }
 
-func (q *Queries) Get_Campaign_Performance(ctx context.Context, brandID sql.NullInt64) ([]Get_Campaign_PerformanceRow, error) {
-	rows, err := q.db.QueryContext(ctx, get_Campaign_Performance, brandID)
+type Get_Campaign_PerformanceParams struct {
+	BrandID        sql.NullInt64
+	IncludeBots    bool
+	IncludePending bool
+}
+
+func (q *Queries) Get_Campaign_Performance(ctx context.Context, arg Get_Campaign_PerformanceParams) ([]Get_Campaign_PerformanceRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Campaign_Performance, arg.BrandID, arg.IncludeBots, arg.IncludePending)
 	if err != nil {
 		return nil, err
 	}
@@ -300,6 +373,170 @@ func (q *Queries) Get_Campaign_Performance(ctx context.Context, brandID sql.Null
 	return items, nil
 }
 
+const get_DeviceType_Counts = `-- name: Get_DeviceType_Counts :many
+SELECT 
+    INITCAP(COALESCE(device_type, 'unknown')) AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = $1
+GROUP BY device_type
+ORDER BY value DESC
+`
+
+type Get_DeviceType_CountsRow struct {
+	Name  string
+	Value int64
+}
+
+type Get_DeviceType_CountsParams struct {
+	BrandID     sql.NullInt64
+	IncludeBots bool
+}
+
+func (q *Queries) Get_DeviceType_Counts(ctx context.Context, arg Get_DeviceType_CountsParams) ([]Get_DeviceType_CountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_DeviceType_Counts, arg.BrandID, arg.IncludeBots)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_DeviceType_CountsRow
+	for rows.Next() {
+		var i Get_DeviceType_CountsRow
+		if err := rows.Scan(&i.Name, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const get_Geo_Country_Breakdown = `-- name: Get_Geo_Country_Breakdown :many
+SELECT 
+    COALESCE(cl.country, '') AS country,
+    COUNT(DISTINCT cl.id) AS clicks,
+    COUNT(DISTINCT conv.id) AS conversions,
+    COALESCE(SUM(conv.amount * conv.weight), 0)::numeric(10, 2) AS revenue
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($1::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($4::boolean AND conv.status = 'pending'))
+WHERE ($2::bigint IS NULL OR c.brand_id = $2)
+AND ($3::bigint IS NULL OR c.id = $3)
+GROUP BY 1
+ORDER BY clicks DESC
+`
+
+type Get_Geo_Country_BreakdownRow struct {
+	Country     string
+	Clicks      int64
+	Conversions int64
+	Revenue     string
+}
+
+type Get_Geo_Country_BreakdownParams struct {
+	IncludeBots    bool
+	BrandID        sql.NullInt64
+	CampaignID     sql.NullInt64
+	IncludePending bool
+}
+
+func (q *Queries) Get_Geo_Country_Breakdown(ctx context.Context, arg Get_Geo_Country_BreakdownParams) ([]Get_Geo_Country_BreakdownRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Geo_Country_Breakdown, arg.IncludeBots, arg.BrandID, arg.CampaignID, arg.IncludePending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_Geo_Country_BreakdownRow
+	for rows.Next() {
+		var i Get_Geo_Country_BreakdownRow
+		if err := rows.Scan(
+			&i.Country,
+			&i.Clicks,
+			&i.Conversions,
+			&i.Revenue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const get_Geo_Region_Breakdown = `-- name: Get_Geo_Region_Breakdown :many
+SELECT 
+    COALESCE(cl.country, '') AS country,
+    COALESCE(cl.region, '') AS region,
+    COUNT(DISTINCT cl.id) AS clicks,
+    COUNT(DISTINCT conv.id) AS conversions,
+    COALESCE(SUM(conv.amount * conv.weight), 0)::numeric(10, 2) AS revenue
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($1::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($4::boolean AND conv.status = 'pending'))
+WHERE ($2::bigint IS NULL OR c.brand_id = $2)
+AND ($3::bigint IS NULL OR c.id = $3)
+GROUP BY 1, 2
+ORDER BY clicks DESC
+`
+
+type Get_Geo_Region_BreakdownRow struct {
+	Country     string
+	Region      string
+	Clicks      int64
+	Conversions int64
+	Revenue     string
+}
+
+type Get_Geo_Region_BreakdownParams struct {
+	IncludeBots    bool
+	BrandID        sql.NullInt64
+	CampaignID     sql.NullInt64
+	IncludePending bool
+}
+
+func (q *Queries) Get_Geo_Region_Breakdown(ctx context.Context, arg Get_Geo_Region_BreakdownParams) ([]Get_Geo_Region_BreakdownRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Geo_Region_Breakdown, arg.IncludeBots, arg.BrandID, arg.CampaignID, arg.IncludePending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_Geo_Region_BreakdownRow
+	for rows.Next() {
+		var i Get_Geo_Region_BreakdownRow
+		if err := rows.Scan(
+			&i.Country,
+			&i.Region,
+			&i.Clicks,
+			&i.Conversions,
+			&i.Revenue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const get_MetricsOverTime = `-- name: Get_MetricsOverTime :many
 WITH monthly_metrics AS (
     SELECT 
@@ -310,8 +547,8 @@ WITH monthly_metrics AS (
         COALESCE(SUM(conv.amount), 0) AS total_cost 
     FROM campaigns c
     JOIN tracking_links tl ON c.id = tl.campaign_id
-    JOIN clicks cl ON tl.id = cl.tracking_link_id
-    LEFT JOIN conversions conv ON cl.click_id = conv.click_id
+    JOIN clicks cl ON tl.id = cl.tracking_link_id AND ($2::boolean OR NOT cl.is_bot)
+    LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($3::boolean AND conv.status = 'pending'))
     WHERE c.brand_id = $1
     GROUP BY TO_CHAR(cl.timestamp, 'YYYY-MM')
 )
@@ -332,8 +569,14 @@ type Get_MetricsOverTimeRow struct {
 	ConversionRate string
 }
 
-func (q *Queries) Get_MetricsOverTime(ctx context.Context, brandID sql.NullInt64) ([]Get_MetricsOverTimeRow, error) {
-	rows, err := q.db.QueryContext(ctx, get_MetricsOverTime, brandID)
+type Get_MetricsOverTimeParams struct {
+	BrandID        sql.NullInt64
+	IncludeBots    bool
+	IncludePending bool
+}
+
+func (q *Queries) Get_MetricsOverTime(ctx context.Context, arg Get_MetricsOverTimeParams) ([]Get_MetricsOverTimeRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_MetricsOverTime, arg.BrandID, arg.IncludeBots, arg.IncludePending)
 	if err != nil {
 		return nil, err
 	}
@@ -360,13 +603,151 @@ func (q *Queries) Get_MetricsOverTime(ctx context.Context, brandID sql.NullInt64
 	return items, nil
 }
 
+const get_OS_Counts = `-- name: Get_OS_Counts :many
+SELECT 
+    COALESCE(os, 'Unknown') AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = $1
+GROUP BY os
+ORDER BY value DESC
+`
+
+type Get_OS_CountsRow struct {
+	Name  string
+	Value int64
+}
+
+type Get_OS_CountsParams struct {
+	BrandID     sql.NullInt64
+	IncludeBots bool
+}
+
+func (q *Queries) Get_OS_Counts(ctx context.Context, arg Get_OS_CountsParams) ([]Get_OS_CountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_OS_Counts, arg.BrandID, arg.IncludeBots)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_OS_CountsRow
+	for rows.Next() {
+		var i Get_OS_CountsRow
+		if err := rows.Scan(&i.Name, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const get_Referrer_Host_Counts = `-- name: Get_Referrer_Host_Counts :many
+SELECT 
+    COALESCE(cl.referrer_host, '') AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = $1
+AND cl.referrer_host IS NOT NULL
+GROUP BY cl.referrer_host
+ORDER BY value DESC
+LIMIT 20
+`
+
+type Get_Referrer_Host_CountsRow struct {
+	Name  string
+	Value int64
+}
+
+type Get_Referrer_Host_CountsParams struct {
+	BrandID     sql.NullInt64
+	IncludeBots bool
+}
+
+func (q *Queries) Get_Referrer_Host_Counts(ctx context.Context, arg Get_Referrer_Host_CountsParams) ([]Get_Referrer_Host_CountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Referrer_Host_Counts, arg.BrandID, arg.IncludeBots)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_Referrer_Host_CountsRow
+	for rows.Next() {
+		var i Get_Referrer_Host_CountsRow
+		if err := rows.Scan(&i.Name, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const get_Referrer_Platform_Counts = `-- name: Get_Referrer_Platform_Counts :many
+SELECT 
+    COALESCE(cl.referrer_platform, 'unknown') AS name,
+    COUNT(*) AS value
+FROM clicks cl
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
+JOIN campaigns c ON tl.campaign_id = c.id
+WHERE c.brand_id = $1
+GROUP BY cl.referrer_platform
+ORDER BY value DESC
+`
+
+type Get_Referrer_Platform_CountsRow struct {
+	Name  string
+	Value int64
+}
+
+type Get_Referrer_Platform_CountsParams struct {
+	BrandID     sql.NullInt64
+	IncludeBots bool
+}
+
+func (q *Queries) Get_Referrer_Platform_Counts(ctx context.Context, arg Get_Referrer_Platform_CountsParams) ([]Get_Referrer_Platform_CountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Referrer_Platform_Counts, arg.BrandID, arg.IncludeBots)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_Referrer_Platform_CountsRow
+	for rows.Next() {
+		var i Get_Referrer_Platform_CountsRow
+		if err := rows.Scan(&i.Name, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const get_Revenue_Data = `-- name: Get_Revenue_Data :many
 SELECT 
     TO_CHAR(s.timestamp, 'Mon') AS month,
-    COALESCE(SUM(s.amount), 0)::numeric(10, 2) AS raw_revenue,
+    COALESCE(SUM(s.amount - s.refunded_amount), 0)::numeric(10, 2) AS raw_revenue,
     STRING_AGG(DISTINCT s.currency, ', ') AS currencies -- Track currencies for logging
 FROM sales s
 WHERE s.brand_id = $1
+AND (s.status = 'approved' OR ($2::boolean AND s.status = 'pending'))
 GROUP BY TO_CHAR(s.timestamp, 'Mon')
 ORDER BY MIN(s.timestamp)
 `
@@ -377,8 +758,13 @@ type Get_Revenue_DataRow struct {
 	Currencies []byte
 }
 
-func (q *Queries) Get_Revenue_Data(ctx context.Context, brandID int64) ([]Get_Revenue_DataRow, error) {
-	rows, err := q.db.QueryContext(ctx, get_Revenue_Data, brandID)
+type Get_Revenue_DataParams struct {
+	BrandID        int64
+	IncludePending bool
+}
+
+func (q *Queries) Get_Revenue_Data(ctx context.Context, arg Get_Revenue_DataParams) ([]Get_Revenue_DataRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_Revenue_Data, arg.BrandID, arg.IncludePending)
 	if err != nil {
 		return nil, err
 	}
@@ -400,12 +786,83 @@ func (q *Queries) Get_Revenue_Data(ctx context.Context, brandID int64) ([]Get_Re
 	return items, nil
 }
 
+const get_SubLink_Breakdown = `-- name: Get_SubLink_Breakdown :many
+SELECT 
+    tl.affiliate_id,
+    COALESCE(tl.label, 'default') AS label,
+    COALESCE(
+        CASE $1::text
+            WHEN 'sub1' THEN cl.sub1
+            WHEN 'sub2' THEN cl.sub2
+            WHEN 'sub3' THEN cl.sub3
+            WHEN 'sub4' THEN cl.sub4
+            WHEN 'sub5' THEN cl.sub5
+        END,
+        ''
+    )::text AS sub_id,
+    COUNT(DISTINCT cl.id) AS clicks,
+    COUNT(DISTINCT conv.id) AS conversions,
+    COALESCE(SUM(conv.amount * conv.weight), 0)::numeric(10, 2) AS revenue
+FROM tracking_links tl
+JOIN clicks cl ON tl.id = cl.tracking_link_id AND ($3::boolean OR NOT cl.is_bot)
+LEFT JOIN conversions conv ON cl.click_id = conv.click_id AND (conv.status = 'approved' OR ($4::boolean AND conv.status = 'pending'))
+WHERE tl.campaign_id = $2
+GROUP BY tl.affiliate_id, 2, 3
+ORDER BY clicks DESC
+`
+
+type Get_SubLink_BreakdownParams struct {
+	SubKey         string
+	CampaignID     sql.NullInt64
+	IncludeBots    bool
+	IncludePending bool
+}
+
+type Get_SubLink_BreakdownRow struct {
+	AffiliateID sql.NullInt64
+	Label       string
+	SubID       string
+	Clicks      int64
+	Conversions int64
+	Revenue     string
+}
+
+func (q *Queries) Get_SubLink_Breakdown(ctx context.Context, arg Get_SubLink_BreakdownParams) ([]Get_SubLink_BreakdownRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_SubLink_Breakdown, arg.SubKey, arg.CampaignID, arg.IncludeBots, arg.IncludePending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Get_SubLink_BreakdownRow
+	for rows.Next() {
+		var i Get_SubLink_BreakdownRow
+		if err := rows.Scan(
+			&i.AffiliateID,
+			&i.Label,
+			&i.SubID,
+			&i.Clicks,
+			&i.Conversions,
+			&i.Revenue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const get_UTMMedium_Counts = `-- name: Get_UTMMedium_Counts :many
 SELECT 
     COALESCE(utm_medium, 'Unknown') AS name,
     COUNT(*) AS value
 FROM clicks cl
-JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
 JOIN campaigns c ON tl.campaign_id = c.id
 WHERE c.brand_id = $1
 GROUP BY utm_medium
@@ -417,8 +874,13 @@ type Get_UTMMedium_CountsRow struct {
 	Value int64
 }
 
-func (q *Queries) Get_UTMMedium_Counts(ctx context.Context, brandID sql.NullInt64) ([]Get_UTMMedium_CountsRow, error) {
-	rows, err := q.db.QueryContext(ctx, get_UTMMedium_Counts, brandID)
+type Get_UTMMedium_CountsParams struct {
+	BrandID     sql.NullInt64
+	IncludeBots bool
+}
+
+func (q *Queries) Get_UTMMedium_Counts(ctx context.Context, arg Get_UTMMedium_CountsParams) ([]Get_UTMMedium_CountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_UTMMedium_Counts, arg.BrandID, arg.IncludeBots)
 	if err != nil {
 		return nil, err
 	}
@@ -445,7 +907,7 @@ SELECT
     COALESCE(utm_source, 'Unknown') AS name,
     COUNT(*) AS value
 FROM clicks cl
-JOIN tracking_links tl ON cl.tracking_link_id = tl.id
+JOIN tracking_links tl ON cl.tracking_link_id = tl.id AND ($2::boolean OR NOT cl.is_bot)
 JOIN campaigns c ON tl.campaign_id = c.id
 WHERE c.brand_id = $1
 GROUP BY utm_source
@@ -457,8 +919,13 @@ type Get_UTMSource_CountsRow struct {
 	Value int64
 }
 
-func (q *Queries) Get_UTMSource_Counts(ctx context.Context, brandID sql.NullInt64) ([]Get_UTMSource_CountsRow, error) {
-	rows, err := q.db.QueryContext(ctx, get_UTMSource_Counts, brandID)
+type Get_UTMSource_CountsParams struct {
+	BrandID     sql.NullInt64
+	IncludeBots bool
+}
+
+func (q *Queries) Get_UTMSource_Counts(ctx context.Context, arg Get_UTMSource_CountsParams) ([]Get_UTMSource_CountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, get_UTMSource_Counts, arg.BrandID, arg.IncludeBots)
 	if err != nil {
 		return nil, err
 	}
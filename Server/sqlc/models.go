@@ -24,43 +24,158 @@ type AffiliateCampaign struct {
 	CreatedAt   sql.NullTime
 }
 
+type AttributionJob struct {
+	ID                       int64
+	BrandID                  int64
+	AttributionModel         string
+	AttributionHalfLifeHours sql.NullInt32
+	FromTime                 time.Time
+	ToTime                   time.Time
+	Status                   string
+	ConversionsUpdated       int32
+	Error                    sql.NullString
+	CreatedAt                time.Time
+	FinishedAt               sql.NullTime
+	SalesSkipped             int32
+	HeartbeatAt              time.Time
+}
+
 type Brand struct {
-	ID          int64
-	UserID      sql.NullInt64
-	CompanyName string
-	Website     sql.NullString
-	CreatedAt   sql.NullTime
+	ID                       int64
+	UserID                   sql.NullInt64
+	CompanyName              string
+	Website                  sql.NullString
+	CreatedAt                sql.NullTime
+	AttributionModel         string
+	AttributionHalfLifeHours sql.NullInt32
+}
+
+type BrandDomain struct {
+	ID                int64
+	BrandID           int64
+	Domain            string
+	CreatedAt         sql.NullTime
+	VerificationToken string
+	VerifiedAt        sql.NullTime
+}
+
+type BrandLogo struct {
+	BrandID     int64
+	ContentType string
+	Data        []byte
+	UpdatedAt   sql.NullTime
+}
+
+type BrandPostbackKey struct {
+	BrandID                int64
+	KeyHash                string
+	SigningSecret          string
+	CreatedAt              time.Time
+	PixelSignatureRequired bool
 }
 
 type Campaign struct {
-	ID             int64
-	BrandID        sql.NullInt64
-	Name           string
-	Description    sql.NullString
-	CommissionRate string
-	LandingUrl     string
-	CreatedAt      sql.NullTime
+	ID                       int64
+	BrandID                  sql.NullInt64
+	Name                     string
+	Description              sql.NullString
+	CommissionRate           string
+	LandingUrl               string
+	CreatedAt                sql.NullTime
+	FallbackUrl              sql.NullString
+	PassthroughParams        []string
+	RedirectTemplate         sql.NullString
+	AttributionModel         sql.NullString
+	AttributionHalfLifeHours sql.NullInt32
+	LookbackDays             int32
+	ApprovalDelayDays        int32
+}
+
+type CampaignEventType struct {
+	ID           int64
+	CampaignID   int64
+	Name         string
+	Payout       string
+	Currency     string
+	DedupPerUser bool
+	CreatedAt    time.Time
 }
 
 type Click struct {
-	ID             int64
-	TrackingLinkID sql.NullInt64
-	ClickID        uuid.UUID
-	UserIp         string
-	UserAgent      sql.NullString
-	Referrer       sql.NullString
-	Timestamp      sql.NullTime
-	UtmSource      sql.NullString
-	UtmMedium      sql.NullString
+	ID               int64
+	TrackingLinkID   sql.NullInt64
+	ClickID          uuid.UUID
+	UserIp           sql.NullString
+	UserAgent        sql.NullString
+	Referrer         sql.NullString
+	Timestamp        sql.NullTime
+	UtmSource        sql.NullString
+	UtmMedium        sql.NullString
+	UtmCampaign      sql.NullString
+	Sub1             sql.NullString
+	Sub2             sql.NullString
+	Sub3             sql.NullString
+	Sub4             sql.NullString
+	Sub5             sql.NullString
+	IsBot            bool
+	BotReason        sql.NullString
+	Fingerprint      sql.NullString
+	RawClicks        int32
+	LastClickedAt    sql.NullTime
+	DeviceType       sql.NullString
+	Os               sql.NullString
+	Browser          sql.NullString
+	BrowserVersion   sql.NullString
+	Country          sql.NullString
+	Region           sql.NullString
+	City             sql.NullString
+	PiiScrubbedAt    sql.NullTime
+	ReferrerHost     sql.NullString
+	ReferrerPlatform sql.NullString
 }
 
 type Conversion struct {
-	ID        int64
-	Amount    float64
-	Timestamp sql.NullTime
-	Currency  sql.NullString
-	ClickID   uuid.NullUUID
-	Weight    sql.NullFloat64
+	ID               int64
+	Amount           float64
+	Timestamp        sql.NullTime
+	Currency         sql.NullString
+	ClickID          uuid.NullUUID
+	Weight           sql.NullFloat64
+	SaleID           sql.NullInt64
+	AttributionModel sql.NullString
+	Status           string
+}
+
+type Event struct {
+	ID              int64
+	BrandID         int64
+	EventTypeID     int64
+	ClickID         uuid.UUID
+	UserRef         sql.NullString
+	DedupKey        sql.NullString
+	OrderID         sql.NullString
+	RequestHash     sql.NullString
+	Payout          string
+	Currency        string
+	CreatedAt       time.Time
+	Status          string
+	StatusReason    sql.NullString
+	StatusChangedAt sql.NullTime
+	ApproveAt       sql.NullTime
+}
+
+type FraudFlag struct {
+	ID           int64
+	BrandID      int64
+	EventType    string
+	ClickID      uuid.UUID
+	ConversionID sql.NullInt64
+	Score        int32
+	Reasons      []string
+	Status       string
+	ReviewedAt   sql.NullTime
+	CreatedAt    time.Time
+	EventID      sql.NullInt64
 }
 
 type Invite struct {
@@ -73,12 +188,20 @@ type Invite struct {
 }
 
 type Sale struct {
-	ID        int64
-	BrandID   int64
-	Amount    string
-	Currency  string
-	Timestamp sql.NullTime
-	CreatedAt sql.NullTime
+	ID              int64
+	BrandID         int64
+	Amount          string
+	Currency        string
+	Timestamp       sql.NullTime
+	CreatedAt       sql.NullTime
+	OrderID         sql.NullString
+	RequestHash     sql.NullString
+	EventName       sql.NullString
+	Status          string
+	StatusReason    sql.NullString
+	StatusChangedAt sql.NullTime
+	ApproveAt       sql.NullTime
+	RefundedAmount  string
 }
 
 type Session struct {
@@ -93,11 +216,20 @@ type Session struct {
 }
 
 type TrackingLink struct {
-	ID          int64
-	AffiliateID sql.NullInt64
-	CampaignID  sql.NullInt64
-	LinkCode    string
-	CreatedAt   sql.NullTime
+	ID                 int64
+	AffiliateID        sql.NullInt64
+	CampaignID         sql.NullInt64
+	LinkCode           string
+	CreatedAt          sql.NullTime
+	Label              sql.NullString
+	UtmSource          sql.NullString
+	UtmMedium          sql.NullString
+	UtmCampaign        sql.NullString
+	ExpiresAt          sql.NullTime
+	IsActive           bool
+	MaxClicks          sql.NullInt64
+	ClickCount         int64
+	DedupWindowSeconds sql.NullInt32
 }
 
 type User struct {
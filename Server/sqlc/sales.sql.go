@@ -10,26 +10,260 @@ import (
 	"database/sql"
 )
 
+const approve_Due_Sales = `-- name: Approve_Due_Sales :one
+WITH due AS (
+    UPDATE sales
+    SET 
+        status = 'approved',
+        status_changed_at = CURRENT_TIMESTAMP
+    WHERE status = 'pending' AND approve_at <= CURRENT_TIMESTAMP
+    RETURNING id
+), approved AS (
+    UPDATE conversions
+    SET status = 'approved'
+    WHERE sale_id IN (SELECT id FROM due) AND status = 'pending'
+)
+SELECT COUNT(*) FROM due
+`
+
+// Approves every pending sale whose approve_at has passed, and its conversions
+func (q *Queries) Approve_Due_Sales(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, approve_Due_Sales)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const create_Sale = `-- name: Create_Sale :one
-INSERT INTO sales (brand_id, amount, currency, timestamp)
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (id) DO NOTHING
-RETURNING id, brand_id, amount, currency, timestamp, created_at
+INSERT INTO sales (brand_id, amount, currency, timestamp, order_id, request_hash, event_name, status, approve_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (brand_id, order_id) DO NOTHING
+RETURNING id, brand_id, amount, currency, timestamp, created_at, order_id, request_hash, event_name, status, status_reason, status_changed_at, approve_at, refunded_amount
 `
 
 type Create_SaleParams struct {
-	BrandID   int64
-	Amount    string
-	Currency  string
-	Timestamp sql.NullTime
+	BrandID     int64
+	Amount      string
+	Currency    string
+	Timestamp   sql.NullTime
+	OrderID     sql.NullString
+	RequestHash sql.NullString
+	EventName   sql.NullString
+	Status      string
+	ApproveAt   sql.NullTime
 }
 
+// Returns no row when the brand already has a sale for order_id
 func (q *Queries) Create_Sale(ctx context.Context, arg Create_SaleParams) (Sale, error) {
 	row := q.db.QueryRowContext(ctx, create_Sale,
 		arg.BrandID,
 		arg.Amount,
 		arg.Currency,
 		arg.Timestamp,
+		arg.OrderID,
+		arg.RequestHash,
+		arg.EventName,
+		arg.Status,
+		arg.ApproveAt,
+	)
+	var i Sale
+	err := row.Scan(
+		&i.ID,
+		&i.BrandID,
+		&i.Amount,
+		&i.Currency,
+		&i.Timestamp,
+		&i.CreatedAt,
+		&i.OrderID,
+		&i.RequestHash,
+		&i.EventName,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.ApproveAt,
+		&i.RefundedAmount,
+	)
+	return i, err
+}
+
+const get_Sale = `-- name: Get_Sale :one
+SELECT id, brand_id, amount, currency, timestamp, created_at, order_id, request_hash, event_name, status, status_reason, status_changed_at, approve_at, refunded_amount
+FROM sales
+WHERE id = $1
+`
+
+func (q *Queries) Get_Sale(ctx context.Context, id int64) (Sale, error) {
+	row := q.db.QueryRowContext(ctx, get_Sale, id)
+	var i Sale
+	err := row.Scan(
+		&i.ID,
+		&i.BrandID,
+		&i.Amount,
+		&i.Currency,
+		&i.Timestamp,
+		&i.CreatedAt,
+		&i.OrderID,
+		&i.RequestHash,
+		&i.EventName,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.ApproveAt,
+		&i.RefundedAmount,
+	)
+	return i, err
+}
+
+const get_Sale_By_Order = `-- name: Get_Sale_By_Order :one
+SELECT id, brand_id, amount, currency, timestamp, created_at, order_id, request_hash, event_name, status, status_reason, status_changed_at, approve_at, refunded_amount
+FROM sales
+WHERE brand_id = $1 AND order_id = $2
+`
+
+type Get_Sale_By_OrderParams struct {
+	BrandID int64
+	OrderID sql.NullString
+}
+
+func (q *Queries) Get_Sale_By_Order(ctx context.Context, arg Get_Sale_By_OrderParams) (Sale, error) {
+	row := q.db.QueryRowContext(ctx, get_Sale_By_Order, arg.BrandID, arg.OrderID)
+	var i Sale
+	err := row.Scan(
+		&i.ID,
+		&i.BrandID,
+		&i.Amount,
+		&i.Currency,
+		&i.Timestamp,
+		&i.CreatedAt,
+		&i.OrderID,
+		&i.RequestHash,
+		&i.EventName,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.ApproveAt,
+		&i.RefundedAmount,
+	)
+	return i, err
+}
+
+const list_Sales_By_Brand = `-- name: List_Sales_By_Brand :many
+SELECT id, brand_id, amount, currency, timestamp, created_at, order_id, request_hash, event_name, status, status_reason, status_changed_at, approve_at, refunded_amount
+FROM sales
+WHERE brand_id = $1
+AND ($2::varchar IS NULL OR status = $2)
+ORDER BY id DESC
+LIMIT 500
+`
+
+type List_Sales_By_BrandParams struct {
+	BrandID int64
+	Status  sql.NullString
+}
+
+func (q *Queries) List_Sales_By_Brand(ctx context.Context, arg List_Sales_By_BrandParams) ([]Sale, error) {
+	rows, err := q.db.QueryContext(ctx, list_Sales_By_Brand, arg.BrandID, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Sale
+	for rows.Next() {
+		var i Sale
+		if err := rows.Scan(
+			&i.ID,
+			&i.BrandID,
+			&i.Amount,
+			&i.Currency,
+			&i.Timestamp,
+			&i.CreatedAt,
+			&i.OrderID,
+			&i.RequestHash,
+			&i.EventName,
+			&i.Status,
+			&i.StatusReason,
+			&i.StatusChangedAt,
+			&i.ApproveAt,
+			&i.RefundedAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const refund_Sale = `-- name: Refund_Sale :one
+UPDATE sales
+SET 
+    refunded_amount = refunded_amount + $1::decimal,
+    status_reason = $2
+WHERE id = $3
+AND status IN ('pending', 'approved')
+AND refunded_amount + $1::decimal <= amount
+RETURNING id, brand_id, amount, currency, timestamp, created_at, order_id, request_hash, event_name, status, status_reason, status_changed_at, approve_at, refunded_amount
+`
+
+type Refund_SaleParams struct {
+	Amount       string
+	StatusReason sql.NullString
+	ID           int64
+}
+
+// Returns no row unless the sale is pending or approved and has at least
+// amount left to refund
+func (q *Queries) Refund_Sale(ctx context.Context, arg Refund_SaleParams) (Sale, error) {
+	row := q.db.QueryRowContext(ctx, refund_Sale, arg.Amount, arg.StatusReason, arg.ID)
+	var i Sale
+	err := row.Scan(
+		&i.ID,
+		&i.BrandID,
+		&i.Amount,
+		&i.Currency,
+		&i.Timestamp,
+		&i.CreatedAt,
+		&i.OrderID,
+		&i.RequestHash,
+		&i.EventName,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.ApproveAt,
+		&i.RefundedAmount,
+	)
+	return i, err
+}
+
+const update_Sale_Status = `-- name: Update_Sale_Status :one
+UPDATE sales
+SET 
+    status = $1,
+    status_reason = $2,
+    status_changed_at = CURRENT_TIMESTAMP
+WHERE id = $3 AND status = $4
+RETURNING id, brand_id, amount, currency, timestamp, created_at, order_id, request_hash, event_name, status, status_reason, status_changed_at, approve_at, refunded_amount
+`
+
+type Update_Sale_StatusParams struct {
+	Status       string
+	StatusReason sql.NullString
+	ID           int64
+	FromStatus   string
+}
+
+// Returns no row unless the sale is currently in from_status
+func (q *Queries) Update_Sale_Status(ctx context.Context, arg Update_Sale_StatusParams) (Sale, error) {
+	row := q.db.QueryRowContext(ctx, update_Sale_Status,
+		arg.Status,
+		arg.StatusReason,
+		arg.ID,
+		arg.FromStatus,
 	)
 	var i Sale
 	err := row.Scan(
@@ -39,6 +273,14 @@ func (q *Queries) Create_Sale(ctx context.Context, arg Create_SaleParams) (Sale,
 		&i.Currency,
 		&i.Timestamp,
 		&i.CreatedAt,
+		&i.OrderID,
+		&i.RequestHash,
+		&i.EventName,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
+		&i.ApproveAt,
+		&i.RefundedAmount,
 	)
 	return i, err
 }
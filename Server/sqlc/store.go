@@ -1,6 +1,18 @@
 package sqlc
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
 
 type Store struct {
 	*Queries
@@ -18,3 +30,398 @@ func NewStore(db *sql.DB) *Store {
 func (store *Store) GetDB() *sql.DB {
 	return store.db
 }
+
+// CreateConversionTxParams is a sale and the weighted conversions it is split
+// into. Each conversion's SaleID and Status are filled in by
+// CreateConversionTx from the sale.
+type CreateConversionTxParams struct {
+	Sale        Create_SaleParams
+	Conversions []Create_ConversionParams
+}
+
+type CreateConversionTxResult struct {
+	Sale        Sale
+	Conversions []Conversion
+}
+
+// CreateConversionTx writes a sale and all of its conversions in one
+// transaction, so a failure part way leaves none of them behind. It returns
+// sql.ErrNoRows when the brand already has a sale for the order.
+func (store *Store) CreateConversionTx(ctx context.Context, arg CreateConversionTxParams) (CreateConversionTxResult, error) {
+	var result CreateConversionTxResult
+
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	qtx := store.WithTx(tx)
+	sale, err := qtx.Create_Sale(ctx, arg.Sale)
+	if err != nil {
+		return result, err
+	}
+
+	conversions := make([]Conversion, 0, len(arg.Conversions))
+	for _, params := range arg.Conversions {
+		params.SaleID = sql.NullInt64{Int64: sale.ID, Valid: true}
+		params.Status = sale.Status
+		conversion, err := qtx.Create_Conversion(ctx, params)
+		if err != nil {
+			return result, err
+		}
+		conversions = append(conversions, conversion)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+
+	result.Sale = sale
+	result.Conversions = conversions
+	return result, nil
+}
+
+// UpdateSaleStatusTx moves a sale and all of its conversions to a new status.
+// It returns sql.ErrNoRows unless the sale is in arg.FromStatus.
+func (store *Store) UpdateSaleStatusTx(ctx context.Context, arg Update_Sale_StatusParams) (Sale, error) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Sale{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := store.WithTx(tx)
+	sale, err := qtx.Update_Sale_Status(ctx, arg)
+	if err != nil {
+		return Sale{}, err
+	}
+	err = qtx.Update_Sale_Conversions_Status(ctx, Update_Sale_Conversions_StatusParams{
+		SaleID: sql.NullInt64{Int64: sale.ID, Valid: true},
+		Status: sale.Status,
+	})
+	if err != nil {
+		return Sale{}, err
+	}
+
+	return sale, tx.Commit()
+}
+
+// RefundSaleTx refunds part of a sale and scales its conversions down to
+// what is left. Refunding all of it reverses the sale. It returns
+// sql.ErrNoRows unless the sale is pending or approved and has arg.Amount
+// left to refund.
+func (store *Store) RefundSaleTx(ctx context.Context, arg Refund_SaleParams) (Sale, error) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Sale{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := store.WithTx(tx)
+	sale, err := qtx.Refund_Sale(ctx, arg)
+	if err != nil {
+		return Sale{}, err
+	}
+	if err := qtx.Scale_Sale_Conversions(ctx, sale.ID); err != nil {
+		return Sale{}, err
+	}
+
+	amount, ok := new(big.Rat).SetString(sale.Amount)
+	refunded, ok2 := new(big.Rat).SetString(sale.RefundedAmount)
+	if !ok || !ok2 {
+		return Sale{}, fmt.Errorf("sale %d has unreadable amounts %q and %q", sale.ID, sale.Amount, sale.RefundedAmount)
+	}
+	if amount.Cmp(refunded) == 0 {
+		sale, err = qtx.Update_Sale_Status(ctx, Update_Sale_StatusParams{
+			Status:       "reversed",
+			StatusReason: arg.StatusReason,
+			ID:           sale.ID,
+			FromStatus:   sale.Status,
+		})
+		if err != nil {
+			return Sale{}, err
+		}
+		err = qtx.Update_Sale_Conversions_Status(ctx, Update_Sale_Conversions_StatusParams{
+			SaleID: sql.NullInt64{Int64: sale.ID, Valid: true},
+			Status: sale.Status,
+		})
+		if err != nil {
+			return Sale{}, err
+		}
+	}
+
+	return sale, tx.Commit()
+}
+
+// linkCodeConstraint is the unique constraint a colliding tracking code trips.
+const linkCodeConstraint = "tracking_links_link_code_key"
+
+// CreateTrackingLinksTx creates links in one transaction, so a failure part
+// way leaves none of them behind. Each link is stored under a code drawn from
+// newCode; an insert whose code is taken is rolled back to a savepoint and
+// tried again with a fresh code, up to maxAttempts times per link.
+func (store *Store) CreateTrackingLinksTx(ctx context.Context, links []Create_TrackingLinkParams, newCode func() (string, error), maxAttempts int) ([]TrackingLink, error) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := store.WithTx(tx)
+	created := make([]TrackingLink, 0, len(links))
+	for _, args := range links {
+		link, err := createTrackingLink(ctx, tx, qtx, args, newCode, maxAttempts)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, link)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func createTrackingLink(ctx context.Context, tx *sql.Tx, qtx *Queries, args Create_TrackingLinkParams, newCode func() (string, error), maxAttempts int) (TrackingLink, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		code, err := newCode()
+		if err != nil {
+			return TrackingLink{}, err
+		}
+		args.LinkCode = code
+
+		// A failed statement aborts the whole transaction unless it is
+		// rolled back to a savepoint
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT tracking_link"); err != nil {
+			return TrackingLink{}, err
+		}
+		link, err := qtx.Create_TrackingLink(ctx, args)
+		if err == nil {
+			return link, nil
+		}
+
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) || pqErr.Code != "23505" || pqErr.Constraint != linkCodeConstraint {
+			return TrackingLink{}, err
+		}
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT tracking_link"); err != nil {
+			return TrackingLink{}, err
+		}
+		log.Printf("Tracking code collision on attempt %d, retrying", attempt+1)
+	}
+	return TrackingLink{}, fmt.Errorf("could not allocate a unique tracking code after %d attempts", maxAttempts)
+}
+
+// Update_Conversion_Attribution_Batch re-weights conversions in one
+// transaction. Callers pass whole sales so none is left half re-weighted.
+func (store *Store) Update_Conversion_Attribution_Batch(ctx context.Context, updates []Update_Conversion_AttributionParams) error {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := store.WithTx(tx)
+	for _, update := range updates {
+		if err := qtx.Update_Conversion_Attribution(ctx, update); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ClickRow is a click recorded at Timestamp, waiting to be written by
+// Create_Clicks_Batch. A Repeat row reuses the ClickID of an earlier click
+// from the same visitor and only bumps that click's raw_clicks. A row with a
+// Flag is written to fraud_flags alongside the click. A Counted row already
+// took its place in the link's click_count when it was redirected.
+type ClickRow struct {
+	Create_ClickParams
+	Timestamp time.Time
+	Repeat    bool
+	Counted   bool
+	Flag      *Create_Fraud_FlagParams
+}
+
+var clickInsertColumns = []string{
+	"tracking_link_id",
+	"click_id",
+	"user_ip",
+	"user_agent",
+	"referrer",
+	"timestamp",
+	"utm_source",
+	"utm_medium",
+	"utm_campaign",
+	"sub1",
+	"sub2",
+	"sub3",
+	"sub4",
+	"sub5",
+	"is_bot",
+	"bot_reason",
+	"fingerprint",
+	"device_type",
+	"os",
+	"browser",
+	"browser_version",
+	"country",
+	"region",
+	"city",
+	"referrer_host",
+	"referrer_platform",
+	"raw_clicks",
+	"last_clicked_at",
+}
+
+// Postgres caps a statement at 65535 bind parameters
+var clickInsertChunk = 65535 / len(clickInsertColumns)
+
+// A repeat can reach the database before the click it repeats, for instance
+// when the two land in different workers' batches, so both sides upsert and
+// the row ends up the same whichever arrives first.
+const clickUpsertConflict = `
+ON CONFLICT (click_id) DO UPDATE SET
+    raw_clicks = clicks.raw_clicks + EXCLUDED.raw_clicks,
+    timestamp = LEAST(clicks.timestamp, EXCLUDED.timestamp),
+    last_clicked_at = GREATEST(clicks.last_clicked_at, EXCLUDED.last_clicked_at)`
+
+// clickUpsert is every row of a batch sharing one click_id, folded together
+// because a single INSERT may not touch the same row twice.
+type clickUpsert struct {
+	ClickRow
+	rawClicks     int32
+	lastClickedAt sql.NullTime
+}
+
+func (row clickUpsert) insertValues() []interface{} {
+	return []interface{}{
+		row.TrackingLinkID,
+		row.ClickID,
+		row.UserIp,
+		row.UserAgent,
+		row.Referrer,
+		row.Timestamp,
+		row.UtmSource,
+		row.UtmMedium,
+		row.UtmCampaign,
+		row.Sub1,
+		row.Sub2,
+		row.Sub3,
+		row.Sub4,
+		row.Sub5,
+		row.IsBot,
+		row.BotReason,
+		row.Fingerprint,
+		row.DeviceType,
+		row.Os,
+		row.Browser,
+		row.BrowserVersion,
+		row.Country,
+		row.Region,
+		row.City,
+		row.ReferrerHost,
+		row.ReferrerPlatform,
+		row.rawClicks,
+		row.lastClickedAt,
+	}
+}
+
+func foldClickRows(rows []ClickRow) []clickUpsert {
+	index := map[uuid.UUID]int{}
+	var folded []clickUpsert
+	for _, row := range rows {
+		i, ok := index[row.ClickID]
+		if !ok {
+			index[row.ClickID] = len(folded)
+			folded = append(folded, clickUpsert{ClickRow: row})
+			i = len(folded) - 1
+		} else if folded[i].Repeat && !row.Repeat {
+			// Keep the original click's details over a repeat's
+			folded[i].ClickRow = row
+		}
+
+		entry := &folded[i]
+		entry.rawClicks++
+		if row.Timestamp.Before(entry.Timestamp) {
+			entry.Timestamp = row.Timestamp
+		}
+		if row.Repeat && (!entry.lastClickedAt.Valid || row.Timestamp.After(entry.lastClickedAt.Time)) {
+			entry.lastClickedAt = sql.NullTime{Time: row.Timestamp, Valid: true}
+		}
+	}
+	return folded
+}
+
+// Create_Clicks_Batch upserts rows into clicks, records their fraud flags and
+// bumps each link's click_count for new human clicks in the same transaction.
+func (store *Store) Create_Clicks_Batch(ctx context.Context, rows []ClickRow) error {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	folded := foldClickRows(rows)
+	for start := 0; start < len(folded); start += clickInsertChunk {
+		end := min(start+clickInsertChunk, len(folded))
+		if err := insertClicks(ctx, tx, folded[start:end]); err != nil {
+			return err
+		}
+	}
+
+	// Bots, previews and repeats never use up a link's click cap
+	counts := map[int64]int64{}
+	for _, row := range rows {
+		if row.TrackingLinkID.Valid && !row.IsBot && !row.Repeat && !row.Counted {
+			counts[row.TrackingLinkID.Int64]++
+		}
+	}
+
+	qtx := store.WithTx(tx)
+	for _, row := range rows {
+		if row.Flag == nil {
+			continue
+		}
+		if err := qtx.Create_Fraud_Flag(ctx, *row.Flag); err != nil {
+			return err
+		}
+	}
+
+	for id, clicks := range counts {
+		if err := qtx.Add_TrackingLink_Clicks(ctx, Add_TrackingLink_ClicksParams{Clicks: clicks, ID: id}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func insertClicks(ctx context.Context, tx *sql.Tx, rows []clickUpsert) error {
+	var query strings.Builder
+	query.WriteString("INSERT INTO clicks (")
+	query.WriteString(strings.Join(clickInsertColumns, ", "))
+	query.WriteString(") VALUES ")
+
+	args := make([]interface{}, 0, len(rows)*len(clickInsertColumns))
+	for i, row := range rows {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for j := range clickInsertColumns {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$%d", len(args)+j+1)
+		}
+		query.WriteString(")")
+		args = append(args, row.insertValues()...)
+	}
+	query.WriteString(clickUpsertConflict)
+
+	_, err := tx.ExecContext(ctx, query.String(), args...)
+	return err
+}
@@ -0,0 +1,240 @@
+package sqlc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func createTestBrand(t *testing.T, store *Store) int64 {
+	t.Helper()
+	brandID, err := store.Create_Brand(context.Background(), Create_BrandParams{CompanyName: "test-" + uuid.NewString()})
+	if err != nil {
+		t.Fatalf("Create_Brand: %v", err)
+	}
+	return brandID
+}
+
+func conversionTxParams(brandID int64, orderID string, weights ...float64) CreateConversionTxParams {
+	arg := CreateConversionTxParams{
+		Sale: Create_SaleParams{
+			BrandID:     brandID,
+			Amount:      "100",
+			Currency:    "USD",
+			OrderID:     sql.NullString{String: orderID, Valid: true},
+			RequestHash: sql.NullString{String: "hash", Valid: true},
+			Status:      "pending",
+		},
+	}
+	for _, weight := range weights {
+		arg.Conversions = append(arg.Conversions, Create_ConversionParams{
+			ClickID:          uuid.NullUUID{UUID: uuid.New(), Valid: true},
+			Amount:           100,
+			Currency:         sql.NullString{String: "USD", Valid: true},
+			Weight:           sql.NullFloat64{Float64: weight, Valid: true},
+			AttributionModel: sql.NullString{String: "linear", Valid: true},
+		})
+	}
+	return arg
+}
+
+func TestCreateConversionTx(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+	brandID := createTestBrand(t, store)
+
+	result, err := store.CreateConversionTx(ctx, conversionTxParams(brandID, uuid.NewString(), 0.25, 0.75))
+	if err != nil {
+		t.Fatalf("CreateConversionTx: %v", err)
+	}
+
+	if len(result.Conversions) != 2 {
+		t.Fatalf("got %d conversions, want 2", len(result.Conversions))
+	}
+	for _, conversion := range result.Conversions {
+		if conversion.SaleID.Int64 != result.Sale.ID {
+			t.Errorf("conversion %d has sale %d, want %d", conversion.ID, conversion.SaleID.Int64, result.Sale.ID)
+		}
+		if conversion.Status != result.Sale.Status {
+			t.Errorf("conversion %d has status %q, want the sale's %q", conversion.ID, conversion.Status, result.Sale.Status)
+		}
+	}
+}
+
+func TestCreateConversionTxConcurrentOrder(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+	brandID := createTestBrand(t, store)
+	orderID := uuid.NewString()
+
+	const submissions = 10
+	results := make([]CreateConversionTxResult, submissions)
+	errs := make([]error, submissions)
+
+	var wg sync.WaitGroup
+	for i := 0; i < submissions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = store.CreateConversionTx(ctx, conversionTxParams(brandID, orderID, 0.5, 0.5))
+		}(i)
+	}
+	wg.Wait()
+
+	var winner CreateConversionTxResult
+	won := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			won++
+			winner = results[i]
+		case !errors.Is(err, sql.ErrNoRows):
+			t.Errorf("submission %d: %v", i, err)
+		}
+	}
+	if won != 1 {
+		t.Fatalf("%d submissions recorded the order, want exactly 1", won)
+	}
+
+	sale, err := store.Get_Sale_By_Order(ctx, Get_Sale_By_OrderParams{
+		BrandID: brandID,
+		OrderID: sql.NullString{String: orderID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Get_Sale_By_Order: %v", err)
+	}
+	if sale.ID != winner.Sale.ID {
+		t.Errorf("order has sale %d, want %d", sale.ID, winner.Sale.ID)
+	}
+
+	conversions, err := store.Get_Conversions_By_Sale(ctx, sql.NullInt64{Int64: sale.ID, Valid: true})
+	if err != nil {
+		t.Fatalf("Get_Conversions_By_Sale: %v", err)
+	}
+	if len(conversions) != 2 {
+		t.Errorf("sale has %d conversions, want 2", len(conversions))
+	}
+}
+
+func TestCreateConversionTxRollsBack(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+	brandID := createTestBrand(t, store)
+	orderID := uuid.NewString()
+
+	// The second conversion breaks the weight CHECK after the sale and the
+	// first conversion are written
+	arg := conversionTxParams(brandID, orderID, 0.5, 2)
+	if _, err := store.CreateConversionTx(ctx, arg); err == nil {
+		t.Fatal("CreateConversionTx succeeded with an invalid weight")
+	}
+
+	_, err := store.Get_Sale_By_Order(ctx, Get_Sale_By_OrderParams{
+		BrandID: brandID,
+		OrderID: sql.NullString{String: orderID, Valid: true},
+	})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Get_Sale_By_Order after a failed write: got %v, want sql.ErrNoRows", err)
+	}
+
+	var conversions int
+	err = store.GetDB().QueryRowContext(ctx, "SELECT count(*) FROM conversions WHERE click_id = $1", arg.Conversions[0].ClickID).Scan(&conversions)
+	if err != nil {
+		t.Fatalf("count conversions: %v", err)
+	}
+	if conversions != 0 {
+		t.Errorf("%d conversions survived the failed write, want 0", conversions)
+	}
+
+	// The order is free to be recorded once the payload is fixed
+	if _, err := store.CreateConversionTx(ctx, conversionTxParams(brandID, orderID, 0.5, 0.5)); err != nil {
+		t.Errorf("CreateConversionTx after the rollback: %v", err)
+	}
+}
+
+func TestFoldClickRows(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	row := func(clickID uuid.UUID, offset time.Duration, repeat bool, source string) ClickRow {
+		return ClickRow{
+			Create_ClickParams: Create_ClickParams{
+				ClickID:   clickID,
+				UtmSource: sql.NullString{String: source, Valid: source != ""},
+			},
+			Timestamp: at.Add(offset),
+			Repeat:    repeat,
+		}
+	}
+	lastClicked := func(offset time.Duration) sql.NullTime {
+		return sql.NullTime{Time: at.Add(offset), Valid: true}
+	}
+
+	type folded struct {
+		clickID       uuid.UUID
+		source        string
+		rawClicks     int32
+		timestamp     time.Time
+		lastClickedAt sql.NullTime
+	}
+	tests := []struct {
+		name string
+		rows []ClickRow
+		want []folded
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "distinct clicks keep their order",
+			rows: []ClickRow{row(first, 0, false, "a"), row(second, time.Second, false, "b")},
+			want: []folded{
+				{clickID: first, source: "a", rawClicks: 1, timestamp: at},
+				{clickID: second, source: "b", rawClicks: 1, timestamp: at.Add(time.Second)},
+			},
+		},
+		{
+			name: "repeats fold into the original",
+			rows: []ClickRow{row(first, 0, false, "a"), row(first, time.Minute, true, "r"), row(first, 2*time.Minute, true, "r")},
+			want: []folded{
+				{clickID: first, source: "a", rawClicks: 3, timestamp: at, lastClickedAt: lastClicked(2 * time.Minute)},
+			},
+		},
+		{
+			name: "original arriving after its repeat still wins",
+			rows: []ClickRow{row(first, time.Minute, true, "r"), row(first, 0, false, "a")},
+			want: []folded{
+				{clickID: first, source: "a", rawClicks: 2, timestamp: at, lastClickedAt: lastClicked(time.Minute)},
+			},
+		},
+		{
+			name: "repeats alone keep the earliest timestamp and the latest repeat",
+			rows: []ClickRow{row(first, 2*time.Minute, true, "r"), row(first, time.Minute, true, "r")},
+			want: []folded{
+				{clickID: first, source: "r", rawClicks: 2, timestamp: at.Add(time.Minute), lastClickedAt: lastClicked(2 * time.Minute)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := foldClickRows(tt.rows)
+			if len(got) != len(tt.want) {
+				t.Fatalf("foldClickRows() returned %d rows, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				row := got[i]
+				if row.ClickID != want.clickID || row.UtmSource.String != want.source || row.rawClicks != want.rawClicks ||
+					!row.Timestamp.Equal(want.timestamp) || row.lastClickedAt != want.lastClickedAt {
+					t.Errorf("row %d = {%s %q raw=%d at=%s last=%v}, want {%s %q raw=%d at=%s last=%v}", i,
+						row.ClickID, row.UtmSource.String, row.rawClicks, row.Timestamp, row.lastClickedAt,
+						want.clickID, want.source, want.rawClicks, want.timestamp, want.lastClickedAt)
+				}
+			}
+		})
+	}
+}
@@ -10,25 +10,72 @@ import (
 	"database/sql"
 )
 
+const add_TrackingLink_Clicks = `-- name: Add_TrackingLink_Clicks :exec
+UPDATE tracking_links
+SET click_count = click_count + $1
+WHERE id = $2
+`
+
+type Add_TrackingLink_ClicksParams struct {
+	Clicks int64
+	ID     int64
+}
+
+func (q *Queries) Add_TrackingLink_Clicks(ctx context.Context, arg Add_TrackingLink_ClicksParams) error {
+	_, err := q.db.ExecContext(ctx, add_TrackingLink_Clicks, arg.Clicks, arg.ID)
+	return err
+}
+
+const claim_TrackingLink_Click = `-- name: Claim_TrackingLink_Click :one
+UPDATE tracking_links
+SET click_count = click_count + 1
+WHERE id = $1 AND (max_clicks IS NULL OR click_count < max_clicks)
+RETURNING click_count
+`
+
+// Takes one click from a capped link's allowance; no row means it is used up
+func (q *Queries) Claim_TrackingLink_Click(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, claim_TrackingLink_Click, id)
+	var click_count int64
+	err := row.Scan(&click_count)
+	return click_count, err
+}
+
 const create_TrackingLink = `-- name: Create_TrackingLink :one
 INSERT INTO tracking_links (
     affiliate_id,
     campaign_id,
     link_code,
+    label,
+    utm_source,
+    utm_medium,
+    utm_campaign,
     created_at
 ) VALUES (
-    $1, $2, $3, CURRENT_TIMESTAMP
-) RETURNING id, affiliate_id, campaign_id, link_code, created_at
+    $1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP
+) RETURNING id, affiliate_id, campaign_id, link_code, created_at, label, utm_source, utm_medium, utm_campaign, expires_at, is_active, max_clicks, click_count, dedup_window_seconds
 `
 
 type Create_TrackingLinkParams struct {
 	AffiliateID sql.NullInt64
 	CampaignID  sql.NullInt64
 	LinkCode    string
+	Label       sql.NullString
+	UtmSource   sql.NullString
+	UtmMedium   sql.NullString
+	UtmCampaign sql.NullString
 }
 
 func (q *Queries) Create_TrackingLink(ctx context.Context, arg Create_TrackingLinkParams) (TrackingLink, error) {
-	row := q.db.QueryRowContext(ctx, create_TrackingLink, arg.AffiliateID, arg.CampaignID, arg.LinkCode)
+	row := q.db.QueryRowContext(ctx, create_TrackingLink,
+		arg.AffiliateID,
+		arg.CampaignID,
+		arg.LinkCode,
+		arg.Label,
+		arg.UtmSource,
+		arg.UtmMedium,
+		arg.UtmCampaign,
+	)
 	var i TrackingLink
 	err := row.Scan(
 		&i.ID,
@@ -36,6 +83,15 @@ func (q *Queries) Create_TrackingLink(ctx context.Context, arg Create_TrackingLi
 		&i.CampaignID,
 		&i.LinkCode,
 		&i.CreatedAt,
+		&i.Label,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.ExpiresAt,
+		&i.IsActive,
+		&i.MaxClicks,
+		&i.ClickCount,
+		&i.DedupWindowSeconds,
 	)
 	return i, err
 }
@@ -51,7 +107,7 @@ func (q *Queries) Delete_TrackingLink(ctx context.Context, id int64) error {
 }
 
 const get_TrackingLink_By_Link_Code = `-- name: Get_TrackingLink_By_Link_Code :one
-SELECT id, affiliate_id, campaign_id, link_code, created_at
+SELECT id, affiliate_id, campaign_id, link_code, created_at, label, utm_source, utm_medium, utm_campaign, expires_at, is_active, max_clicks, click_count, dedup_window_seconds
 FROM tracking_links
 WHERE link_code = $1
 `
@@ -65,6 +121,15 @@ func (q *Queries) Get_TrackingLink_By_Link_Code(ctx context.Context, linkCode st
 		&i.CampaignID,
 		&i.LinkCode,
 		&i.CreatedAt,
+		&i.Label,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.ExpiresAt,
+		&i.IsActive,
+		&i.MaxClicks,
+		&i.ClickCount,
+		&i.DedupWindowSeconds,
 	)
 	return i, err
 }
@@ -79,6 +144,8 @@ FROM users u
 JOIN affiliates a ON u.id = a.user_id
 JOIN tracking_links tl ON a.id = tl.affiliate_id
 WHERE tl.campaign_id = $1 AND u.id = $2
+ORDER BY tl.label NULLS FIRST, tl.created_at
+LIMIT 1
 `
 
 type Get_TrackingLink_For_AffiliateParams struct {
@@ -105,19 +172,154 @@ func (q *Queries) Get_TrackingLink_For_Affiliate(ctx context.Context, arg Get_Tr
 	return i, err
 }
 
+const get_TrackingLink_Owners = `-- name: Get_TrackingLink_Owners :one
+SELECT 
+    c.brand_id,
+    a.user_id AS affiliate_user_id
+FROM tracking_links tl
+LEFT JOIN campaigns c ON c.id = tl.campaign_id
+LEFT JOIN affiliates a ON a.id = tl.affiliate_id
+WHERE tl.id = $1
+`
+
+type Get_TrackingLink_OwnersRow struct {
+	BrandID         sql.NullInt64
+	AffiliateUserID sql.NullInt64
+}
+
+// The brand running a link's campaign and the user behind its affiliate
+func (q *Queries) Get_TrackingLink_Owners(ctx context.Context, id int64) (Get_TrackingLink_OwnersRow, error) {
+	row := q.db.QueryRowContext(ctx, get_TrackingLink_Owners, id)
+	var i Get_TrackingLink_OwnersRow
+	err := row.Scan(&i.BrandID, &i.AffiliateUserID)
+	return i, err
+}
+
+const get_TrackingLink_QR_Target = `-- name: Get_TrackingLink_QR_Target :one
+SELECT 
+    tl.link_code,
+    c.brand_id,
+    bd.domain AS short_domain
+FROM tracking_links tl
+JOIN campaigns c ON tl.campaign_id = c.id
+LEFT JOIN LATERAL (
+    SELECT domain
+    FROM brand_domains
+    WHERE brand_domains.brand_id = c.brand_id
+    AND brand_domains.verified_at IS NOT NULL
+    ORDER BY created_at
+    LIMIT 1
+) bd ON true
+WHERE tl.id = $1
+`
+
+type Get_TrackingLink_QR_TargetRow struct {
+	LinkCode    string
+	BrandID     sql.NullInt64
+	ShortDomain sql.NullString
+}
+
+func (q *Queries) Get_TrackingLink_QR_Target(ctx context.Context, id int64) (Get_TrackingLink_QR_TargetRow, error) {
+	row := q.db.QueryRowContext(ctx, get_TrackingLink_QR_Target, id)
+	var i Get_TrackingLink_QR_TargetRow
+	err := row.Scan(&i.LinkCode, &i.BrandID, &i.ShortDomain)
+	return i, err
+}
+
 const get_TrackingLinks_By_Affiliate = `-- name: Get_TrackingLinks_By_Affiliate :many
-SELECT id, affiliate_id, campaign_id, link_code, created_at
-FROM tracking_links
-WHERE affiliate_id = $1
-ORDER BY created_at DESC
+SELECT 
+    tl.id, tl.affiliate_id, tl.campaign_id, tl.link_code, tl.created_at, tl.label, tl.utm_source, tl.utm_medium, tl.utm_campaign, tl.expires_at, tl.is_active, tl.max_clicks, tl.click_count, tl.dedup_window_seconds,
+    bd.domain AS short_domain
+FROM tracking_links tl
+LEFT JOIN campaigns c ON tl.campaign_id = c.id
+LEFT JOIN LATERAL (
+    SELECT domain
+    FROM brand_domains
+    WHERE brand_domains.brand_id = c.brand_id
+    AND brand_domains.verified_at IS NOT NULL
+    ORDER BY created_at
+    LIMIT 1
+) bd ON true
+WHERE tl.affiliate_id = $1
+ORDER BY tl.created_at DESC
 `
 
-func (q *Queries) Get_TrackingLinks_By_Affiliate(ctx context.Context, affiliateID sql.NullInt64) ([]TrackingLink, error) {
+type Get_TrackingLinks_By_AffiliateRow struct {
+	ID                 int64
+	AffiliateID        sql.NullInt64
+	CampaignID         sql.NullInt64
+	LinkCode           string
+	CreatedAt          sql.NullTime
+	Label              sql.NullString
+	UtmSource          sql.NullString
+	UtmMedium          sql.NullString
+	UtmCampaign        sql.NullString
+	ExpiresAt          sql.NullTime
+	IsActive           bool
+	MaxClicks          sql.NullInt64
+	ClickCount         int64
+	DedupWindowSeconds sql.NullInt32
+	ShortDomain        sql.NullString
+}
+
+func (q *Queries) Get_TrackingLinks_By_Affiliate(ctx context.Context, affiliateID sql.NullInt64) ([]Get_TrackingLinks_By_AffiliateRow, error) {
 	rows, err := q.db.QueryContext(ctx, get_TrackingLinks_By_Affiliate, affiliateID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	var items []Get_TrackingLinks_By_AffiliateRow
+	for rows.Next() {
+		var i Get_TrackingLinks_By_AffiliateRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AffiliateID,
+			&i.CampaignID,
+			&i.LinkCode,
+			&i.CreatedAt,
+			&i.Label,
+			&i.UtmSource,
+			&i.UtmMedium,
+			&i.UtmCampaign,
+			&i.ExpiresAt,
+			&i.IsActive,
+			&i.MaxClicks,
+			&i.ClickCount,
+			&i.DedupWindowSeconds,
+			&i.ShortDomain,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const get_TrackingLinks_By_Affiliate_Campaign = `-- name: Get_TrackingLinks_By_Affiliate_Campaign :many
+SELECT id, affiliate_id, campaign_id, link_code, created_at, label, utm_source, utm_medium, utm_campaign, expires_at, is_active, max_clicks, click_count, dedup_window_seconds
+FROM tracking_links
+WHERE affiliate_id = $1 AND campaign_id = $2
+ORDER BY label NULLS FIRST, created_at
+`
+
+type Get_TrackingLinks_By_Affiliate_CampaignParams struct {
+	AffiliateID sql.NullInt64
+	CampaignID  sql.NullInt64
+}
+
+// The unlabelled default link first, then sub-links by label
+func (q *Queries) Get_TrackingLinks_By_Affiliate_Campaign(ctx context.Context, arg Get_TrackingLinks_By_Affiliate_CampaignParams) ([]TrackingLink, error) {
+	rows, err := q.db.QueryContext(ctx, get_TrackingLinks_By_Affiliate_Campaign, arg.AffiliateID, arg.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 	var items []TrackingLink
 	for rows.Next() {
 		var i TrackingLink
@@ -127,6 +329,15 @@ func (q *Queries) Get_TrackingLinks_By_Affiliate(ctx context.Context, affiliateI
 			&i.CampaignID,
 			&i.LinkCode,
 			&i.CreatedAt,
+			&i.Label,
+			&i.UtmSource,
+			&i.UtmMedium,
+			&i.UtmCampaign,
+			&i.ExpiresAt,
+			&i.IsActive,
+			&i.MaxClicks,
+			&i.ClickCount,
+			&i.DedupWindowSeconds,
 		); err != nil {
 			return nil, err
 		}
@@ -142,7 +353,7 @@ func (q *Queries) Get_TrackingLinks_By_Affiliate(ctx context.Context, affiliateI
 }
 
 const get_TrackingLinks_By_Campaign = `-- name: Get_TrackingLinks_By_Campaign :many
-SELECT id, affiliate_id, campaign_id, link_code, created_at
+SELECT id, affiliate_id, campaign_id, link_code, created_at, label, utm_source, utm_medium, utm_campaign, expires_at, is_active, max_clicks, click_count, dedup_window_seconds
 FROM tracking_links
 WHERE campaign_id = $1
 ORDER BY created_at DESC
@@ -163,6 +374,15 @@ func (q *Queries) Get_TrackingLinks_By_Campaign(ctx context.Context, campaignID
 			&i.CampaignID,
 			&i.LinkCode,
 			&i.CreatedAt,
+			&i.Label,
+			&i.UtmSource,
+			&i.UtmMedium,
+			&i.UtmCampaign,
+			&i.ExpiresAt,
+			&i.IsActive,
+			&i.MaxClicks,
+			&i.ClickCount,
+			&i.DedupWindowSeconds,
 		); err != nil {
 			return nil, err
 		}
@@ -178,7 +398,7 @@ func (q *Queries) Get_TrackingLinks_By_Campaign(ctx context.Context, campaignID
 }
 
 const get_Tracking_By_Link = `-- name: Get_Tracking_By_Link :one
-SELECT id, affiliate_id, campaign_id, link_code, created_at
+SELECT id, affiliate_id, campaign_id, link_code, created_at, label, utm_source, utm_medium, utm_campaign, expires_at, is_active, max_clicks, click_count, dedup_window_seconds
 FROM tracking_links
 WHERE id = $1
 `
@@ -192,6 +412,132 @@ func (q *Queries) Get_Tracking_By_Link(ctx context.Context, id int64) (TrackingL
 		&i.CampaignID,
 		&i.LinkCode,
 		&i.CreatedAt,
+		&i.Label,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.ExpiresAt,
+		&i.IsActive,
+		&i.MaxClicks,
+		&i.ClickCount,
+		&i.DedupWindowSeconds,
+	)
+	return i, err
+}
+
+const release_TrackingLink_Click = `-- name: Release_TrackingLink_Click :exec
+UPDATE tracking_links
+SET click_count = click_count - 1
+WHERE id = $1 AND click_count > 0
+`
+
+// Gives back a click taken by Claim_TrackingLink_Click that was never recorded
+func (q *Queries) Release_TrackingLink_Click(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, release_TrackingLink_Click, id)
+	return err
+}
+
+const set_TrackingLink_Active = `-- name: Set_TrackingLink_Active :one
+UPDATE tracking_links
+SET is_active = $2
+WHERE id = $1
+RETURNING id, affiliate_id, campaign_id, link_code, created_at, label, utm_source, utm_medium, utm_campaign, expires_at, is_active, max_clicks, click_count, dedup_window_seconds
+`
+
+type Set_TrackingLink_ActiveParams struct {
+	ID       int64
+	IsActive bool
+}
+
+func (q *Queries) Set_TrackingLink_Active(ctx context.Context, arg Set_TrackingLink_ActiveParams) (TrackingLink, error) {
+	row := q.db.QueryRowContext(ctx, set_TrackingLink_Active, arg.ID, arg.IsActive)
+	var i TrackingLink
+	err := row.Scan(
+		&i.ID,
+		&i.AffiliateID,
+		&i.CampaignID,
+		&i.LinkCode,
+		&i.CreatedAt,
+		&i.Label,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.ExpiresAt,
+		&i.IsActive,
+		&i.MaxClicks,
+		&i.ClickCount,
+		&i.DedupWindowSeconds,
+	)
+	return i, err
+}
+
+const update_TrackingLink_Dedup_Window = `-- name: Update_TrackingLink_Dedup_Window :one
+UPDATE tracking_links
+SET dedup_window_seconds = $2
+WHERE id = $1
+RETURNING id, affiliate_id, campaign_id, link_code, created_at, label, utm_source, utm_medium, utm_campaign, expires_at, is_active, max_clicks, click_count, dedup_window_seconds
+`
+
+type Update_TrackingLink_Dedup_WindowParams struct {
+	ID                 int64
+	DedupWindowSeconds sql.NullInt32
+}
+
+func (q *Queries) Update_TrackingLink_Dedup_Window(ctx context.Context, arg Update_TrackingLink_Dedup_WindowParams) (TrackingLink, error) {
+	row := q.db.QueryRowContext(ctx, update_TrackingLink_Dedup_Window, arg.ID, arg.DedupWindowSeconds)
+	var i TrackingLink
+	err := row.Scan(
+		&i.ID,
+		&i.AffiliateID,
+		&i.CampaignID,
+		&i.LinkCode,
+		&i.CreatedAt,
+		&i.Label,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.ExpiresAt,
+		&i.IsActive,
+		&i.MaxClicks,
+		&i.ClickCount,
+		&i.DedupWindowSeconds,
+	)
+	return i, err
+}
+
+const update_TrackingLink_Limits = `-- name: Update_TrackingLink_Limits :one
+UPDATE tracking_links
+SET 
+    expires_at = $2,
+    max_clicks = $3
+WHERE id = $1
+RETURNING id, affiliate_id, campaign_id, link_code, created_at, label, utm_source, utm_medium, utm_campaign, expires_at, is_active, max_clicks, click_count, dedup_window_seconds
+`
+
+type Update_TrackingLink_LimitsParams struct {
+	ID        int64
+	ExpiresAt sql.NullTime
+	MaxClicks sql.NullInt64
+}
+
+func (q *Queries) Update_TrackingLink_Limits(ctx context.Context, arg Update_TrackingLink_LimitsParams) (TrackingLink, error) {
+	row := q.db.QueryRowContext(ctx, update_TrackingLink_Limits, arg.ID, arg.ExpiresAt, arg.MaxClicks)
+	var i TrackingLink
+	err := row.Scan(
+		&i.ID,
+		&i.AffiliateID,
+		&i.CampaignID,
+		&i.LinkCode,
+		&i.CreatedAt,
+		&i.Label,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.ExpiresAt,
+		&i.IsActive,
+		&i.MaxClicks,
+		&i.ClickCount,
+		&i.DedupWindowSeconds,
 	)
 	return i, err
 }
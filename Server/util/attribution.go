@@ -0,0 +1,188 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Attribution model names, as stored on brands, campaigns and conversions.
+const (
+	AttributionFirstTouch = "first_touch"
+	AttributionLastTouch  = "last_touch"
+	AttributionLinear     = "linear"
+	AttributionUShaped    = "u_shaped"
+	AttributionWShaped    = "w_shaped"
+	AttributionTimeDecay  = "time_decay"
+)
+
+// DefaultAttributionModel is what a brand uses until it picks another.
+const DefaultAttributionModel = AttributionUShaped
+
+// AttributionModels lists every model a brand or campaign may choose.
+var AttributionModels = []string{
+	AttributionFirstTouch,
+	AttributionLastTouch,
+	AttributionLinear,
+	AttributionUShaped,
+	AttributionWShaped,
+	AttributionTimeDecay,
+}
+
+// AttributionModel splits a conversion's credit between the clicks that led
+// to it. touches are click times, oldest first; the returned weights line up
+// with them, each between 0 and 1, and sum to 1 whenever there is at least
+// one touch. With no touches, Weights returns an empty, non-nil slice.
+type AttributionModel interface {
+	Name() string
+	Weights(touches []time.Time, convertedAt time.Time) []float64
+}
+
+// NewAttributionModel returns the named model. halfLife only matters for
+// time decay.
+func NewAttributionModel(name string, halfLife time.Duration) (AttributionModel, error) {
+	switch name {
+	case AttributionFirstTouch:
+		return firstTouch{}, nil
+	case AttributionLastTouch:
+		return lastTouch{}, nil
+	case AttributionLinear:
+		return linear{}, nil
+	case AttributionUShaped:
+		return uShaped{}, nil
+	case AttributionWShaped:
+		return wShaped{}, nil
+	case AttributionTimeDecay:
+		if halfLife <= 0 {
+			return nil, fmt.Errorf("time decay needs a positive half-life, got %s", halfLife)
+		}
+		return TimeDecay{HalfLife: halfLife}, nil
+	}
+	return nil, fmt.Errorf("unknown attribution model %q", name)
+}
+
+// ValidAttributionModel reports whether name is one of AttributionModels.
+func ValidAttributionModel(name string) bool {
+	for _, model := range AttributionModels {
+		if model == name {
+			return true
+		}
+	}
+	return false
+}
+
+type firstTouch struct{}
+
+func (firstTouch) Name() string { return AttributionFirstTouch }
+
+func (firstTouch) Weights(touches []time.Time, _ time.Time) []float64 {
+	weights := make([]float64, len(touches))
+	if len(weights) > 0 {
+		weights[0] = 1
+	}
+	return weights
+}
+
+type lastTouch struct{}
+
+func (lastTouch) Name() string { return AttributionLastTouch }
+
+func (lastTouch) Weights(touches []time.Time, _ time.Time) []float64 {
+	weights := make([]float64, len(touches))
+	if len(weights) > 0 {
+		weights[len(weights)-1] = 1
+	}
+	return weights
+}
+
+type linear struct{}
+
+func (linear) Name() string { return AttributionLinear }
+
+func (linear) Weights(touches []time.Time, _ time.Time) []float64 {
+	weights := make([]float64, len(touches))
+	for i := range weights {
+		weights[i] = 1 / float64(len(weights))
+	}
+	return weights
+}
+
+// uShaped gives 40% each to the first and last touch and splits the other 20%
+// between the ones in between. With no touches in between, the first and last
+// share the credit evenly.
+type uShaped struct{}
+
+func (uShaped) Name() string { return AttributionUShaped }
+
+func (uShaped) Weights(touches []time.Time, _ time.Time) []float64 {
+	return positionWeights(len(touches), []int{0, len(touches) - 1}, 0.4)
+}
+
+// wShaped gives 30% each to the first touch, the middle one and the last,
+// and splits the other 10% between the rest.
+type wShaped struct{}
+
+func (wShaped) Name() string { return AttributionWShaped }
+
+func (wShaped) Weights(touches []time.Time, _ time.Time) []float64 {
+	n := len(touches)
+	return positionWeights(n, []int{0, n / 2, n - 1}, 0.3)
+}
+
+// positionWeights gives each of the key positions share and splits what is
+// left between the other touches. When every touch is a key position, they
+// share the credit evenly instead.
+func positionWeights(n int, keys []int, share float64) []float64 {
+	weights := make([]float64, n)
+	if n == 0 {
+		return weights
+	}
+
+	key := map[int]bool{}
+	for _, i := range keys {
+		key[i] = true
+	}
+	if len(key) == n {
+		for i := range weights {
+			weights[i] = 1 / float64(n)
+		}
+		return weights
+	}
+
+	rest := (1 - share*float64(len(key))) / float64(n-len(key))
+	for i := range weights {
+		if key[i] {
+			weights[i] = share
+		} else {
+			weights[i] = rest
+		}
+	}
+	return weights
+}
+
+// TimeDecay halves a touch's credit for every HalfLife between the click and
+// the conversion.
+type TimeDecay struct {
+	HalfLife time.Duration
+}
+
+func (TimeDecay) Name() string { return AttributionTimeDecay }
+
+func (model TimeDecay) Weights(touches []time.Time, convertedAt time.Time) []float64 {
+	weights := make([]float64, len(touches))
+	total := 0.0
+	for i, touch := range touches {
+		age := max(convertedAt.Sub(touch), 0)
+		weights[i] = math.Exp2(-float64(age) / float64(model.HalfLife))
+		total += weights[i]
+	}
+
+	// Touches so old that every weight underflows share the credit evenly
+	if total == 0 {
+		return linear{}.Weights(touches, convertedAt)
+	}
+	for i := range weights {
+		weights[i] /= total
+	}
+	return weights
+}
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const weightTolerance = 1e-9
+
+func newTestModel(t *testing.T, name string) AttributionModel {
+	t.Helper()
+	model, err := NewAttributionModel(name, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewAttributionModel(%q): %v", name, err)
+	}
+	return model
+}
+
+// touchPaths returns paths of n touches before convertedAt, oldest first.
+func touchPaths(n int, convertedAt time.Time) map[string][]time.Time {
+	spaced := make([]time.Time, n)
+	equal := make([]time.Time, n)
+	stale := make([]time.Time, n)
+	mixed := make([]time.Time, n)
+	for i := range spaced {
+		spaced[i] = convertedAt.Add(-time.Duration(n-i) * time.Hour)
+		equal[i] = convertedAt.Add(-time.Hour)
+		// Far more half-lives old than a float64 can decay through
+		stale[i] = convertedAt.Add(-time.Duration(5000+n-i) * 24 * time.Hour)
+		mixed[i] = stale[i]
+	}
+	mixed[n-1] = convertedAt
+
+	return map[string][]time.Time{
+		"spaced":          spaced,
+		"equal":           equal,
+		"past half-life":  stale,
+		"stale and fresh": mixed,
+	}
+}
+
+func TestAttributionWeightsSumToOne(t *testing.T) {
+	convertedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, name := range AttributionModels {
+		model := newTestModel(t, name)
+		for n := 1; n <= 12; n++ {
+			for path, touches := range touchPaths(n, convertedAt) {
+				weights := model.Weights(touches, convertedAt)
+				if len(weights) != n {
+					t.Fatalf("%s, %d %s touches: got %d weights", name, n, path, len(weights))
+				}
+
+				sum := 0.0
+				for i, weight := range weights {
+					if weight < 0 || weight > 1 || math.IsNaN(weight) {
+						t.Errorf("%s, %d %s touches: weight %d is %v", name, n, path, i, weight)
+					}
+					sum += weight
+				}
+				if math.Abs(sum-1) > weightTolerance {
+					t.Errorf("%s, %d %s touches: weights %v sum to %v", name, n, path, weights, sum)
+				}
+			}
+		}
+	}
+}
+
+func TestAttributionWeightsNoTouches(t *testing.T) {
+	for _, name := range AttributionModels {
+		for _, touches := range [][]time.Time{nil, {}} {
+			weights := newTestModel(t, name).Weights(touches, time.Now())
+			if weights == nil || len(weights) != 0 {
+				t.Errorf("%s with no touches: got %#v, want an empty slice", name, weights)
+			}
+		}
+	}
+}
+
+func TestAttributionWeights(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(hours ...int) []time.Time {
+		touches := make([]time.Time, len(hours))
+		for i, h := range hours {
+			touches[i] = at.Add(-time.Duration(h) * time.Hour)
+		}
+		return touches
+	}
+
+	tests := []struct {
+		model   string
+		touches []time.Time
+		want    []float64
+	}{
+		{AttributionFirstTouch, ago(3, 2, 1), []float64{1, 0, 0}},
+		{AttributionLastTouch, ago(3, 2, 1), []float64{0, 0, 1}},
+		{AttributionLinear, ago(4, 3, 2, 1), []float64{0.25, 0.25, 0.25, 0.25}},
+		{AttributionUShaped, ago(1), []float64{1}},
+		{AttributionUShaped, ago(2, 1), []float64{0.5, 0.5}},
+		{AttributionUShaped, ago(3, 2, 1), []float64{0.4, 0.2, 0.4}},
+		{AttributionUShaped, ago(4, 3, 2, 1), []float64{0.4, 0.1, 0.1, 0.4}},
+		{AttributionWShaped, ago(2, 1), []float64{0.5, 0.5}},
+		{AttributionWShaped, ago(3, 2, 1), []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}},
+		{AttributionWShaped, ago(4, 3, 2, 1), []float64{0.3, 0.1, 0.3, 0.3}},
+		{AttributionWShaped, ago(5, 4, 3, 2, 1), []float64{0.3, 0.05, 0.3, 0.05, 0.3}},
+		// A 24 hour half-life: a touch a day older gets half the credit
+		{AttributionTimeDecay, ago(48, 24), []float64{1.0 / 3, 2.0 / 3}},
+		{AttributionTimeDecay, ago(5, 5, 5), []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}},
+		{AttributionTimeDecay, ago(-1), []float64{1}},
+	}
+
+	for _, tt := range tests {
+		weights := newTestModel(t, tt.model).Weights(tt.touches, at)
+		if len(weights) != len(tt.want) {
+			t.Errorf("%s over %d touches: got %v, want %v", tt.model, len(tt.touches), weights, tt.want)
+			continue
+		}
+		for i := range weights {
+			if math.Abs(weights[i]-tt.want[i]) > weightTolerance {
+				t.Errorf("%s over %d touches: got %v, want %v", tt.model, len(tt.touches), weights, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestNewAttributionModel(t *testing.T) {
+	for _, name := range AttributionModels {
+		model := newTestModel(t, name)
+		if model.Name() != name {
+			t.Errorf("NewAttributionModel(%q).Name() = %q", name, model.Name())
+		}
+	}
+
+	if _, err := NewAttributionModel(AttributionTimeDecay, 0); err == nil {
+		t.Error("time decay without a half-life was accepted")
+	}
+	if _, err := NewAttributionModel("most_touches", time.Hour); err == nil {
+		t.Error("an unknown model was accepted")
+	}
+}
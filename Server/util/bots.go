@@ -0,0 +1,156 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Bot reasons are stored on clicks.bot_reason as "<kind>:<name>".
+const (
+	BotKindPreview  = "preview"
+	BotKindCrawler  = "crawler"
+	BotKindHeadless = "headless"
+	BotKindTool     = "tool"
+	BotKindIPRange  = "ip_range"
+	BotKindNoAgent  = "no_user_agent"
+)
+
+type botSignature struct {
+	kind   string
+	name   string
+	tokens []string
+}
+
+// Tokens are matched case-insensitively against the user agent. Previews come
+// first because several fetchers also say "bot". In-app browsers (Instagram,
+// TikTok, Snapchat) are real visitors and deliberately absent.
+var botSignatures = []botSignature{
+	{BotKindPreview, "whatsapp", []string{"whatsapp/"}},
+	{BotKindPreview, "facebook", []string{"facebookexternalhit", "facebookcatalog", "meta-externalagent"}},
+	{BotKindPreview, "slack", []string{"slackbot", "slack-imgproxy"}},
+	{BotKindPreview, "twitter", []string{"twitterbot"}},
+	{BotKindPreview, "linkedin", []string{"linkedinbot"}},
+	{BotKindPreview, "telegram", []string{"telegrambot"}},
+	{BotKindPreview, "discord", []string{"discordbot"}},
+	{BotKindPreview, "skype", []string{"skypeuripreview"}},
+	{BotKindPreview, "pinterest", []string{"pinterestbot"}},
+	{BotKindPreview, "apple", []string{"applebot"}},
+	{BotKindPreview, "microsoft", []string{"microsoftpreview"}},
+	{BotKindPreview, "embedly", []string{"embedly"}},
+
+	{BotKindHeadless, "headless_chrome", []string{"headlesschrome"}},
+	{BotKindHeadless, "phantomjs", []string{"phantomjs"}},
+	{BotKindHeadless, "puppeteer", []string{"puppeteer"}},
+	{BotKindHeadless, "playwright", []string{"playwright"}},
+	{BotKindHeadless, "selenium", []string{"selenium", "webdriver"}},
+	{BotKindHeadless, "lighthouse", []string{"chrome-lighthouse"}},
+
+	{BotKindTool, "curl", []string{"curl/"}},
+	{BotKindTool, "wget", []string{"wget/"}},
+	{BotKindTool, "python", []string{"python-requests", "python-urllib", "aiohttp", "httpx"}},
+	{BotKindTool, "go", []string{"go-http-client"}},
+	{BotKindTool, "java", []string{"java/", "okhttp", "apache-httpclient"}},
+	{BotKindTool, "node", []string{"node-fetch", "axios/", "undici"}},
+	{BotKindTool, "postman", []string{"postmanruntime"}},
+
+	{BotKindCrawler, "google", []string{"googlebot", "adsbot-google", "mediapartners-google", "google-inspectiontool", "googleother"}},
+	{BotKindCrawler, "bing", []string{"bingbot", "bingpreview", "msnbot"}},
+	{BotKindCrawler, "yandex", []string{"yandexbot", "yandex.com/bots"}},
+	{BotKindCrawler, "baidu", []string{"baiduspider"}},
+	{BotKindCrawler, "duckduckgo", []string{"duckduckbot"}},
+	{BotKindCrawler, "ahrefs", []string{"ahrefsbot"}},
+	{BotKindCrawler, "semrush", []string{"semrushbot"}},
+	{BotKindCrawler, "openai", []string{"gptbot", "chatgpt-user", "oai-searchbot"}},
+	// Catch-alls, loose enough to skip device names such as "CUBOT X30"
+	{BotKindCrawler, "generic", []string{"bot/", "bot;", "+http", "crawler", "spider", "scraper"}},
+}
+
+type ipRangeList struct {
+	name string
+	nets []*net.IPNet
+}
+
+// BotClassifier flags clicks from bots, link-preview fetchers and headless
+// browsers by user agent, and optionally by source IP range.
+type BotClassifier struct {
+	ranges []ipRangeList
+}
+
+// NewBotClassifier loads CIDR lists from paths, one range per line with #
+// comments. Each file's base name becomes the reason for matching IPs.
+func NewBotClassifier(paths []string) (*BotClassifier, error) {
+	classifier := &BotClassifier{}
+	for _, path := range paths {
+		list, err := loadIPRangeList(path)
+		if err != nil {
+			return nil, err
+		}
+		classifier.ranges = append(classifier.ranges, list)
+	}
+	return classifier, nil
+}
+
+func loadIPRangeList(path string) (ipRangeList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ipRangeList{}, err
+	}
+	defer file.Close()
+
+	list := ipRangeList{name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(entry, '#'); i >= 0 {
+			entry = strings.TrimSpace(entry[:i])
+		}
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if strings.Contains(entry, ":") {
+				entry += "/128"
+			} else {
+				entry += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return ipRangeList{}, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		list.nets = append(list.nets, ipNet)
+	}
+	return list, scanner.Err()
+}
+
+// Classify returns why a request looks automated, or "" for a human visitor.
+func (classifier *BotClassifier) Classify(userAgent, ip string) string {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return BotKindNoAgent
+	}
+
+	for _, signature := range botSignatures {
+		for _, token := range signature.tokens {
+			if strings.Contains(ua, token) {
+				return signature.kind + ":" + signature.name
+			}
+		}
+	}
+
+	if parsed := net.ParseIP(ip); parsed != nil {
+		for _, list := range classifier.ranges {
+			for _, ipNet := range list.nets {
+				if ipNet.Contains(parsed) {
+					return BotKindIPRange + ":" + list.name
+				}
+			}
+		}
+	}
+
+	return ""
+}
@@ -2,14 +2,59 @@ package util
 
 import (
 	"crypto/rand"
-	"fmt"
+	"errors"
 	"math/big"
 )
 
-func Generate_Code() (string, error) {
-	n, err := rand.Int(rand.Reader, big.NewInt(900000))
-	if err != nil {
-		return "", err
+// DefaultCodeAlphabet leaves out characters that are easy to misread when a
+// link is typed by hand (0/o, 1/i/l).
+const (
+	DefaultCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+	DefaultCodeLength   = 8
+)
+
+type CodeGenerator struct {
+	alphabet []rune
+	length   int
+}
+
+func NewCodeGenerator(alphabet string, length int) (*CodeGenerator, error) {
+	if alphabet == "" {
+		alphabet = DefaultCodeAlphabet
+	}
+	if length == 0 {
+		length = DefaultCodeLength
+	}
+
+	runes := []rune(alphabet)
+	seen := make(map[rune]bool, len(runes))
+	for _, r := range runes {
+		if seen[r] {
+			return nil, errors.New("tracking code alphabet contains duplicate characters")
+		}
+		seen[r] = true
+	}
+
+	if len(runes) < 2 {
+		return nil, errors.New("tracking code alphabet needs at least 2 characters")
+	}
+	if length < 4 {
+		return nil, errors.New("tracking code length must be at least 4")
+	}
+
+	return &CodeGenerator{alphabet: runes, length: length}, nil
+}
+
+// Generate returns a uniformly random code drawn from the generator's alphabet.
+func (g *CodeGenerator) Generate() (string, error) {
+	max := big.NewInt(int64(len(g.alphabet)))
+	code := make([]rune, g.length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = g.alphabet[n.Int64()]
 	}
-	return fmt.Sprintf("%06d", n.Int64()+100000), nil
+	return string(code), nil
 }
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNewCodeGenerator(t *testing.T) {
+	tests := []struct {
+		name       string
+		alphabet   string
+		length     int
+		wantErr    bool
+		wantLength int
+	}{
+		{name: "defaults", wantLength: DefaultCodeLength},
+		{name: "custom alphabet and length", alphabet: "abc", length: 6, wantLength: 6},
+		{name: "multi-byte alphabet", alphabet: "äöü", length: 4, wantLength: 4},
+		{name: "duplicate characters", alphabet: "abca", length: 6, wantErr: true},
+		{name: "single character", alphabet: "a", length: 6, wantErr: true},
+		{name: "too short", alphabet: "abc", length: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := NewCodeGenerator(tt.alphabet, tt.length)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCodeGenerator(%q, %d) error = %v, wantErr %v", tt.alphabet, tt.length, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			alphabet := tt.alphabet
+			if alphabet == "" {
+				alphabet = DefaultCodeAlphabet
+			}
+			code, err := generator.Generate()
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if got := utf8.RuneCountInString(code); got != tt.wantLength {
+				t.Errorf("Generate() = %q has %d characters, want %d", code, got, tt.wantLength)
+			}
+			for _, r := range code {
+				if !strings.ContainsRune(alphabet, r) {
+					t.Errorf("Generate() = %q uses %q, which is not in %q", code, r, alphabet)
+				}
+			}
+		})
+	}
+}
+
+func TestCodeGeneratorUsesWholeAlphabet(t *testing.T) {
+	generator, err := NewCodeGenerator("ab", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 16 possible codes; drawing a few hundred should see every one of them
+	seen := map[string]bool{}
+	for i := 0; i < 500; i++ {
+		code, err := generator.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[code] = true
+	}
+	if len(seen) != 16 {
+		t.Errorf("saw %d distinct codes, want 16", len(seen))
+	}
+}
@@ -15,6 +15,40 @@ type Config struct {
 	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
 	ENVIRONMENT          string        `mapstructure:"ENVIRONMENT"`
+	TrackingCodeAlphabet string        `mapstructure:"TRACKING_CODE_ALPHABET"`
+	TrackingCodeLength   int           `mapstructure:"TRACKING_CODE_LENGTH"`
+	ShortLinkBaseUrl     string        `mapstructure:"SHORT_LINK_BASE_URL"`
+	ShortLinkHosts       string        `mapstructure:"SHORT_LINK_HOSTS"`
+	FallbackUrl          string        `mapstructure:"FALLBACK_URL"`
+	ClickQueueSize       int           `mapstructure:"CLICK_QUEUE_SIZE"`
+	ClickQueueWorkers    int           `mapstructure:"CLICK_QUEUE_WORKERS"`
+	ClickBatchSize       int           `mapstructure:"CLICK_BATCH_SIZE"`
+	ClickFlushInterval   time.Duration `mapstructure:"CLICK_FLUSH_INTERVAL"`
+	ClickQueuePolicy     string        `mapstructure:"CLICK_QUEUE_POLICY"`
+	ClickBlockTimeout    time.Duration `mapstructure:"CLICK_QUEUE_BLOCK_TIMEOUT"`
+	LinkCacheTTL         time.Duration `mapstructure:"LINK_CACHE_TTL"`
+	LinkCacheSize        int           `mapstructure:"LINK_CACHE_SIZE"`
+	RedisUrl             string        `mapstructure:"REDIS_URL"`
+	BotIPRangeFiles      string        `mapstructure:"BOT_IP_RANGE_FILES"`
+	ShutdownTimeout      time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	ClickDedupWindow     time.Duration `mapstructure:"CLICK_DEDUP_WINDOW"`
+	ClickDedupCacheSize  int           `mapstructure:"CLICK_DEDUP_CACHE_SIZE"`
+	GeoIPDbPath          string        `mapstructure:"GEOIP_DB_PATH"`
+	IPPrivacyMode        string        `mapstructure:"IP_PRIVACY_MODE"`
+	IPHashKey            string        `mapstructure:"IP_HASH_KEY"`
+	IPHashRotation       time.Duration `mapstructure:"IP_HASH_ROTATION"`
+	PIIRetentionDays     int           `mapstructure:"PII_RETENTION_DAYS"`
+	PIIRetentionInterval time.Duration `mapstructure:"PII_RETENTION_INTERVAL"`
+	ReferrerStripQuery   bool          `mapstructure:"REFERRER_STRIP_QUERY"`
+	FraudFlagThreshold   int           `mapstructure:"FRAUD_FLAG_THRESHOLD"`
+	FraudIPClicksPerMin  int           `mapstructure:"FRAUD_IP_CLICKS_PER_MINUTE"`
+	FraudLinkClicksPerHr int           `mapstructure:"FRAUD_LINK_IP_CLICKS_PER_HOUR"`
+	FraudMinConvDelay    time.Duration `mapstructure:"FRAUD_MIN_CONVERSION_DELAY"`
+	FraudASNDbPath       string        `mapstructure:"FRAUD_ASN_DB_PATH"`
+	FraudDatacenterASNs  string        `mapstructure:"FRAUD_DATACENTER_ASN_FILE"`
+	AttributionHalfLife  time.Duration `mapstructure:"ATTRIBUTION_HALF_LIFE"`
+	ApprovalInterval     time.Duration `mapstructure:"CONVERSION_APPROVAL_INTERVAL"`
+	CountPendingConvs    bool          `mapstructure:"COUNT_PENDING_CONVERSIONS"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -25,6 +59,42 @@ func LoadConfig(path string) (config Config, err error) {
 	// Enable environment variable overrides
 	viper.AutomaticEnv()
 
+	// Defaults also register the keys so Unmarshal picks up env overrides
+	viper.SetDefault("TRACKING_CODE_ALPHABET", DefaultCodeAlphabet)
+	viper.SetDefault("TRACKING_CODE_LENGTH", DefaultCodeLength)
+	viper.SetDefault("SHORT_LINK_BASE_URL", "")
+	viper.SetDefault("SHORT_LINK_HOSTS", "")
+	viper.SetDefault("FALLBACK_URL", "")
+	viper.SetDefault("CLICK_QUEUE_SIZE", 10000)
+	viper.SetDefault("CLICK_QUEUE_WORKERS", 4)
+	viper.SetDefault("CLICK_BATCH_SIZE", 500)
+	viper.SetDefault("CLICK_FLUSH_INTERVAL", time.Second)
+	viper.SetDefault("CLICK_QUEUE_POLICY", "block")
+	viper.SetDefault("CLICK_QUEUE_BLOCK_TIMEOUT", 250*time.Millisecond)
+	viper.SetDefault("LINK_CACHE_TTL", 30*time.Second)
+	viper.SetDefault("LINK_CACHE_SIZE", 10000)
+	viper.SetDefault("REDIS_URL", "")
+	viper.SetDefault("BOT_IP_RANGE_FILES", "")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", 15*time.Second)
+	viper.SetDefault("CLICK_DEDUP_WINDOW", 30*time.Minute)
+	viper.SetDefault("CLICK_DEDUP_CACHE_SIZE", 100000)
+	viper.SetDefault("GEOIP_DB_PATH", "")
+	viper.SetDefault("IP_PRIVACY_MODE", "off")
+	viper.SetDefault("IP_HASH_KEY", "")
+	viper.SetDefault("IP_HASH_ROTATION", 24*time.Hour)
+	viper.SetDefault("PII_RETENTION_DAYS", 0)
+	viper.SetDefault("PII_RETENTION_INTERVAL", time.Hour)
+	viper.SetDefault("REFERRER_STRIP_QUERY", true)
+	viper.SetDefault("FRAUD_FLAG_THRESHOLD", 50)
+	viper.SetDefault("FRAUD_IP_CLICKS_PER_MINUTE", 30)
+	viper.SetDefault("FRAUD_LINK_IP_CLICKS_PER_HOUR", 10)
+	viper.SetDefault("FRAUD_MIN_CONVERSION_DELAY", 10*time.Second)
+	viper.SetDefault("FRAUD_ASN_DB_PATH", "")
+	viper.SetDefault("FRAUD_DATACENTER_ASN_FILE", "")
+	viper.SetDefault("ATTRIBUTION_HALF_LIFE", 7*24*time.Hour)
+	viper.SetDefault("CONVERSION_APPROVAL_INTERVAL", time.Hour)
+	viper.SetDefault("COUNT_PENDING_CONVERSIONS", true)
+
 	// Read config file, but don't fail if it's missing
 	err = viper.ReadInConfig()
 	if err != nil {
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidDomain = errors.New("domain must be a bare hostname such as go.example.com")
+
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// NormalizeDomain lowercases a hostname and strips a trailing dot so it can be
+// compared against the Host header of incoming requests.
+func NormalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if len(domain) > 253 || !domainPattern.MatchString(domain) {
+		return "", ErrInvalidDomain
+	}
+	return domain, nil
+}
+
+// DomainVerificationPrefix is prepended to a domain to name the TXT record
+// that proves the brand controls it.
+const DomainVerificationPrefix = "_hanami-verify."
+
+// NewDomainVerificationToken returns the value a brand must publish in its
+// domain's verification TXT record.
+func NewDomainVerificationToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return "hanami-verify=" + hex.EncodeToString(token), nil
+}
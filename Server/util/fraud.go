@@ -0,0 +1,83 @@
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Fraud rules, stored in fraud_flags.reasons.
+const (
+	FraudIPVelocity     = "ip_velocity"
+	FraudLinkVelocity   = "link_velocity"
+	FraudHiddenFrame    = "hidden_frame"
+	FraudDatacenter     = "datacenter_asn"
+	FraudFastConversion = "fast_conversion"
+	FraudIPMismatch     = "ip_mismatch"
+	FraudClickNotFound  = "click_not_found"
+)
+
+// FraudScores is how much each rule adds to an event's score. An event is
+// flagged once its score reaches the configured threshold.
+var FraudScores = map[string]int{
+	FraudIPVelocity:     40,
+	FraudLinkVelocity:   60,
+	FraudHiddenFrame:    80,
+	FraudDatacenter:     50,
+	FraudFastConversion: 60,
+	FraudIPMismatch:     30,
+	FraudClickNotFound:  50,
+}
+
+// LoadASNList reads autonomous system numbers, one per line with optional
+// "AS" prefix and # comments.
+func LoadASNList(path string) (map[uint]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	asns := map[uint]bool{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(entry, '#'); i >= 0 {
+			entry = strings.TrimSpace(entry[:i])
+		}
+		if entry == "" {
+			continue
+		}
+		entry = strings.TrimPrefix(strings.ToUpper(entry), "AS")
+		asn, err := strconv.ParseUint(entry, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		asns[uint(asn)] = true
+	}
+	return asns, scanner.Err()
+}
+
+// SameIPRange reports whether a and b fall in the same /24 (IPv4) or /48
+// (IPv6), the ranges truncated click IPs are stored at. Anything unparseable
+// never matches.
+func SameIPRange(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return false
+	}
+
+	if v4A, v4B := ipA.To4(), ipB.To4(); v4A != nil || v4B != nil {
+		if v4A == nil || v4B == nil {
+			return false
+		}
+		mask := net.CIDRMask(truncateBitsV4, 32)
+		return v4A.Mask(mask).Equal(v4B.Mask(mask))
+	}
+
+	mask := net.CIDRMask(truncateBitsV6, 128)
+	return ipA.Mask(mask).Equal(ipB.Mask(mask))
+}
@@ -0,0 +1,177 @@
+package util
+
+import (
+	"log"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/oschwald/geoip2-golang"
+)
+
+// geoReloadDelay lets a database copy finish before it is reopened; updaters
+// often write the file in several steps.
+const geoReloadDelay = 2 * time.Second
+
+// Location is where an IP address resolves to. Country is the ISO 3166-1
+// alpha-2 code; empty fields mean the database had no answer.
+type Location struct {
+	Country string
+	Region  string
+	City    string
+}
+
+// GeoIP looks up locations, or ASNs, in a MaxMind-format database and reopens
+// it whenever the file changes on disk.
+type GeoIP struct {
+	path    string
+	mu      sync.RWMutex
+	reader  *geoip2.Reader
+	watcher *fsnotify.Watcher
+}
+
+// NewGeoIP opens the database at path. An empty path returns a GeoIP whose
+// lookups always come back empty.
+func NewGeoIP(path string) (*GeoIP, error) {
+	geo := &GeoIP{path: path}
+	if path == "" {
+		return geo, nil
+	}
+
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	geo.reader = reader
+
+	// Watch the directory, not the file, so replacing the file by rename is
+	// noticed too
+	geo.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	if err := geo.watcher.Add(filepath.Dir(path)); err != nil {
+		geo.watcher.Close()
+		reader.Close()
+		return nil, err
+	}
+
+	go geo.watch()
+	return geo, nil
+}
+
+func (geo *GeoIP) watch() {
+	var reload <-chan time.Time
+	for {
+		select {
+		case event, ok := <-geo.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) == filepath.Clean(geo.path) &&
+				event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				reload = time.After(geoReloadDelay)
+			}
+		case err, ok := <-geo.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("GeoIP watcher error: %v", err)
+		case <-reload:
+			reload = nil
+			geo.reload()
+		}
+	}
+}
+
+// reload swaps in the database on disk, keeping the current one if the new
+// file cannot be opened.
+func (geo *GeoIP) reload() {
+	reader, err := geoip2.Open(geo.path)
+	if err != nil {
+		log.Printf("Failed to reload GeoIP database %s, keeping the previous one: %v", geo.path, err)
+		return
+	}
+
+	geo.mu.Lock()
+	previous := geo.reader
+	geo.reader = reader
+	geo.mu.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	log.Printf("Reloaded GeoIP database %s (built %s)", geo.path,
+		time.Unix(int64(reader.Metadata().BuildEpoch), 0).UTC().Format(time.RFC3339))
+}
+
+// Lookup resolves ip, returning an empty Location when it cannot.
+func (geo *GeoIP) Lookup(ip string) Location {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Location{}
+	}
+
+	geo.mu.RLock()
+	defer geo.mu.RUnlock()
+	if geo.reader == nil {
+		return Location{}
+	}
+
+	record, err := geo.reader.City(parsed)
+	if err != nil {
+		return Location{}
+	}
+
+	location := Location{
+		Country: record.Country.IsoCode,
+		City:    record.City.Names["en"],
+	}
+	if len(record.Subdivisions) > 0 {
+		location.Region = record.Subdivisions[0].Names["en"]
+		if location.Region == "" {
+			location.Region = record.Subdivisions[0].IsoCode
+		}
+	}
+	return location
+}
+
+// ASN returns the autonomous system number announcing ip, or 0 when the
+// database is not an ASN database or has no answer.
+func (geo *GeoIP) ASN(ip string) uint {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return 0
+	}
+
+	geo.mu.RLock()
+	defer geo.mu.RUnlock()
+	if geo.reader == nil {
+		return 0
+	}
+
+	record, err := geo.reader.ASN(parsed)
+	if err != nil {
+		return 0
+	}
+	return record.AutonomousSystemNumber
+}
+
+// Close stops watching the file and closes the database.
+func (geo *GeoIP) Close() error {
+	if geo.watcher != nil {
+		geo.watcher.Close()
+	}
+
+	geo.mu.Lock()
+	defer geo.mu.Unlock()
+	if geo.reader == nil {
+		return nil
+	}
+	err := geo.reader.Close()
+	geo.reader = nil
+	return err
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// PostbackKeyPrefix marks brand API keys so they are recognisable in logs
+// and secret scanners.
+const PostbackKeyPrefix = "hk_"
+
+var (
+	ErrPostbackSignature = errors.New("postback signature does not match")
+	ErrPostbackExpired   = errors.New("postback timestamp is outside the allowed window")
+)
+
+// NewPostbackCredentials returns a fresh API key and HMAC signing secret for
+// a brand. Only the key's hash is stored; the secret must be kept to check
+// signatures.
+func NewPostbackCredentials() (apiKey, secret string, err error) {
+	key := make([]byte, 24)
+	if _, err := rand.Read(key); err != nil {
+		return "", "", err
+	}
+	signing := make([]byte, 32)
+	if _, err := rand.Read(signing); err != nil {
+		return "", "", err
+	}
+	return PostbackKeyPrefix + hex.EncodeToString(key), hex.EncodeToString(signing), nil
+}
+
+// HashAPIKey is the form a brand API key is stored and looked up in.
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignPostback signs payload as sent at timestamp (Unix seconds):
+// hex(HMAC-SHA256(secret, timestamp + "." + payload)).
+func SignPostback(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPostback checks signature against payload and rejects timestamps
+// more than tolerance away from now, so a captured request cannot be
+// replayed later.
+func VerifyPostback(secret, timestamp string, payload []byte, signature string, now time.Time, tolerance time.Duration) error {
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrPostbackExpired
+	}
+	if skew := now.Sub(time.Unix(sent, 0)); skew > tolerance || skew < -tolerance {
+		return ErrPostbackExpired
+	}
+
+	expected := SignPostback(secret, timestamp, payload)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrPostbackSignature
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// IP privacy modes for clicks.user_ip.
+const (
+	// IPPrivacyOff stores the address as received.
+	IPPrivacyOff = "off"
+	// IPPrivacyTruncate zeroes the host part: IPv4 to /24, IPv6 to /48.
+	IPPrivacyTruncate = "truncate"
+	// IPPrivacyHash stores a keyed hash whose salt changes every rotation
+	// period, so the same visitor cannot be linked across periods.
+	IPPrivacyHash = "hash"
+)
+
+const (
+	truncateBitsV4 = 24
+	truncateBitsV6 = 48
+)
+
+// IPAnonymizer turns a client IP into the form that may be stored. It runs
+// after bot checks, dedup and GeoIP, which all need the full address.
+type IPAnonymizer struct {
+	mode     string
+	key      []byte
+	rotation time.Duration
+}
+
+// NewIPAnonymizer validates mode. Hash mode needs a secret key and a rotation
+// period of at least a second.
+func NewIPAnonymizer(mode, key string, rotation time.Duration) (*IPAnonymizer, error) {
+	switch mode {
+	case IPPrivacyOff, IPPrivacyTruncate:
+	case IPPrivacyHash:
+		if key == "" {
+			return nil, errors.New("IP hash mode needs IP_HASH_KEY")
+		}
+		if rotation < time.Second {
+			return nil, errors.New("IP hash salt rotation must be at least a second")
+		}
+	default:
+		return nil, fmt.Errorf("unknown IP privacy mode %q", mode)
+	}
+	return &IPAnonymizer{mode: mode, key: []byte(key), rotation: rotation}, nil
+}
+
+// Mode returns the configured privacy mode.
+func (anonymizer *IPAnonymizer) Mode() string {
+	return anonymizer.mode
+}
+
+// Anonymize returns the storable form of ip for a click made at at.
+// Unparseable input is dropped rather than stored verbatim.
+func (anonymizer *IPAnonymizer) Anonymize(ip string, at time.Time) string {
+	if anonymizer.mode == IPPrivacyOff {
+		return ip
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if anonymizer.mode == IPPrivacyHash {
+		mac := hmac.New(sha256.New, anonymizer.salt(at))
+		mac.Write(parsed.To16())
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(truncateBitsV4, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(truncateBitsV6, 128)).String()
+}
+
+// salt derives the period's salt from the key, so replicas agree on it without
+// sharing state.
+func (anonymizer *IPAnonymizer) salt(at time.Time) []byte {
+	period := at.Unix() / int64(anonymizer.rotation/time.Second)
+	mac := hmac.New(sha256.New, anonymizer.key)
+	mac.Write([]byte("ip-salt:" + strconv.FormatInt(period, 10)))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,268 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	MinQRSize     = 64
+	MaxQRSize     = 2048
+	DefaultQRSize = 512
+	MaxQRMargin   = 16
+	// DefaultQRMargin is the 4-module quiet zone the QR spec asks for.
+	DefaultQRMargin = 4
+
+	// A centered logo covers at most this share of the symbol's width, which
+	// the highest recovery level can absorb.
+	qrLogoRatio = 0.22
+
+	// MaxLogoDimension bounds an uploaded logo's width and height, so a
+	// small but highly compressed file cannot decode into gigabytes.
+	MaxLogoDimension = 4096
+	// qrLogoStoredSize is the largest side a logo is stored at. The biggest
+	// QR leaves a logo about MaxQRSize*qrLogoRatio pixels across.
+	qrLogoStoredSize = 512
+)
+
+var ErrLogoTooLarge = fmt.Errorf("logo must be at most %dx%d pixels", MaxLogoDimension, MaxLogoDimension)
+
+type QROptions struct {
+	Size   int
+	Level  qrcode.RecoveryLevel
+	Margin int
+	// Logo is an optional PNG or JPEG drawn over the center of the code.
+	Logo            []byte
+	LogoContentType string
+}
+
+// ParseQRLevel maps the L/M/Q/H error-correction letters to recovery levels.
+func ParseQRLevel(level string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return qrcode.Low, nil
+	case "", "M":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	}
+	return qrcode.Medium, errors.New("error correction level must be one of L, M, Q, H")
+}
+
+func (opts QROptions) validate() error {
+	if opts.Size < MinQRSize || opts.Size > MaxQRSize {
+		return fmt.Errorf("size must be between %d and %d", MinQRSize, MaxQRSize)
+	}
+	if opts.Margin < 0 || opts.Margin > MaxQRMargin {
+		return fmt.Errorf("margin must be between 0 and %d", MaxQRMargin)
+	}
+	return nil
+}
+
+// qrModules encodes content and returns the module grid without a quiet zone.
+func qrModules(content string, opts QROptions) ([][]bool, error) {
+	level := opts.Level
+	if len(opts.Logo) > 0 {
+		level = qrcode.Highest
+	}
+
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+	return code.Bitmap(), nil
+}
+
+// QRCodePNG renders content as a square PNG of opts.Size pixels, or slightly
+// larger when the code needs more than one pixel per module.
+func QRCodePNG(content string, opts QROptions) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	modules, err := qrModules(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(modules) + 2*opts.Margin
+	scale := opts.Size / total
+	if scale < 1 {
+		scale = 1
+	}
+	size := opts.Size
+	if total*scale > size {
+		size = total * scale
+	}
+	offset := (size-total*scale)/2 + opts.Margin*scale
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			cell := image.Rect(offset+x*scale, offset+y*scale, offset+(x+1)*scale, offset+(y+1)*scale)
+			draw.Draw(img, cell, image.Black, image.Point{}, draw.Src)
+		}
+	}
+
+	if len(opts.Logo) > 0 {
+		logo, err := decodeLogo(opts.Logo)
+		if err != nil {
+			return nil, err
+		}
+		drawCenteredLogo(img, logo, int(float64(len(modules)*scale)*qrLogoRatio))
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeLogo decodes a PNG or JPEG logo, refusing one whose dimensions
+// exceed MaxLogoDimension before any pixels are allocated.
+func decodeLogo(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode logo: %w", err)
+	}
+	if config.Width > MaxLogoDimension || config.Height > MaxLogoDimension {
+		return nil, ErrLogoTooLarge
+	}
+
+	logo, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode logo: %w", err)
+	}
+	return logo, nil
+}
+
+// PrepareQRLogo turns an uploaded PNG or JPEG into the PNG stored for QR
+// rendering, shrunk to fit qrLogoStoredSize so renders decode a small image.
+func PrepareQRLogo(data []byte) ([]byte, error) {
+	logo, err := decodeLogo(data)
+	if err != nil {
+		return nil, err
+	}
+
+	src := logo.Bounds()
+	width, height := src.Dx(), src.Dy()
+	if width > qrLogoStoredSize || height > qrLogoStoredSize {
+		if width >= height {
+			width, height = qrLogoStoredSize, max(1, height*qrLogoStoredSize/width)
+		} else {
+			width, height = max(1, width*qrLogoStoredSize/height), qrLogoStoredSize
+		}
+	}
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			scaled.Set(x, y, logo.At(src.Min.X+x*src.Dx()/width, src.Min.Y+y*src.Dy()/height))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaled); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawCenteredLogo scales logo to fit a box of width px on a white pad in the
+// middle of img.
+func drawCenteredLogo(img *image.RGBA, logo image.Image, width int) {
+	src := logo.Bounds()
+	if width < 1 || src.Dx() == 0 || src.Dy() == 0 {
+		return
+	}
+	height := width * src.Dy() / src.Dx()
+	if height > width {
+		height = width
+		width = height * src.Dx() / src.Dy()
+	}
+
+	center := img.Bounds().Dx() / 2
+	pad := width / 10
+	dst := image.Rect(center-width/2, center-height/2, center-width/2+width, center-height/2+height)
+	draw.Draw(img, dst.Inset(-pad), image.White, image.Point{}, draw.Src)
+
+	// Nearest-neighbour is plenty for a logo a few dozen pixels across
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := logo.At(src.Min.X+x*src.Dx()/width, src.Min.Y+y*src.Dy()/height)
+			img.Set(dst.Min.X+x, dst.Min.Y+y, blendOnWhite(c))
+		}
+	}
+}
+
+func blendOnWhite(c color.Color) color.Color {
+	r, g, b, a := c.RGBA()
+	blend := func(v uint32) uint8 {
+		return uint8((v + (0xffff - a)) >> 8)
+	}
+	return color.RGBA{R: blend(r), G: blend(g), B: blend(b), A: 0xff}
+}
+
+// QRCodeSVG renders content as a scalable SVG whose viewBox is measured in
+// modules and whose width/height are opts.Size.
+func QRCodeSVG(content string, opts QROptions) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	modules, err := qrModules(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	total := len(modules) + 2*opts.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, total, total)
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// Merge horizontal runs of dark modules into one rectangle
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", x+opts.Margin, y+opts.Margin, run, run)
+			x += run - 1
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if len(opts.Logo) > 0 {
+		width := float64(len(modules)) * qrLogoRatio
+		origin := (float64(total) - width) / 2
+		pad := width / 10
+		fmt.Fprintf(&buf, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="#fff"/>`, origin-pad, origin-pad, width+2*pad, width+2*pad)
+		fmt.Fprintf(&buf, `<image x="%.2f" y="%.2f" width="%.2f" height="%.2f" preserveAspectRatio="xMidYMid meet" href="data:%s;base64,%s"/>`,
+			origin, origin, width, width, opts.LogoContentType, base64.StdEncoding.EncodeToString(opts.Logo))
+	}
+
+	buf.WriteString(`</svg>`)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,160 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrLandingURL       = errors.New("landing URL must be an absolute http or https URL")
+	ErrRedirectTemplate = errors.New("redirect template must be an absolute http or https URL")
+)
+
+// RedirectMacros are the placeholders a campaign redirect template may use,
+// written as {name}.
+var RedirectMacros = map[string]bool{
+	"click_id":      true,
+	"tracking_code": true,
+	"affiliate_id":  true,
+	"campaign_id":   true,
+	"label":         true,
+	"landing_url":   true,
+	"utm_source":    true,
+	"utm_medium":    true,
+	"utm_campaign":  true,
+	"sub1":          true,
+	"sub2":          true,
+	"sub3":          true,
+	"sub4":          true,
+	"sub5":          true,
+}
+
+// Parameters Hanami sets itself, which an incoming request can never override.
+var reservedRedirectParams = map[string]bool{
+	"click_id":      true,
+	"tracking_code": true,
+}
+
+var (
+	macroPattern     = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+	schemePattern    = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.\-]*://`)
+	paramNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-\[\]]{1,64}$`)
+)
+
+type RedirectTarget struct {
+	LandingURL string
+	// Template, when set, replaces LandingURL as the outgoing URL after its
+	// macros are expanded.
+	Template string
+	// Passthrough names incoming parameters, beyond utm_*, that are copied
+	// onto the outgoing URL.
+	Passthrough []string
+	Incoming    url.Values
+	// Params are set on the outgoing URL last and win over everything else.
+	// Empty values are skipped.
+	Params map[string]string
+	// Macros supply template values. Unset macros expand to "".
+	Macros map[string]string
+}
+
+// BuildRedirectURL merges the incoming and Hanami parameters into the
+// destination's own query string, keeping its fragment and scheme.
+func BuildRedirectURL(target RedirectTarget) (string, error) {
+	destination := target.LandingURL
+	if target.Template != "" {
+		destination = ExpandRedirectTemplate(target.Template, target.Macros)
+	}
+
+	u, err := parseDestination(destination)
+	if err != nil {
+		return "", err
+	}
+
+	passthrough := make(map[string]bool, len(target.Passthrough))
+	for _, name := range target.Passthrough {
+		passthrough[name] = true
+	}
+
+	query := u.Query()
+	for name, values := range target.Incoming {
+		if reservedRedirectParams[name] {
+			continue
+		}
+		if strings.HasPrefix(name, "utm_") || passthrough[name] {
+			query[name] = values
+		}
+	}
+	for name, value := range target.Params {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// ExpandRedirectTemplate replaces each {macro} in template with its
+// query-escaped value. Unknown placeholders are left as written.
+func ExpandRedirectTemplate(template string, macros map[string]string) string {
+	return macroPattern.ReplaceAllStringFunc(template, func(match string) string {
+		name := match[1 : len(match)-1]
+		if !RedirectMacros[name] {
+			return match
+		}
+		return url.QueryEscape(macros[name])
+	})
+}
+
+// ValidateRedirectTemplate rejects templates with unknown macros or that do not
+// expand to an absolute http(s) URL.
+func ValidateRedirectTemplate(template string) error {
+	for _, match := range macroPattern.FindAllStringSubmatch(template, -1) {
+		if !RedirectMacros[match[1]] {
+			return fmt.Errorf("unknown redirect macro {%s}", match[1])
+		}
+	}
+
+	u, err := url.Parse(ExpandRedirectTemplate(template, nil))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrRedirectTemplate
+	}
+	return nil
+}
+
+// ValidatePassthroughParams checks campaign-configured parameter names.
+func ValidatePassthroughParams(names []string) error {
+	for _, name := range names {
+		if !paramNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid passthrough parameter %q", name)
+		}
+		if reservedRedirectParams[name] {
+			return fmt.Errorf("%s is set by Hanami and cannot be passed through", name)
+		}
+	}
+	return nil
+}
+
+// parseDestination accepts a landing URL with or without a scheme. A missing
+// scheme defaults to https, but an explicit http is kept as is.
+func parseDestination(destination string) (*url.URL, error) {
+	destination = strings.TrimSpace(destination)
+	if !schemePattern.MatchString(destination) {
+		destination = "https://" + destination
+	}
+
+	u, err := url.Parse(destination)
+	if err != nil {
+		return nil, ErrLandingURL
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrLandingURL
+	}
+	if u.Hostname() == "" || strings.Contains(u.Hostname(), "..") {
+		return nil, ErrLandingURL
+	}
+	return u, nil
+}
@@ -0,0 +1,188 @@
+package util
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestBuildRedirectURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  RedirectTarget
+		want    string
+		wantErr error
+	}{
+		{
+			name: "landing URL with a query string",
+			target: RedirectTarget{
+				LandingURL: "https://shop.example/p?ref=home",
+				Params:     map[string]string{"click_id": "abc"},
+			},
+			want: "https://shop.example/p?click_id=abc&ref=home",
+		},
+		{
+			name: "landing URL with a fragment",
+			target: RedirectTarget{
+				LandingURL: "https://shop.example/p#reviews",
+				Params:     map[string]string{"click_id": "abc"},
+			},
+			want: "https://shop.example/p?click_id=abc#reviews",
+		},
+		{
+			name: "landing URL with a query string and a fragment",
+			target: RedirectTarget{
+				LandingURL: "https://shop.example/p?a=1#top",
+				Params:     map[string]string{"click_id": "abc"},
+			},
+			want: "https://shop.example/p?a=1&click_id=abc#top",
+		},
+		{
+			name: "http landing URL is preserved",
+			target: RedirectTarget{
+				LandingURL: "http://shop.example/p",
+				Params:     map[string]string{"click_id": "abc"},
+			},
+			want: "http://shop.example/p?click_id=abc",
+		},
+		{
+			name: "landing URL without a scheme defaults to https",
+			target: RedirectTarget{
+				LandingURL: "shop.example/p",
+				Params:     map[string]string{"click_id": "abc"},
+			},
+			want: "https://shop.example/p?click_id=abc",
+		},
+		{
+			name: "only utm and passthrough params are copied",
+			target: RedirectTarget{
+				LandingURL:  "https://shop.example/",
+				Passthrough: []string{"gclid"},
+				Incoming:    url.Values{"utm_source": {"fb"}, "gclid": {"g1"}, "foo": {"bar"}},
+			},
+			want: "https://shop.example/?gclid=g1&utm_source=fb",
+		},
+		{
+			name: "incoming reserved params are dropped even when passed through",
+			target: RedirectTarget{
+				LandingURL:  "https://shop.example/",
+				Passthrough: []string{"click_id", "tracking_code"},
+				Incoming:    url.Values{"click_id": {"forged"}, "tracking_code": {"forged"}},
+				Params:      map[string]string{"click_id": "abc", "tracking_code": ""},
+			},
+			want: "https://shop.example/?click_id=abc",
+		},
+		{
+			name: "Hanami params win over incoming utm params",
+			target: RedirectTarget{
+				LandingURL: "https://shop.example/",
+				Incoming:   url.Values{"utm_source": {"fb"}, "utm_medium": {"cpc"}},
+				Params:     map[string]string{"utm_source": "newsletter", "utm_medium": ""},
+			},
+			want: "https://shop.example/?utm_medium=cpc&utm_source=newsletter",
+		},
+		{
+			name: "Hanami params override the landing URL's own",
+			target: RedirectTarget{
+				LandingURL: "https://shop.example/?click_id=old&utm_campaign=spring",
+				Params:     map[string]string{"click_id": "abc", "utm_campaign": "summer"},
+			},
+			want: "https://shop.example/?click_id=abc&utm_campaign=summer",
+		},
+		{
+			name: "template replaces the landing URL",
+			target: RedirectTarget{
+				LandingURL: "https://shop.example/",
+				Template:   "https://track.example/{campaign_id}?lp={landing_url}",
+				Macros:     map[string]string{"campaign_id": "7", "landing_url": "https://shop.example/"},
+				Params:     map[string]string{"click_id": "abc"},
+			},
+			want: "https://track.example/7?click_id=abc&lp=https%3A%2F%2Fshop.example%2F",
+		},
+		{
+			name:    "unsupported scheme",
+			target:  RedirectTarget{LandingURL: "ftp://shop.example/"},
+			wantErr: ErrLandingURL,
+		},
+		{
+			name:    "empty landing URL",
+			target:  RedirectTarget{LandingURL: ""},
+			wantErr: ErrLandingURL,
+		},
+		{
+			name:    "malformed host",
+			target:  RedirectTarget{LandingURL: "https://shop..example/"},
+			wantErr: ErrLandingURL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildRedirectURL(tt.target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BuildRedirectURL() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BuildRedirectURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandRedirectTemplate(t *testing.T) {
+	macros := map[string]string{
+		"click_id":   "abc",
+		"utm_source": "a b&c",
+		"label":      "",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"known macro", "https://t.example/?c={click_id}", "https://t.example/?c=abc"},
+		{"value is query-escaped", "https://t.example/?s={utm_source}", "https://t.example/?s=a+b%26c"},
+		{"empty macro", "https://t.example/?l={label}", "https://t.example/?l="},
+		{"unset macro", "https://t.example/?s={sub1}", "https://t.example/?s="},
+		{"unknown macro is left as written", "https://t.example/?x={nope}", "https://t.example/?x={nope}"},
+		{"macro names are case sensitive", "https://t.example/?c={CLICK_ID}", "https://t.example/?c={CLICK_ID}"},
+		{"empty braces", "https://t.example/?c={}", "https://t.example/?c={}"},
+		{"repeated macro", "https://t.example/{click_id}?c={click_id}", "https://t.example/abc?c=abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandRedirectTemplate(tt.template, macros); got != tt.want {
+				t.Errorf("ExpandRedirectTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRedirectTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"valid", "https://t.example/?c={click_id}&lp={landing_url}", false},
+		{"http is allowed", "http://t.example/{campaign_id}", false},
+		{"no macros", "https://t.example/", false},
+		{"unknown macro", "https://t.example/?x={nope}", true},
+		{"empty", "", true},
+		{"relative", "/go?c={click_id}", true},
+		{"unsupported scheme", "ftp://t.example/{click_id}", true},
+		{"host only from a macro", "https://{sub1}/", true},
+		{"landing URL macro alone", "{landing_url}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRedirectTemplate(tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRedirectTemplate(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
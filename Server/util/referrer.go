@@ -0,0 +1,143 @@
+package util
+
+import (
+	"net/url"
+	"strings"
+)
+
+// MaxReferrerLength caps what is stored from the Referer header.
+const MaxReferrerLength = 2048
+
+// Referrer platforms for traffic that is not from a known site.
+const (
+	ReferrerDirect  = "direct"
+	ReferrerOther   = "other"
+	ReferrerInvalid = "invalid"
+)
+
+// Referrer is a click's Referer header cleaned up for storage.
+type Referrer struct {
+	URL      string
+	Host     string
+	Platform string
+}
+
+// referrerPlatforms maps site domains, subdomains included, to a platform.
+var referrerPlatforms = map[string]string{
+	"instagram.com":    "instagram",
+	"facebook.com":     "facebook",
+	"fb.com":           "facebook",
+	"fb.me":            "facebook",
+	"messenger.com":    "messenger",
+	"youtube.com":      "youtube",
+	"youtu.be":         "youtube",
+	"whatsapp.com":     "whatsapp",
+	"wa.me":            "whatsapp",
+	"t.co":             "twitter",
+	"twitter.com":      "twitter",
+	"x.com":            "twitter",
+	"tiktok.com":       "tiktok",
+	"threads.net":      "threads",
+	"linkedin.com":     "linkedin",
+	"lnkd.in":          "linkedin",
+	"reddit.com":       "reddit",
+	"pinterest.com":    "pinterest",
+	"pin.it":           "pinterest",
+	"snapchat.com":     "snapchat",
+	"t.me":             "telegram",
+	"telegram.org":     "telegram",
+	"discord.com":      "discord",
+	"discord.gg":       "discord",
+	"twitch.tv":        "twitch",
+	"bing.com":         "bing",
+	"duckduckgo.com":   "duckduckgo",
+	"yahoo.com":        "yahoo",
+	"mail.google.com":  "gmail",
+	"outlook.live.com": "outlook",
+}
+
+// Android apps send android-app://<package> as their referrer.
+var referrerApps = map[string]string{
+	"com.instagram.android":                   "instagram",
+	"com.facebook.katana":                     "facebook",
+	"com.facebook.orca":                       "messenger",
+	"com.google.android.youtube":              "youtube",
+	"com.whatsapp":                            "whatsapp",
+	"com.twitter.android":                     "twitter",
+	"com.zhiliaoapp.musically":                "tiktok",
+	"com.linkedin.android":                    "linkedin",
+	"com.reddit.frontpage":                    "reddit",
+	"com.pinterest":                           "pinterest",
+	"com.snapchat.android":                    "snapchat",
+	"org.telegram.messenger":                  "telegram",
+	"com.google.android.gm":                   "gmail",
+	"com.google.android.googlequicksearchbox": "google",
+}
+
+// ParseReferrer cleans raw for storage: credentials and fragment are always
+// dropped, the query too when stripQuery is set. A header that does not parse
+// is kept, truncated, under the invalid platform.
+func ParseReferrer(raw string, stripQuery bool) Referrer {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Referrer{Platform: ReferrerDirect}
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return Referrer{URL: truncateReferrer(raw), Platform: ReferrerInvalid}
+	}
+
+	parsed.User = nil
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	if stripQuery {
+		parsed.RawQuery = ""
+		parsed.ForceQuery = false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	return Referrer{
+		URL:      truncateReferrer(parsed.String()),
+		Host:     host,
+		Platform: referrerPlatform(parsed.Scheme, host),
+	}
+}
+
+func referrerPlatform(scheme, host string) string {
+	if scheme == "android-app" {
+		if platform, ok := referrerApps[host]; ok {
+			return platform
+		}
+		return ReferrerOther
+	}
+
+	// Walk up the domain so l.instagram.com and m.youtube.com match too
+	for domain := host; domain != ""; {
+		if platform, ok := referrerPlatforms[domain]; ok {
+			return platform
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+
+	// Google search runs on a domain per country
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if label == "google" && i < len(labels)-1 {
+			return "google"
+		}
+	}
+
+	return ReferrerOther
+}
+
+func truncateReferrer(s string) string {
+	if len(s) > MaxReferrerLength {
+		return s[:MaxReferrerLength]
+	}
+	return s
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrSlugFormat   = errors.New("vanity slug must be 4-32 characters of a-z, 0-9 and single hyphens, starting and ending with a letter or digit")
+	ErrSlugNumeric  = errors.New("vanity slug must contain at least one letter")
+	ErrSlugReserved = errors.New("vanity slug is reserved")
+	ErrSlugBlocked  = errors.New("vanity slug contains a blocked word")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// reservedSlugs collide with routes on the short-link host or read as
+// something Hanami itself would publish.
+var reservedSlugs = map[string]bool{
+	"admin": true, "assets": true, "brand": true, "campaign": true,
+	"dashboard": true, "favicon": true, "hanami": true, "health": true,
+	"help": true, "login": true, "logout": true, "metrics": true,
+	"pixel": true, "postback": true, "signup": true, "static": true,
+	"support": true, "tracking": true,
+}
+
+var blockedWords = []string{
+	"bitch", "cunt", "fuck", "nazi", "nigg", "penis",
+	"porn", "pussy", "shit", "slut", "whore",
+}
+
+// NormalizeVanitySlug lowercases and trims a requested slug and checks it
+// against the format, reserved-word and blocked-word rules.
+func NormalizeVanitySlug(slug string) (string, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+
+	if len(slug) < 4 || len(slug) > 32 || !slugPattern.MatchString(slug) {
+		return "", ErrSlugFormat
+	}
+
+	if strings.Trim(slug, "0123456789-") == "" {
+		return "", ErrSlugNumeric
+	}
+
+	if reservedSlugs[slug] {
+		return "", ErrSlugReserved
+	}
+
+	compact := strings.ReplaceAll(slug, "-", "")
+	for _, word := range blockedWords {
+		if strings.Contains(compact, word) {
+			return "", ErrSlugBlocked
+		}
+	}
+
+	return slug, nil
+}
@@ -0,0 +1,47 @@
+package util
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeVanitySlug(t *testing.T) {
+	tests := []struct {
+		name    string
+		slug    string
+		want    string
+		wantErr error
+	}{
+		{"valid", "summer-sale", "summer-sale", nil},
+		{"uppercase is lowered", "Summer-Sale", "summer-sale", nil},
+		{"surrounding space is trimmed", "  promo24 ", "promo24", nil},
+		{"digits with a letter", "2024x", "2024x", nil},
+		{"too short", "abc", "", ErrSlugFormat},
+		{"too long", "abcdefghijklmnopqrstuvwxyz0123456", "", ErrSlugFormat},
+		{"underscore", "summer_sale", "", ErrSlugFormat},
+		{"inner space", "summer sale", "", ErrSlugFormat},
+		{"slash", "summer/sale", "", ErrSlugFormat},
+		{"non-ascii letter", "café-deal", "", ErrSlugFormat},
+		{"leading hyphen", "-summer", "", ErrSlugFormat},
+		{"trailing hyphen", "summer-", "", ErrSlugFormat},
+		{"double hyphen", "summer--sale", "", ErrSlugFormat},
+		{"digits only", "2024", "", ErrSlugNumeric},
+		{"digits and hyphens only", "20-24", "", ErrSlugNumeric},
+		{"reserved", "admin", "", ErrSlugReserved},
+		{"reserved in another case", "Login", "", ErrSlugReserved},
+		{"blocked word", "shitdeal", "", ErrSlugBlocked},
+		{"blocked word split by hyphens", "sh-it-deal", "", ErrSlugBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeVanitySlug(tt.slug)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NormalizeVanitySlug(%q) error = %v, want %v", tt.slug, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeVanitySlug(%q) = %q, want %q", tt.slug, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/mssola/useragent"
+)
+
+// Device classes stored on clicks.device_type.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// Device is what a click's user agent says about the visitor's hardware and
+// software. Empty fields mean the parser could not tell.
+type Device struct {
+	Type           string
+	OS             string
+	Browser        string
+	BrowserVersion string
+}
+
+// ParseUserAgent classifies userAgent into a device class, OS and browser.
+func ParseUserAgent(userAgent string) Device {
+	if strings.TrimSpace(userAgent) == "" {
+		return Device{Type: DeviceUnknown}
+	}
+
+	ua := useragent.New(userAgent)
+	browser, version := ua.Browser()
+	return Device{
+		Type:           deviceType(ua, userAgent),
+		OS:             osName(ua),
+		Browser:        browser,
+		BrowserVersion: version,
+	}
+}
+
+func deviceType(ua *useragent.UserAgent, raw string) string {
+	lower := strings.ToLower(raw)
+	switch {
+	case ua.Bot():
+		return DeviceBot
+	// Android tablets leave "Mobile" out of their user agent
+	case strings.Contains(lower, "ipad") || strings.Contains(lower, "tablet") ||
+		(strings.Contains(lower, "android") && !strings.Contains(lower, "mobile")):
+		return DeviceTablet
+	case ua.Mobile():
+		return DeviceMobile
+	case ua.OS() == "":
+		return DeviceUnknown
+	}
+	return DeviceDesktop
+}
+
+// osName folds the parser's OS names into the ones dashboards show.
+func osName(ua *useragent.UserAgent) string {
+	name := ua.OSInfo().Name
+	switch {
+	case ua.Platform() == "iPhone" || ua.Platform() == "iPad" || ua.Platform() == "iPod":
+		return "iOS"
+	case strings.HasPrefix(name, "Mac OS"):
+		return "macOS"
+	case strings.HasPrefix(name, "Windows"):
+		return "Windows"
+	}
+	return name
+}
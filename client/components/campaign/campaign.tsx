@@ -129,6 +129,27 @@ export default function CampaignPage() {
           </div>
         </motion.section>
 
+        <motion.section
+          variants={sectionVariants}
+          initial="hidden"
+          animate="visible"
+          className="bg-white w-full rounded-lg shadow-lg p-6  gap-6"
+        >
+          <div className="flex  gap-10 items-center w-full">
+            <motion.h2
+              variants={textVariants}
+              className="flex justify-center gap-6 items-center text-2xl  mt-2 font-semibold text-gray-700 mb-2"
+            >
+              Lookback Window <ArrowRight />
+            </motion.h2>
+
+            <motion.h3 variants={textVariants} className=" text-lg break-all">
+              Clicks earn commission on conversions within{" "}
+              {campaign.LookbackDays} days
+            </motion.h3>
+          </div>
+        </motion.section>
+
         <motion.section
           variants={sectionVariants}
           initial="hidden"
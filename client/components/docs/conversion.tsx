@@ -30,7 +30,7 @@ interface Tracker {
 
 // Interface for the conversion request
 interface ConversionRequest {
-  session_id: string;
+  order_id?: string;
   trackers: Tracker[];
   amount: number;
   currency: 'USD' | 'INR' | 'EUR' | 'GBP' | 'JPY' | 'CAD' | 'AUD';
@@ -59,7 +59,7 @@ async function createConversion(request: ConversionRequest): Promise<void> {
 
 // Example usage
 const conversionRequest: ConversionRequest = {
-  session_id: '9b4b219d-c669-4d20-b14a-e2d0de36d007',
+  order_id: 'ORD-10045',
   trackers: [
     {
       click_id: '323dbb5f-49ac-4415-afd3-4ad841fa97c6',
@@ -111,7 +111,7 @@ async function createConversion(request) {
 
 // Example usage
 const conversionRequest = {
-  session_id: '9b4b219d-c669-4d20-b14a-e2d0de36d007',
+  order_id: 'ORD-10045',
   trackers: [
     {
       click_id: '323dbb5f-49ac-4415-afd3-4ad841fa97c6',
@@ -161,7 +161,7 @@ def create_conversion(request):
 
 # Example usage
 conversion_request = {
-    'session_id': '9b4b219d-c669-4d20-b14a-e2d0de36d007',
+    'order_id': 'ORD-10045',
     'trackers': [
         {
             'click_id': '323dbb5f-49ac-4415-afd3-4ad841fa97c6',
@@ -209,10 +209,10 @@ type Tracker struct {
 
 // ConversionRequest represents the request body
 type ConversionRequest struct {
-    SessionID string    \`json:"session_id"\`
-    Trackers  []Tracker \`json:"trackers"\`
-    Amount    float64   \`json:"amount"\`
-    Currency  string    \`json:"currency"\`
+    OrderID  string    \`json:"order_id"\`
+    Trackers []Tracker \`json:"trackers"\`
+    Amount   float64   \`json:"amount"\`
+    Currency string    \`json:"currency"\`
 }
 
 // CreateConversion makes the API call to create a conversion
@@ -260,7 +260,7 @@ func CreateConversion(request ConversionRequest) error {
 
 func main() {
     conversionRequest := ConversionRequest{
-        SessionID: "9b4b219d-c669-4d20-b14a-e2d0de36d007",
+        OrderID: "ORD-10045",
         Trackers: []Tracker{
             {
                 ClickID:      "323dbb5f-49ac-4415-afd3-4ad841fa97c6",
@@ -341,7 +341,7 @@ func main() {
         </h2>
         <pre className="bg-gray-800 text-white p-4 rounded-lg overflow-x-auto">
           {`{
-  "session_id": "string", // UUID of the session
+  "order_id": "string", // Optional order reference, unique per brand
   "trackers": [
     {
       "click_id": "string", // UUID of the click
@@ -352,7 +352,8 @@ func main() {
     }
   ],
   "amount": number, // Conversion amount
-  "currency": "string" // ISO 4217 currency code
+  "currency": "string", // ISO 4217 currency code
+  "event": "string" // Optional event name, e.g. "purchase"
 }`}
         </pre>
         <h3 className="text-xl font-medium text-gray-700 mt-4 mb-2">
@@ -370,13 +371,15 @@ func main() {
           </thead>
           <tbody>
             <tr>
-              <td className="p-2 border">session_id</td>
+              <td className="p-2 border">order_id</td>
               <td className="p-2 border">string</td>
               <td className="p-2 border">
-                Unique identifier for the user session (UUID format).
+                Your reference for the order. Retrying with the same order_id
+                returns the original conversions instead of recording the sale
+                again; without one, every request records a new sale.
               </td>
-              <td className="p-2 border">Yes</td>
-              <td className="p-2 border">Must be a valid UUID.</td>
+              <td className="p-2 border">No, but recommended</td>
+              <td className="p-2 border">At most 255 characters.</td>
             </tr>
             <tr>
               <td className="p-2 border">trackers</td>
@@ -407,6 +410,15 @@ func main() {
                 Must be one of: USD, INR, EUR, GBP, JPY, CAD, AUD.
               </td>
             </tr>
+            <tr>
+              <td className="p-2 border">event</td>
+              <td className="p-2 border">string</td>
+              <td className="p-2 border">
+                What converted, such as purchase or signup.
+              </td>
+              <td className="p-2 border">No</td>
+              <td className="p-2 border">At most 64 characters.</td>
+            </tr>
           </tbody>
         </table>
       </section>
@@ -489,12 +501,19 @@ func main() {
     {
       "id": 1,
       "click_id": "323dbb5f-49ac-4415-afd3-4ad841fa97c6",
-      "session_id": "9b4b219d-c669-4d20-b14a-e2d0de36d007",
+      "sale_id": 1,
       "amount": 100.00,
       "currency": "USD",
       "weight": 0.4,
       "timestamp": "2025-03-27T15:55:00Z"
     }
+  ],
+  "trackers": [
+    {
+      "click_id": "323dbb5f-49ac-4415-afd3-4ad841fa97c6",
+      "tracking_code": "105802",
+      "status": "accepted" // or duplicate, unknown_click, tracking_code_mismatch, foreign_brand, outside_lookback; not_credited on a replayed order
+    }
   ]
 }`}
         </pre>
@@ -513,24 +532,181 @@ func main() {
           Attribution Model
         </h2>
         <p className="text-gray-600">
-          The endpoint uses the <strong>U-Shaped (Position-Based)</strong> model
-          to assign weights:
+          Credit is split between the accepted trackers, oldest click first,
+          using the campaign&apos;s attribution model, or the brand&apos;s when
+          the campaign has none. Weights always add up to 1, and each
+          conversion records the model that produced it.
         </p>
         <ul className="list-disc pl-5 text-gray-600">
           <li>
-            1 tracker: 100% (<code>1.0</code>).
+            <code>first_touch</code> / <code>last_touch</code>: 100% to the
+            first or last click.
           </li>
           <li>
-            2 trackers: 40% first, 40% last (<code>[0.4, 0.4]</code>).
+            <code>linear</code>: equal shares.
           </li>
           <li>
-            3+ trackers: 40% first, 40% last, 20% distributed equally among
-            middle trackers (e.g., 4 trackers: <code>[0.4, 0.1, 0.1, 0.4]</code>
-            ).
+            <code>u_shaped</code> (default): 40% first, 40% last, 20% split
+            among the middle clicks; two clicks get 50% each.
+          </li>
+          <li>
+            <code>w_shaped</code>: 30% each to the first, middle and last
+            click, 10% split among the rest.
+          </li>
+          <li>
+            <code>time_decay</code>: a click&apos;s share halves for every
+            half-life between it and the conversion (7 days unless configured).
           </li>
         </ul>
       </section>
 
+      <section className="mb-8">
+        <h2 className="text-2xl font-semibold text-gray-700 mb-4">
+          Server-to-Server Postbacks
+        </h2>
+        <p className="text-gray-600 mb-4">
+          When checkout happens in an app or on your server, report the sale
+          with <code>GET</code> or <code>POST /api/postback</code> instead.
+          Pass the <code>click_id</code> your landing page received along with{" "}
+          <code>order_id</code>, <code>amount</code>, <code>currency</code> and
+          an optional <code>event</code>, as query parameters, a form or JSON.
+          The conversion is attributed exactly like one sent by the SDK.
+        </p>
+        <p className="text-gray-600 mb-4">
+          Create credentials with <code>POST /api/brand/:id/postback/key</code>,
+          signed in as the brand; the API key and signing secret are only shown
+          once. Authenticate with either:
+        </p>
+        <ul className="list-disc pl-5 text-gray-600">
+          <li>
+            the API key in the <code>X-Api-Key</code> header. Keys sent in the
+            URL are refused, since URLs end up in logs, or
+          </li>
+          <li>
+            a signature in <code>X-Hanami-Signature</code> (or{" "}
+            <code>signature</code>) with the Unix time in{" "}
+            <code>X-Hanami-Timestamp</code> (or <code>ts</code>): the hex
+            HMAC-SHA256, keyed with the signing secret, of{" "}
+            <code>timestamp + &quot;.&quot; + payload</code>. The payload is
+            the raw body of a POST, or the sorted, URL-encoded query of a GET
+            without the signature fields. Timestamps more than 5 minutes off
+            are refused.
+          </li>
+        </ul>
+      </section>
+
+      <section className="mb-8">
+        <h2 className="text-2xl font-semibold text-gray-700 mb-4">
+          Image Pixel and Iframe
+        </h2>
+        <p className="text-gray-600 mb-4">
+          Hosted checkouts that only accept an <code>&lt;img&gt;</code> or{" "}
+          <code>&lt;iframe&gt;</code> tag can load{" "}
+          <code>/api/pixel.gif</code> or <code>/api/pixel.html</code> on the
+          order confirmation page, passing your <code>brand_id</code>,{" "}
+          <code>order_id</code>, <code>amount</code>, <code>currency</code>{" "}
+          and an optional <code>event</code> in the query string. The
+          trackers are read from the <code>hanami_tracking_session</code>{" "}
+          cookie set when the visitor clicked, keeping only clicks on your own
+          links, so serve your tracking links and the pixel from the same
+          custom domain. The pixel is always returned and never cached.
+        </p>
+        <pre className="bg-gray-800 text-white p-4 rounded-lg overflow-x-auto">
+          {`<img src="https://go.yourbrand.com/api/pixel.gif?brand_id=42&order_id={{ order.id }}&amount={{ order.total }}&currency=USD" width="1" height="1" alt="" />`}
+        </pre>
+        <p className="text-gray-600 mt-4">
+          Anyone can read a pixel URL from the page and report any amount
+          with it. If your checkout can render a fresh URL per order, sign it
+          like a GET postback, with <code>ts</code> and{" "}
+          <code>signature</code> over the other query parameters, and turn on{" "}
+          <code>signature_required</code> with{" "}
+          <code>PUT /api/brand/:id/pixel</code>, signed in as the brand, to
+          refuse unsigned pixels. A signature that is sent is always checked.
+        </p>
+      </section>
+
+      <section className="mb-8">
+        <h2 className="text-2xl font-semibold text-gray-700 mb-4">
+          Leads and Custom Events
+        </h2>
+        <p className="text-gray-600 mb-4">
+          To pay per signup, app install or lead form instead of per sale,
+          define the event on the campaign, signed in as its brand, with{" "}
+          <code>POST /api/campaign/:id/events</code>, giving its{" "}
+          <code>name</code>, a fixed <code>payout</code> and{" "}
+          <code>currency</code>, and <code>dedup_per_user</code> to pay for it
+          only once per user. Then report each event with{" "}
+          <code>POST /api/event</code>:
+        </p>
+        <pre className="bg-gray-800 text-white p-4 rounded-lg overflow-x-auto">
+          {`{
+  "event": "signup",
+  "user_id": "user-8812",
+  "order_id": "lead-20931",
+  "trackers": [{ "click_id": "...", "tracking_code": "...", "timestamp": "..." }]
+}`}
+        </pre>
+        <p className="text-gray-600 mt-4">
+          Trackers are checked as for conversions, and the event is credited
+          to the most recent accepted click. <code>user_id</code> is required
+          for deduped events; a repeat returns the original event with{" "}
+          <code>duplicate: true</code>. An optional <code>order_id</code>{" "}
+          works as for conversions: a retry with the same one returns the
+          original event the same way, and a different event reusing it is a
+          409. Events are checked for fraud and wait
+          out the campaign&apos;s <code>approval_delay_days</code> like sales.
+          Signed in as the brand, approve, reject or reverse one with{" "}
+          <code>POST /api/event/:id/approve</code>, <code>reject</code> or{" "}
+          <code>reverse</code>, giving a <code>reason</code> unless approving.
+          Each affiliate&apos;s events and sales are reported side by side by{" "}
+          <code>GET /api/campaign/:id/activity</code>, with sales totalled by
+          currency and events by name and currency.
+        </p>
+      </section>
+
+      <section className="mb-8">
+        <h2 className="text-2xl font-semibold text-gray-700 mb-4">
+          Approvals, Reversals and Refunds
+        </h2>
+        <p className="text-gray-600 mb-4">
+          Each sale and its conversions carry a status. A campaign with an{" "}
+          <code>approval_delay_days</code> (for example a 30-day return window)
+          records sales as <code>pending</code> and approves them once the
+          delay has passed; with no delay they are approved straight away.
+          Signed in as the brand, list its sales with{" "}
+          <code>GET /api/brand/:id/sales?status=pending</code> and change them
+          with:
+        </p>
+        <ul className="list-disc pl-5 text-gray-600">
+          <li>
+            <code>POST /api/sale/:id/approve</code>: approve a pending sale
+            early.
+          </li>
+          <li>
+            <code>POST /api/sale/:id/reject</code> with a{" "}
+            <code>reason</code>: reject a pending sale, such as a cancelled
+            order.
+          </li>
+          <li>
+            <code>POST /api/sale/:id/reverse</code> with a{" "}
+            <code>reason</code>: reverse an approved sale, such as a late
+            return.
+          </li>
+          <li>
+            <code>POST /api/sale/:id/refund</code> with an{" "}
+            <code>amount</code> and <code>reason</code>: refund part of a
+            pending or approved sale. Its conversions are scaled down to the
+            amount kept, and a full refund reverses the sale.
+          </li>
+        </ul>
+        <p className="text-gray-600 mt-4">
+          Analytics and commissions never count rejected or reversed sales.
+          Whether pending ones count is set by the server and can be changed
+          per request with <code>?include_pending=true</code> or{" "}
+          <code>false</code>.
+        </p>
+      </section>
+
       <section className="mb-8">
         <h2 className="text-2xl font-semibold text-gray-700 mb-4">
           Error Handling
@@ -543,6 +719,15 @@ func main() {
           <li>
             <strong>401 Unauthorized:</strong> Missing or invalid API token.
           </li>
+          <li>
+            <strong>422 Unprocessable Entity:</strong> None of the trackers
+            matches a click recorded for the tracking code and brand. Only
+            accepted trackers are credited.
+          </li>
+          <li>
+            <strong>409 Conflict:</strong> The order_id was already used for a
+            conversion with a different amount, currency or trackers.
+          </li>
           <li>
             <strong>500 Internal Server Error:</strong> Database or server
             issues.
@@ -319,7 +319,7 @@ export default ProductPage;
 {`// Example of using tracking data with conversion API
 import axios from 'axios';
 
-async function recordConversion(amount, currency) {
+async function recordConversion(orderId, amount, currency) {
   // Get session data from Hanami Tracker
   const sessionData = window.HanamiTracker.getSessionData();
   
@@ -330,7 +330,7 @@ async function recordConversion(amount, currency) {
   
   // Create conversion request
   const conversionRequest = {
-    session_id: sessionData.session_id,
+    order_id: orderId,
     trackers: sessionData.trackers,
     amount: amount,
     currency: currency
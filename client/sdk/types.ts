@@ -55,6 +55,7 @@ export interface AffiliateCampaigns {
   CommissionRate: string;
   CompanyName: string;
   LandingUrl: string;
+  LookbackDays: number;
   Website: { String: string; Valid: boolean };
 }
 
@@ -75,6 +76,7 @@ export interface ICampaign {
   };
   CommissionRate: string;
   LandingUrl: string;
+  LookbackDays: number;
   CreatedAt: {
     Time: string;
     Valid: boolean;